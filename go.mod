@@ -3,6 +3,7 @@ module technocrat
 go 1.24.4
 
 require (
+	github.com/lithammer/fuzzysearch v1.1.8
 	github.com/pterm/pterm v0.12.79
 	github.com/spf13/cobra v1.10.1
 	golang.org/x/term v0.27.0
@@ -15,7 +16,6 @@ require (
 	github.com/containerd/console v1.0.3 // indirect
 	github.com/gookit/color v1.5.4 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/lithammer/fuzzysearch v1.1.8 // indirect
 	github.com/mattn/go-runewidth v0.0.15 // indirect
 	github.com/rivo/uniseg v0.4.4 // indirect
 	github.com/spf13/pflag v1.0.9 // indirect
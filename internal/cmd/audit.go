@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"technocrat/internal/mcp"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	auditDir        string
+	auditConfigPath string
+	auditSince      string
+	auditUntil      string
+	auditTool       string
+	auditPrompt     string
+	auditSession    string
+	auditPrincipal  string
+	auditStatus     string
+	auditFeature    string
+	auditTransport  string
+	auditLimit      int
+	auditJSON       bool
+)
+
+// auditCmd represents the audit command
+var auditCmd = &cobra.Command{
+	Use:   "audit",
+	Short: "Query the MCP server's audit log",
+	Long: `Show the tool calls and prompt renders recorded in the MCP server's audit log.
+
+The server appends an entry to .tchncrt/audit/audit.jsonl in the workspace it
+was started in for every tools/call and prompts/get it handles, over stdio and
+HTTP alike. Entries hold the time, transport, session, tool or prompt name,
+redacted arguments, status, duration and the workspace of the call. Rotated
+files are read too, oldest first.
+
+Run the command from the server's workspace, or use --dir or --config when the
+server writes its log elsewhere.`,
+	Example: `  # The last 20 entries
+  technocrat audit --limit 20
+
+  # Failed tool calls in the last day
+  technocrat audit --since 24h --status error
+
+  # Every call to create_feature, as JSON
+  technocrat audit --tool create_feature --json`,
+	RunE: runAudit,
+}
+
+func init() {
+	rootCmd.AddCommand(auditCmd)
+
+	auditCmd.Flags().StringVar(&auditDir, "dir", "", "Audit log directory (default .tchncrt/audit in the workspace)")
+	auditCmd.Flags().StringVar(&auditConfigPath, "config", "", "Read the audit log directory from this server config file")
+	auditCmd.Flags().StringVar(&auditSince, "since", "", "Only entries from this time on: a duration such as 24h, a date or an RFC 3339 time")
+	auditCmd.Flags().StringVar(&auditUntil, "until", "", "Only entries before this time, in the same forms as --since")
+	auditCmd.Flags().StringVar(&auditTool, "tool", "", "Only calls to this tool")
+	auditCmd.Flags().StringVar(&auditPrompt, "prompt", "", "Only renders of this prompt")
+	auditCmd.Flags().StringVar(&auditSession, "session", "", "Only entries of sessions with this ID or ID prefix")
+	auditCmd.Flags().StringVar(&auditPrincipal, "principal", "", "Only entries of this authenticated caller")
+	auditCmd.Flags().StringVar(&auditStatus, "status", "", "Only entries with this status: ok, error or cancelled")
+	auditCmd.Flags().StringVar(&auditFeature, "feature", "", "Only entries for this feature")
+	auditCmd.Flags().StringVar(&auditTransport, "transport", "", "Only entries from this transport: stdio or http")
+	auditCmd.Flags().IntVarP(&auditLimit, "limit", "n", 0, "Show only the most recent entries")
+	auditCmd.Flags().BoolVar(&auditJSON, "json", false, "Output the entries as JSON")
+}
+
+func runAudit(cmd *cobra.Command, args []string) error {
+	dir, err := resolveAuditDir()
+	if err != nil {
+		return err
+	}
+	filter, err := buildAuditFilter(time.Now())
+	if err != nil {
+		return err
+	}
+
+	entries, err := mcp.ReadAuditLog(dir, filter)
+	if err != nil {
+		return err
+	}
+
+	if auditJSON {
+		return outputAuditJSON(cmd.OutOrStdout(), entries)
+	}
+	return outputAuditText(cmd.OutOrStdout(), entries)
+}
+
+// resolveAuditDir returns the directory given by --dir, the server config or
+// the workspace, in that order
+func resolveAuditDir() (string, error) {
+	if auditDir != "" {
+		return auditDir, nil
+	}
+	if auditConfigPath != "" {
+		cfg, err := mcp.LoadConfig(auditConfigPath)
+		if err != nil {
+			return "", err
+		}
+		if cfg.Audit.Dir != "" {
+			return cfg.Audit.Dir, nil
+		}
+	}
+	return mcp.AuditDir(mcp.DetectWorkspaceContext().Root), nil
+}
+
+// buildAuditFilter turns the flags into a filter, resolving relative times against now
+func buildAuditFilter(now time.Time) (mcp.AuditFilter, error) {
+	filter := mcp.AuditFilter{
+		Session:   auditSession,
+		Principal: auditPrincipal,
+		Status:    auditStatus,
+		Feature:   auditFeature,
+		Transport: auditTransport,
+		Limit:     auditLimit,
+	}
+
+	switch {
+	case auditTool != "" && auditPrompt != "":
+		return filter, fmt.Errorf("--tool and --prompt cannot be used together")
+	case auditTool != "":
+		filter.Method, filter.Name = "tools/call", auditTool
+	case auditPrompt != "":
+		filter.Method, filter.Name = "prompts/get", auditPrompt
+	}
+
+	switch auditStatus {
+	case "", "ok", "error", "cancelled":
+	default:
+		return filter, fmt.Errorf("invalid --status %q (use ok, error or cancelled)", auditStatus)
+	}
+	switch auditTransport {
+	case "", mcp.TransportStdio, mcp.TransportHTTP:
+	default:
+		return filter, fmt.Errorf("invalid --transport %q (use stdio or http)", auditTransport)
+	}
+	if auditLimit < 0 {
+		return filter, fmt.Errorf("--limit must not be negative")
+	}
+
+	var err error
+	if filter.Since, err = parseAuditTime(auditSince, now); err != nil {
+		return filter, fmt.Errorf("invalid --since: %w", err)
+	}
+	if filter.Until, err = parseAuditTime(auditUntil, now); err != nil {
+		return filter, fmt.Errorf("invalid --until: %w", err)
+	}
+	return filter, nil
+}
+
+// parseAuditTime parses a duration before now, a date or an RFC 3339 time.
+// An empty value is the zero time.
+func parseAuditTime(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("%q is not a duration such as 24h, a date such as 2006-01-02 or an RFC 3339 time", value)
+}
+
+// outputAuditJSON writes the entries as a JSON array
+func outputAuditJSON(w io.Writer, entries []mcp.AuditEntry) error {
+	if entries == nil {
+		entries = []mcp.AuditEntry{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(entries)
+}
+
+// outputAuditText writes the entries as a table, one per line
+func outputAuditText(w io.Writer, entries []mcp.AuditEntry) error {
+	if len(entries) == 0 {
+		_, err := fmt.Fprintln(w, "No audit entries found")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TIME\tTRANSPORT\tSESSION\tMETHOD\tNAME\tSTATUS\tDURATION\tFEATURE\tERROR")
+	for _, e := range entries {
+		// Session IDs are long; a prefix is enough to tell them apart and to pass to --session
+		session := e.Session
+		if len(session) > 8 {
+			session = session[:8]
+		}
+		duration := time.Duration(e.DurationMs * float64(time.Millisecond)).Round(time.Millisecond)
+		errorLine, _, _ := strings.Cut(e.Error, "\n")
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			e.Time.Local().Format(time.DateTime), e.Transport, orDash(session), e.Method, e.Name,
+			e.Status, duration, orDash(e.Feature), errorLine)
+	}
+	return tw.Flush()
+}
+
+// orDash returns s, or "-" when it is empty
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"technocrat/internal/mcp"
+)
+
+// TestParseAuditTime tests the forms accepted by --since and --until
+func TestParseAuditTime(t *testing.T) {
+	now := time.Date(2026, 3, 2, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name        string
+		value       string
+		expected    time.Time
+		expectError bool
+	}{
+		{name: "Empty", value: "", expected: time.Time{}},
+		{name: "Duration", value: "90m", expected: now.Add(-90 * time.Minute)},
+		{name: "RFC 3339", value: "2026-03-01T08:30:00Z", expected: time.Date(2026, 3, 1, 8, 30, 0, 0, time.UTC)},
+		{name: "Date", value: "2026-02-27", expected: time.Date(2026, 2, 27, 0, 0, 0, 0, time.Local)},
+		{name: "Invalid", value: "yesterday", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseAuditTime(tt.value, now)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseAuditTime failed: %v", err)
+			}
+			if !got.Equal(tt.expected) {
+				t.Errorf("Expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestBuildAuditFilter tests turning the audit flags into a filter
+func TestBuildAuditFilter(t *testing.T) {
+	tests := []struct {
+		name           string
+		tool           string
+		prompt         string
+		status         string
+		expectedMethod string
+		expectError    bool
+	}{
+		{name: "Tool", tool: "create_feature", expectedMethod: "tools/call"},
+		{name: "Prompt", prompt: "spec", expectedMethod: "prompts/get"},
+		{name: "Tool and prompt", tool: "create_feature", prompt: "spec", expectError: true},
+		{name: "Unknown status", status: "failed", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			auditTool, auditPrompt, auditStatus = tt.tool, tt.prompt, tt.status
+			defer func() { auditTool, auditPrompt, auditStatus = "", "", "" }()
+
+			filter, err := buildAuditFilter(time.Now())
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("buildAuditFilter failed: %v", err)
+			}
+			if filter.Method != tt.expectedMethod || filter.Name != tt.tool+tt.prompt {
+				t.Errorf("Expected %s %s, got %s %s", tt.expectedMethod, tt.tool+tt.prompt, filter.Method, filter.Name)
+			}
+		})
+	}
+}
+
+// TestOutputAuditText tests the table of audit entries
+func TestOutputAuditText(t *testing.T) {
+	var b bytes.Buffer
+	if err := outputAuditText(&b, nil); err != nil || !strings.Contains(b.String(), "No audit entries found") {
+		t.Errorf("Expected a message for no entries, got %q, %v", b.String(), err)
+	}
+
+	b.Reset()
+	err := outputAuditText(&b, []mcp.AuditEntry{{
+		Time:       time.Now(),
+		Transport:  mcp.TransportHTTP,
+		Session:    "0123456789abcdef",
+		Method:     "tools/call",
+		Name:       "create_feature",
+		Status:     "error",
+		Error:      "branch exists\nmore detail",
+		DurationMs: 12.4,
+	}})
+	if err != nil {
+		t.Fatalf("outputAuditText failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "TIME") {
+		t.Fatalf("Expected a header and one row, got %q", b.String())
+	}
+	for _, want := range []string{"01234567", "create_feature", "error", "12ms", "-", "branch"} {
+		if !strings.Contains(lines[1], want) {
+			t.Errorf("Expected the row to contain %q, got %q", want, lines[1])
+		}
+	}
+	if strings.Contains(lines[1], "more detail") || strings.Contains(lines[1], "89abcdef") {
+		t.Errorf("Expected a shortened session and a one-line error, got %q", lines[1])
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path/filepath"
 
 	"technocrat/internal/editor"
 	"technocrat/internal/installer"
@@ -44,6 +45,10 @@ func init() {
 	// Add flags for configure-mcp
 	configureMcpCmd.Flags().StringSlice("editors", nil, "Specific editors to configure (e.g., --editors claude,vscode)")
 	configureMcpCmd.Flags().Bool("all", false, "Configure all detected editors")
+	configureMcpCmd.Flags().String("listen", "", "Server listen address for HTTP-mode editors (unix:///path/to.sock or host:port)")
+	configureMcpCmd.Flags().String("tls-cert", "", "Server certificate file; HTTP-mode editors use https")
+	configureMcpCmd.Flags().String("tls-key", "", "Server private key file for --tls-cert")
+	configureMcpCmd.Flags().String("tls-client-ca", "", "CA file the server checks client certificates against")
 }
 
 func runCheckMCP(cmd *cobra.Command, args []string) error {
@@ -128,6 +133,22 @@ func runConfigureMCP(cmd *cobra.Command, args []string) error {
 	// Get flags
 	editorNames, _ := cmd.Flags().GetStringSlice("editors")
 	configureAll, _ := cmd.Flags().GetBool("all")
+	var endpoint installer.HTTPEndpoint
+	endpoint.Listen, _ = cmd.Flags().GetString("listen")
+	endpoint.TLSCert, _ = cmd.Flags().GetString("tls-cert")
+	endpoint.TLSKey, _ = cmd.Flags().GetString("tls-key")
+	endpoint.TLSClientCA, _ = cmd.Flags().GetString("tls-client-ca")
+	if (endpoint.TLSCert == "") != (endpoint.TLSKey == "") {
+		return fmt.Errorf("--tls-cert and --tls-key must be given together")
+	}
+	// Editors launch the server from their own working directory
+	for _, path := range []*string{&endpoint.TLSCert, &endpoint.TLSKey, &endpoint.TLSClientCA} {
+		if *path != "" {
+			if abs, err := filepath.Abs(*path); err == nil {
+				*path = abs
+			}
+		}
+	}
 
 	// Get current directory as project path
 	projectPath, err := os.Getwd()
@@ -209,7 +230,7 @@ func runConfigureMCP(cmd *cobra.Command, args []string) error {
 	for _, ed := range editorsToConfig {
 		fmt.Printf("📝 Configuring %s...\n", ed.Name)
 
-		if err := installer.InstallMCPConfig(ed, projectPath); err != nil {
+		if err := installer.InstallMCPConfigWithEndpoint(ed, projectPath, endpoint); err != nil {
 			fmt.Printf("   ❌ Failed: %v\n", err)
 		} else {
 			fmt.Printf("   ✅ Successfully configured\n")
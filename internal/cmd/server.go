@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"fmt"
-	"log"
 
 	"technocrat/internal/mcp"
 
@@ -10,8 +9,15 @@ import (
 )
 
 var (
-	serverPort  int
-	serverStdio bool
+	serverBind         string
+	serverPort         int
+	serverListen       string
+	serverTLSCert      string
+	serverTLSKey       string
+	serverTLSClientCA  string
+	serverStdio        bool
+	serverLegacyRoutes bool
+	serverConfigPath   string
 )
 
 // serverCmd represents the server command
@@ -21,7 +27,7 @@ var serverCmd = &cobra.Command{
 	Long: `Start the Technocrat Model Context Protocol (MCP) server.
 
 The server can run in two modes:
-- HTTP mode (default): Listens on a port for HTTP requests (for Amazon Q, VS Code)  
+- HTTP mode (default): Serves the MCP Streamable HTTP transport at /mcp (for Amazon Q, VS Code)
 - stdio mode: Uses stdin/stdout for communication (for Claude Desktop, Cursor, Windsurf)
 
 The server provides tools, resources, and prompts to connected clients.`,
@@ -31,20 +37,87 @@ The server provides tools, resources, and prompts to connected clients.`,
 func init() {
 	rootCmd.AddCommand(serverCmd)
 
+	serverCmd.Flags().StringVar(&serverBind, "bind", "127.0.0.1", "Address to listen on (HTTP mode); use 0.0.0.0 to accept connections from other machines")
 	serverCmd.Flags().IntVarP(&serverPort, "port", "p", 8080, "Port to listen on (HTTP mode)")
+	serverCmd.Flags().StringVar(&serverListen, "listen", "", "Listen on unix:///path/to.sock or host:port instead of --bind and --port (HTTP mode)")
+	serverCmd.Flags().StringVar(&serverTLSCert, "tls-cert", "", "PEM certificate file; serves HTTPS (HTTP mode)")
+	serverCmd.Flags().StringVar(&serverTLSKey, "tls-key", "", "PEM private key file for --tls-cert")
+	serverCmd.Flags().StringVar(&serverTLSClientCA, "tls-client-ca", "", "PEM CA file; require client certificates signed by it (mutual TLS)")
 	serverCmd.Flags().BoolVar(&serverStdio, "stdio", false, "Use stdio transport (for Claude Desktop)")
+	serverCmd.Flags().BoolVar(&serverLegacyRoutes, "legacy-routes", false, "Also serve the deprecated /mcp/v1/* REST routes (HTTP mode)")
+	serverCmd.Flags().StringVar(&serverConfigPath, "config", "", "Path to a JSON server config file (see config.example.json)")
+}
+
+// loadServerConfig builds the server settings from the config file, if any,
+// with explicitly set flags taking precedence
+func loadServerConfig(cmd *cobra.Command) (mcp.Config, error) {
+	cfg := mcp.DefaultConfig()
+	if serverConfigPath != "" {
+		loaded, err := mcp.LoadConfig(serverConfigPath)
+		if err != nil {
+			return cfg, err
+		}
+		cfg = loaded
+	}
+
+	if serverConfigPath == "" || cmd.Flags().Changed("bind") {
+		cfg.Bind = serverBind
+	}
+	if serverConfigPath == "" || cmd.Flags().Changed("port") {
+		cfg.Port = serverPort
+	}
+	for flag, setting := range map[string]*string{
+		"listen":        &cfg.Listen,
+		"tls-cert":      &cfg.TLSCert,
+		"tls-key":       &cfg.TLSKey,
+		"tls-client-ca": &cfg.TLSClientCA,
+	} {
+		if cmd.Flags().Changed(flag) {
+			*setting, _ = cmd.Flags().GetString(flag)
+		}
+	}
+	if err := cfg.ValidateListener(); err != nil {
+		return cfg, fmt.Errorf("invalid server settings: %w", err)
+	}
+
+	return cfg, nil
 }
 
 func runServer(cmd *cobra.Command, args []string) error {
+	mcp.ServerVersion = version
+
+	cfg, err := loadServerConfig(cmd)
+	if err != nil {
+		return err
+	}
+	if err := mcp.SetLogLevel(cfg.LogLevel); err != nil {
+		return err
+	}
+
+	logger := mcp.Logger()
 	if serverStdio {
-		log.Printf("Starting Technocrat MCP Server in stdio mode...")
-		server := mcp.NewStdioServer()
+		logger.Info("Starting Technocrat MCP Server in stdio mode")
+		server := mcp.NewStdioServerWithConfig(cfg)
 		if err := server.Start(); err != nil {
 			return fmt.Errorf("failed to start stdio server: %w", err)
 		}
 	} else {
-		log.Printf("Starting Technocrat MCP Server on port %d...", serverPort)
-		server := mcp.NewServer(serverPort)
+		auth, err := mcp.NewAuthenticator(cfg.Auth)
+		if err != nil {
+			return err
+		}
+
+		network, address, err := cfg.ListenAddress()
+		if err != nil {
+			return err
+		}
+
+		logger.Info("Starting Technocrat MCP Server", "network", network, "addr", address, "auth", cfg.Auth.Mode)
+		server := mcp.NewServerWithConfig(cfg)
+		server.SetAuthenticator(auth)
+		if serverLegacyRoutes {
+			server.EnableLegacyRoutes()
+		}
 		if err := server.Start(); err != nil {
 			return fmt.Errorf("failed to start server: %w", err)
 		}
@@ -3,9 +3,11 @@ package installer
 import (
 	"encoding/json"
 	"fmt"
+	"net"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 
 	"technocrat/internal/editor"
 )
@@ -17,6 +19,59 @@ type MCPServerConfig struct {
 	Env     map[string]string `json:"env,omitempty"`
 }
 
+// HTTPEndpoint describes where an HTTP-mode server listens, so editors that
+// launch the server pass the same settings and editors that connect to a
+// running one are given its URL. The zero value is the default localhost:8080.
+type HTTPEndpoint struct {
+	Listen      string // unix:///path/to.sock or host:port
+	TLSCert     string
+	TLSKey      string
+	TLSClientCA string
+}
+
+// ServerArgs returns the technocrat server flags selecting the endpoint
+func (e HTTPEndpoint) ServerArgs() []string {
+	args := []string{"server"}
+	if e.Listen == "" {
+		args = append(args, "--port", "8080")
+	} else {
+		args = append(args, "--listen", e.Listen)
+	}
+	if e.TLSCert != "" {
+		args = append(args, "--tls-cert", e.TLSCert, "--tls-key", e.TLSKey)
+	}
+	if e.TLSClientCA != "" {
+		args = append(args, "--tls-client-ca", e.TLSClientCA)
+	}
+	return args
+}
+
+// URL returns the MCP endpoint URL clients connect to
+func (e HTTPEndpoint) URL() (string, error) {
+	if strings.HasPrefix(e.Listen, "unix://") {
+		return "", fmt.Errorf("clients connecting by URL cannot reach unix socket %s", e.Listen)
+	}
+
+	hostport := "localhost:8080"
+	if e.Listen != "" {
+		host, port, err := net.SplitHostPort(strings.TrimPrefix(e.Listen, "tcp://"))
+		if err != nil {
+			return "", fmt.Errorf("invalid listen address %q: %w", e.Listen, err)
+		}
+		// A wildcard bind is reached through the loopback interface
+		if ip := net.ParseIP(host); host == "" || (ip != nil && ip.IsUnspecified()) {
+			host = "localhost"
+		}
+		hostport = net.JoinHostPort(host, port)
+	}
+
+	scheme := "http"
+	if e.TLSCert != "" {
+		scheme = "https"
+	}
+	return scheme + "://" + hostport + "/mcp", nil
+}
+
 // getTechnocratPath returns the best path to the technocrat binary
 func getTechnocratPath() (string, error) {
 	// First try to find technocrat in PATH
@@ -42,16 +97,22 @@ func getTechnocratPath() (string, error) {
 
 // InstallMCPConfig installs MCP server configuration for an editor
 func InstallMCPConfig(ed editor.Editor, projectPath string) error {
+	return InstallMCPConfigWithEndpoint(ed, projectPath, HTTPEndpoint{})
+}
+
+// InstallMCPConfigWithEndpoint installs MCP server configuration for an
+// editor, pointing HTTP-mode clients at the given endpoint
+func InstallMCPConfigWithEndpoint(ed editor.Editor, projectPath string, endpoint HTTPEndpoint) error {
 	var err error
 	switch ed.Type {
 	case editor.VSCode:
-		err = installVSCodeMCP(projectPath)
+		err = installVSCodeMCP(projectPath, endpoint)
 	case editor.ClaudeDesktop:
 		err = installClaudeMCP(ed.ConfigDir)
 	case editor.Cursor:
 		err = installCursorMCP(ed.ConfigDir)
 	case editor.AmazonQ:
-		err = installAmazonQMCP(ed.ConfigDir)
+		err = installAmazonQMCP(ed.ConfigDir, endpoint)
 	case editor.Windsurf:
 		err = installWindsurfMCP(projectPath)
 	default:
@@ -71,7 +132,7 @@ func InstallMCPConfig(ed editor.Editor, projectPath string) error {
 }
 
 // installVSCodeMCP configures MCP for VS Code
-func installVSCodeMCP(projectPath string) error {
+func installVSCodeMCP(projectPath string, endpoint HTTPEndpoint) error {
 	settingsPath := filepath.Join(projectPath, ".vscode", "settings.json")
 
 	// Create .vscode directory if it doesn't exist
@@ -98,7 +159,7 @@ func installVSCodeMCP(projectPath string) error {
 	mcpServers := map[string]interface{}{
 		"technocrat": map[string]interface{}{
 			"command": technocratPath,
-			"args":    []string{"server", "--port", "8080"},
+			"args":    endpoint.ServerArgs(),
 		},
 	}
 
@@ -212,9 +273,19 @@ func installCursorMCP(configDir string) error {
 }
 
 // installAmazonQMCP configures MCP for Amazon Q
-func installAmazonQMCP(configDir string) error {
+func installAmazonQMCP(configDir string, endpoint HTTPEndpoint) error {
 	configPath := filepath.Join(configDir, "mcp-config.json")
 
+	// Amazon Q connects to a running server over TCP and has no way to present
+	// a client certificate
+	url, err := endpoint.URL()
+	if err != nil {
+		return err
+	}
+	if endpoint.TLSClientCA != "" {
+		return fmt.Errorf("Amazon Q cannot present a client certificate; use a bearer token instead of mutual TLS")
+	}
+
 	// Create config directory if it doesn't exist
 	if err := os.MkdirAll(configDir, 0755); err != nil {
 		return fmt.Errorf("failed to create config directory: %w", err)
@@ -231,7 +302,7 @@ func installAmazonQMCP(configDir string) error {
 	// Amazon Q uses HTTP transport
 	config["technocrat"] = map[string]interface{}{
 		"type": "http",
-		"url":  "http://localhost:8080/mcp/v1",
+		"url":  url,
 	}
 
 	// Write config
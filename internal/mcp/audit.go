@@ -0,0 +1,404 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Audit log file layout: entries are appended to auditFileName, which is
+// renamed to audit-<time>.jsonl once it reaches its size limit
+const (
+	auditFileName       = "audit.jsonl"
+	auditRotatedPrefix  = "audit-"
+	auditRotatedSuffix  = ".jsonl"
+	auditRotationLayout = "20060102T150405.000000000Z"
+)
+
+// Placeholders written in place of redacted or overlong argument values
+const (
+	auditRedacted       = "[REDACTED]"
+	auditMaxStringBytes = 1024
+)
+
+// auditSensitiveKeys are argument name fragments whose values are never logged
+var auditSensitiveKeys = []string{"password", "passwd", "secret", "token", "credential", "authorization", "api_key", "apikey", "private_key"}
+
+// Transports an audit entry can come from
+const (
+	TransportStdio = "stdio"
+	TransportHTTP  = "http"
+)
+
+// AuditEntry is one line of the audit log: a tool call or prompt render
+type AuditEntry struct {
+	Time       time.Time              `json:"time"`
+	Transport  string                 `json:"transport"`
+	Session    string                 `json:"session,omitempty"`
+	Client     string                 `json:"client,omitempty"`    // Client name and version reported at initialize
+	Principal  string                 `json:"principal,omitempty"` // Authenticated caller over HTTP
+	Method     string                 `json:"method"`              // "tools/call" or "prompts/get"
+	Name       string                 `json:"name"`                // Tool or prompt name
+	Arguments  map[string]interface{} `json:"arguments,omitempty"` // Redacted arguments
+	Status     string                 `json:"status"`              // "ok", "error" or "cancelled"
+	Error      string                 `json:"error,omitempty"`
+	DurationMs float64                `json:"duration_ms"`
+	Workspace  string                 `json:"workspace,omitempty"`
+	Feature    string                 `json:"feature,omitempty"`
+}
+
+// AuditDir returns the default audit log directory of a workspace
+func AuditDir(workspaceRoot string) string {
+	return filepath.Join(workspaceRoot, ".tchncrt", "audit")
+}
+
+// AuditLog appends entries to a JSONL file, rotating it when it grows past
+// its size limit and, when asked to, keeping a bounded number of rotated files
+type AuditLog struct {
+	dir        string
+	maxBytes   int64
+	maxFiles   int
+	redactKeys []string
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	closed bool
+	now    func() time.Time
+}
+
+// OpenAuditLog opens the audit log in cfg.Dir, or in the .tchncrt/audit of
+// the workspace detected at startup when unset, creating the directory if
+// needed. The directory never follows the roots a client reports.
+func OpenAuditLog(cfg AuditConfig) (*AuditLog, error) {
+	dir := cfg.Dir
+	if dir == "" {
+		dir = AuditDir(DetectWorkspaceContext().Root)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create audit directory: %w", err)
+	}
+
+	l := &AuditLog{
+		dir:        dir,
+		maxBytes:   int64(cfg.MaxFileBytes),
+		maxFiles:   cfg.MaxFiles,
+		redactKeys: append([]string(nil), auditSensitiveKeys...),
+		now:        time.Now,
+	}
+	for _, key := range cfg.RedactArguments {
+		l.redactKeys = append(l.redactKeys, strings.ToLower(key))
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// open opens the current file for appending
+func (l *AuditLog) open() error {
+	file, err := os.OpenFile(filepath.Join(l.dir, auditFileName), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open audit log: %w", err)
+	}
+	l.file, l.size = file, info.Size()
+	return nil
+}
+
+// Record appends an entry, rotating the file first when the entry would take
+// it past the size limit
+func (l *AuditLog) Record(entry AuditEntry) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	line = append(line, '\n')
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return errors.New("audit log is closed")
+	}
+	if l.file == nil {
+		// A failed rotation left the file closed; try again
+		if err := l.open(); err != nil {
+			return err
+		}
+	}
+	if l.maxBytes > 0 && l.size > 0 && l.size+int64(len(line)) > l.maxBytes {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+	return nil
+}
+
+// rotate renames the current file after the time of rotation, starts a new
+// one and, when maxFiles is set, removes the oldest rotated files beyond it.
+// l.mu must be held.
+func (l *AuditLog) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return fmt.Errorf("failed to close audit log: %w", err)
+	}
+	l.file = nil
+
+	rotated := auditRotatedPrefix + l.now().UTC().Format(auditRotationLayout) + auditRotatedSuffix
+	if err := os.Rename(filepath.Join(l.dir, auditFileName), filepath.Join(l.dir, rotated)); err != nil {
+		return fmt.Errorf("failed to rotate audit log: %w", err)
+	}
+	if err := l.open(); err != nil {
+		return err
+	}
+
+	if l.maxFiles > 0 {
+		files, err := rotatedAuditFiles(l.dir)
+		if err != nil {
+			return err
+		}
+		for len(files) > l.maxFiles {
+			if err := os.Remove(files[0]); err != nil {
+				return fmt.Errorf("failed to remove old audit log: %w", err)
+			}
+			files = files[1:]
+		}
+	}
+	return nil
+}
+
+// Close closes the audit log; later entries are refused
+func (l *AuditLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.closed = true
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// redact copies arguments, replacing the values of sensitive keys and
+// shortening long strings
+func (l *AuditLog) redact(args map[string]interface{}) map[string]interface{} {
+	if len(args) == 0 {
+		return nil
+	}
+	redacted, _ := l.redactValue(args).(map[string]interface{})
+	return redacted
+}
+
+// redactValue redacts a value and everything nested in it
+func (l *AuditLog) redactValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, nested := range v {
+			if l.sensitive(key) {
+				out[key] = auditRedacted
+			} else {
+				out[key] = l.redactValue(nested)
+			}
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, nested := range v {
+			out[i] = l.redactValue(nested)
+		}
+		return out
+	case string:
+		if len(v) > auditMaxStringBytes {
+			return fmt.Sprintf("%s... [%d bytes]", strings.ToValidUTF8(v[:auditMaxStringBytes], ""), len(v))
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// sensitive reports whether an argument name marks a secret
+func (l *AuditLog) sensitive(key string) bool {
+	key = strings.ToLower(key)
+	for _, fragment := range l.redactKeys {
+		if strings.Contains(key, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+// startAudit opens the audit log configured for a server and attaches it to
+// the dispatcher. It returns a function that closes the log again.
+func (d *Dispatcher) startAudit(cfg AuditConfig, transport string) (func(), error) {
+	if !cfg.Enabled {
+		return func() {}, nil
+	}
+	log, err := OpenAuditLog(cfg)
+	if err != nil {
+		return nil, err
+	}
+	d.SetAuditLog(log, transport)
+	logger.Debug("Recording tool calls and prompts", "dir", log.dir)
+	return func() {
+		if err := log.Close(); err != nil {
+			logger.Error("Failed to close audit log", "error", err)
+		}
+	}, nil
+}
+
+// SetAuditLog records every tool call and prompt render handled by the
+// dispatcher in log, attributed to the transport
+func (d *Dispatcher) SetAuditLog(log *AuditLog, transport string) {
+	d.audit = log
+	d.transport = transport
+}
+
+// record writes an audit entry for a tool call or prompt render that started
+// at start and ended with err
+func (d *Dispatcher) record(ctx context.Context, method, name string, args map[string]interface{}, start time.Time, err error) {
+	if d.audit == nil {
+		return
+	}
+
+	entry := AuditEntry{
+		Time:       start.UTC(),
+		Transport:  d.transport,
+		Method:     method,
+		Name:       name,
+		Arguments:  d.audit.redact(args),
+		Status:     outcomeOf(err),
+		DurationMs: float64(time.Since(start).Microseconds()) / 1000,
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	if session, ok := SessionFromContext(ctx); ok {
+		entry.Session = session.ID()
+		if info := session.ClientInfo(); info.Name != "" {
+			entry.Client = strings.TrimSuffix(info.Name+"/"+info.Version, "/")
+		}
+	}
+	if principal, ok := PrincipalFromContext(ctx); ok {
+		entry.Principal = principal.Subject
+	}
+	workspace := WorkspaceFromContext(ctx)
+	entry.Workspace, entry.Feature = workspace.Root, workspace.FeatureName
+	if feature, ok := args["feature"].(string); ok && feature != "" {
+		entry.Feature = feature
+	}
+
+	if err := d.audit.Record(entry); err != nil {
+		logger.ErrorContext(ctx, "Failed to write audit entry", "method", method, "name", name, "error", err)
+	}
+}
+
+// toolError returns the error a tool call ended with, including failures
+// reported in its result
+func toolError(result *CallToolResult, err error) error {
+	if err == nil && result != nil && result.IsError {
+		return errors.New(resultText(result))
+	}
+	return err
+}
+
+// AuditFilter selects audit entries; zero fields match everything
+type AuditFilter struct {
+	Since     time.Time
+	Until     time.Time
+	Transport string
+	Session   string
+	Principal string
+	Method    string
+	Name      string
+	Status    string
+	Feature   string
+	Limit     int // Most recent entries to return; 0 returns all
+}
+
+// matches reports whether the entry passes the filter
+func (f AuditFilter) matches(e AuditEntry) bool {
+	return (f.Since.IsZero() || !e.Time.Before(f.Since)) &&
+		(f.Until.IsZero() || e.Time.Before(f.Until)) &&
+		(f.Transport == "" || e.Transport == f.Transport) &&
+		(f.Session == "" || strings.HasPrefix(e.Session, f.Session)) &&
+		(f.Principal == "" || e.Principal == f.Principal) &&
+		(f.Method == "" || e.Method == f.Method) &&
+		(f.Name == "" || e.Name == f.Name) &&
+		(f.Status == "" || e.Status == f.Status) &&
+		(f.Feature == "" || e.Feature == f.Feature)
+}
+
+// ReadAuditLog returns the entries in dir that pass the filter, oldest first.
+// Lines that cannot be parsed, such as one cut short by a crash, are skipped.
+func ReadAuditLog(dir string, filter AuditFilter) ([]AuditEntry, error) {
+	files, err := rotatedAuditFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, filepath.Join(dir, auditFileName))
+
+	var entries []AuditEntry
+	for _, path := range files {
+		file, err := os.Open(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to open audit log: %w", err)
+		}
+
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), 16<<20)
+		for scanner.Scan() {
+			var entry AuditEntry
+			if json.Unmarshal(scanner.Bytes(), &entry) != nil {
+				continue
+			}
+			if filter.matches(entry) {
+				entries = append(entries, entry)
+			}
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit log %s: %w", path, err)
+		}
+	}
+
+	if filter.Limit > 0 && len(entries) > filter.Limit {
+		entries = entries[len(entries)-filter.Limit:]
+	}
+	return entries, nil
+}
+
+// rotatedAuditFiles returns the rotated files in dir, oldest first
+func rotatedAuditFiles(dir string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, auditRotatedPrefix+"*"+auditRotatedSuffix))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list audit logs: %w", err)
+	}
+	// The timestamp layout sorts chronologically
+	sort.Strings(matches)
+	return matches, nil
+}
@@ -0,0 +1,436 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestAuditLogRotation tests rotating the log and pruning old files
+func TestAuditLogRotation(t *testing.T) {
+	dir := t.TempDir()
+	log, err := OpenAuditLog(AuditConfig{Dir: dir, MaxFileBytes: 300, MaxFiles: 2})
+	if err != nil {
+		t.Fatalf("OpenAuditLog failed: %v", err)
+	}
+	defer log.Close()
+
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	log.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	for i := 0; i < 12; i++ {
+		entry := AuditEntry{Time: now, Transport: TransportStdio, Method: "tools/call", Name: "list_features", Status: outcomeOK, Arguments: map[string]interface{}{"n": i}}
+		if err := log.Record(entry); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	rotated, _ := rotatedAuditFiles(dir)
+	if len(rotated) != 2 {
+		t.Fatalf("Expected 2 rotated files to be kept, have %d", len(rotated))
+	}
+	for _, path := range append(rotated, filepath.Join(dir, auditFileName)) {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatalf("Stat failed: %v", err)
+		}
+		if info.Size() > 300 {
+			t.Errorf("Expected %s to stay within 300 bytes, has %d", filepath.Base(path), info.Size())
+		}
+	}
+
+	// The entries left are the most recent, in the order they were written
+	entries, err := ReadAuditLog(dir, AuditFilter{})
+	if err != nil {
+		t.Fatalf("ReadAuditLog failed: %v", err)
+	}
+	if len(entries) == 0 || entries[len(entries)-1].Arguments["n"] != float64(11) {
+		t.Fatalf("Expected the last entry to be kept, got %v", entries)
+	}
+	for i := 1; i < len(entries); i++ {
+		if entries[i].Arguments["n"].(float64) != entries[i-1].Arguments["n"].(float64)+1 {
+			t.Errorf("Expected consecutive entries, got %v then %v", entries[i-1].Arguments, entries[i].Arguments)
+		}
+	}
+
+	log.Close()
+	if err := log.Record(AuditEntry{}); err == nil {
+		t.Error("Expected recording to a closed log to fail")
+	}
+}
+
+// TestAuditLogStartupWorkspace tests that without a directory every entry goes
+// to the log of the workspace detected at startup, whatever workspace the entry
+// names, and that rotated files are kept when max_files is unset
+func TestAuditLogStartupWorkspace(t *testing.T) {
+	t.Chdir(newTestWorkspace(t))
+	elsewhere := t.TempDir()
+	log, err := OpenAuditLog(DefaultConfig().Audit)
+	if err != nil {
+		t.Fatalf("OpenAuditLog failed: %v", err)
+	}
+	defer log.Close()
+	log.maxBytes = 300
+
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	log.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+
+	for i := 0; i < 12; i++ {
+		entry := AuditEntry{Time: now, Transport: TransportHTTP, Method: "tools/call", Name: "list_features", Status: outcomeOK, Workspace: elsewhere, Arguments: map[string]interface{}{"n": i}}
+		if err := log.Record(entry); err != nil {
+			t.Fatalf("Record failed: %v", err)
+		}
+	}
+
+	if log.dir != AuditDir(DetectWorkspaceContext().Root) {
+		t.Errorf("Expected the startup workspace's audit directory, got %s", log.dir)
+	}
+	if entries := readAudit(t, log.dir); len(entries) != 12 || entries[0].Workspace != elsewhere {
+		t.Errorf("Expected every entry in the startup log with its workspace, got %d", len(entries))
+	}
+	if _, err := os.Stat(AuditDir(elsewhere)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected nothing written to the entry's workspace, got %v", err)
+	}
+	if rotated, _ := rotatedAuditFiles(log.dir); len(rotated) < 3 {
+		t.Errorf("Expected the rotated files to be kept, have %d", len(rotated))
+	}
+}
+
+// TestAuditRedaction tests that secrets and long strings are not logged
+func TestAuditRedaction(t *testing.T) {
+	log := &AuditLog{redactKeys: append(append([]string(nil), auditSensitiveKeys...), "ticket")}
+	long := strings.Repeat("x", auditMaxStringBytes+10)
+
+	tests := []struct {
+		name     string
+		args     map[string]interface{}
+		key      string
+		expected interface{}
+	}{
+		{name: "Plain argument", args: map[string]interface{}{"feature": "001-login"}, key: "feature", expected: "001-login"},
+		{name: "Token", args: map[string]interface{}{"github_token": "ghp_abc"}, key: "github_token", expected: auditRedacted},
+		{name: "Mixed case", args: map[string]interface{}{"DB_Password": "hunter2"}, key: "DB_Password", expected: auditRedacted},
+		{name: "Configured name", args: map[string]interface{}{"ticket": "JIRA-1"}, key: "ticket", expected: auditRedacted},
+		{name: "Nested", args: map[string]interface{}{"env": map[string]interface{}{"API_KEY": "k"}}, key: "env", expected: map[string]interface{}{"API_KEY": auditRedacted}},
+		{name: "In a list", args: map[string]interface{}{"items": []interface{}{map[string]interface{}{"secret": "s"}}}, key: "items", expected: []interface{}{map[string]interface{}{"secret": auditRedacted}}},
+		{name: "Long string", args: map[string]interface{}{"description": long}, key: "description", expected: strings.Repeat("x", auditMaxStringBytes) + "... [1034 bytes]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			redacted := log.redact(tt.args)
+			if got := redacted[tt.key]; !equalJSON(got, tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+
+	args := map[string]interface{}{"token": "t"}
+	log.redact(args)
+	if args["token"] != "t" {
+		t.Error("Expected the caller's arguments to be left alone")
+	}
+}
+
+// equalJSON compares decoded JSON values
+func equalJSON(a, b interface{}) bool {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for key := range av {
+			if !equalJSON(av[key], bv[key]) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !equalJSON(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
+
+// TestReadAuditLog tests filtering entries
+func TestReadAuditLog(t *testing.T) {
+	dir := t.TempDir()
+	log, err := OpenAuditLog(AuditConfig{Dir: dir})
+	if err != nil {
+		t.Fatalf("OpenAuditLog failed: %v", err)
+	}
+	start := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	for i, entry := range []AuditEntry{
+		{Transport: TransportStdio, Method: "tools/call", Name: "create_feature", Status: outcomeOK, Feature: "001-login"},
+		{Transport: TransportHTTP, Session: "abc123", Method: "tools/call", Name: "create_feature", Status: outcomeError},
+		{Transport: TransportHTTP, Session: "abc123", Method: "prompts/get", Name: "spec", Status: outcomeOK, Feature: "001-login"},
+		{Transport: TransportHTTP, Session: "def456", Method: "tools/call", Name: "list_features", Status: outcomeCancelled},
+	} {
+		entry.Time = start.Add(time.Duration(i) * time.Hour)
+		log.Record(entry)
+	}
+	log.Close()
+
+	// A line cut short by a crash is skipped
+	file, _ := os.OpenFile(filepath.Join(dir, auditFileName), os.O_WRONLY|os.O_APPEND, 0600)
+	io.WriteString(file, `{"time":"2026-03-02T`)
+	file.Close()
+
+	tests := []struct {
+		name     string
+		filter   AuditFilter
+		expected []string
+	}{
+		{name: "Everything", filter: AuditFilter{}, expected: []string{"create_feature", "create_feature", "spec", "list_features"}},
+		{name: "Since", filter: AuditFilter{Since: start.Add(2 * time.Hour)}, expected: []string{"spec", "list_features"}},
+		{name: "Until", filter: AuditFilter{Until: start.Add(time.Hour)}, expected: []string{"create_feature"}},
+		{name: "Tool", filter: AuditFilter{Method: "tools/call", Name: "create_feature"}, expected: []string{"create_feature", "create_feature"}},
+		{name: "Prompt", filter: AuditFilter{Method: "prompts/get"}, expected: []string{"spec"}},
+		{name: "Session prefix", filter: AuditFilter{Session: "abc"}, expected: []string{"create_feature", "spec"}},
+		{name: "Status", filter: AuditFilter{Status: outcomeCancelled}, expected: []string{"list_features"}},
+		{name: "Transport", filter: AuditFilter{Transport: TransportStdio}, expected: []string{"create_feature"}},
+		{name: "Feature", filter: AuditFilter{Feature: "001-login"}, expected: []string{"create_feature", "spec"}},
+		{name: "Most recent", filter: AuditFilter{Limit: 2}, expected: []string{"spec", "list_features"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := ReadAuditLog(dir, tt.filter)
+			if err != nil {
+				t.Fatalf("ReadAuditLog failed: %v", err)
+			}
+			var names []string
+			for _, entry := range entries {
+				names = append(names, entry.Name)
+			}
+			if strings.Join(names, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected %v, got %v", tt.expected, names)
+			}
+		})
+	}
+
+	entries, err := ReadAuditLog(filepath.Join(dir, "missing"), AuditFilter{})
+	if err != nil || len(entries) != 0 {
+		t.Errorf("Expected no entries from a missing directory, got %v, %v", entries, err)
+	}
+}
+
+// registerAuditedTool registers a tool that fails when asked to
+func registerAuditedTool(h *Handler) {
+	h.RegisterTool(Tool{
+		Name:        "audited",
+		InputSchema: map[string]interface{}{"type": "object"},
+		Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			if args["fail"] == true {
+				return nil, errors.New("it broke")
+			}
+			return "done", nil
+		},
+	})
+}
+
+// readAudit reads every entry in dir, failing the test on error
+func readAudit(t *testing.T, dir string) []AuditEntry {
+	t.Helper()
+	entries, err := ReadAuditLog(dir, AuditFilter{})
+	if err != nil {
+		t.Fatalf("ReadAuditLog failed: %v", err)
+	}
+	return entries
+}
+
+// TestAuditStdio tests that tool calls and prompts over stdio are recorded
+func TestAuditStdio(t *testing.T) {
+	dir := t.TempDir()
+	server := NewStdioServer()
+	registerAuditedTool(server.handler)
+	closeAudit, err := server.dispatcher.startAudit(AuditConfig{Enabled: true, Dir: dir}, TransportStdio)
+	if err != nil {
+		t.Fatalf("startAudit failed: %v", err)
+	}
+	defer closeAudit()
+
+	in, out, served := startStdioServer(context.Background(), t, server)
+	for _, message := range []string{
+		`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"audited","arguments":{"feature":"002-search","api_token":"s3cret"}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"audited","arguments":{"fail":true}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"prompts/get","params":{"name":"no_such_prompt"}}`,
+	} {
+		io.WriteString(in, message+"\n")
+		if _, err := out.ReadString('\n'); err != nil {
+			t.Fatalf("Failed to read reply: %v", err)
+		}
+	}
+	in.Close()
+	waitFor(t, served, "serve to return")
+
+	entries := readAudit(t, dir)
+	if len(entries) != 3 {
+		t.Fatalf("Expected 3 entries, got %d", len(entries))
+	}
+
+	ok := entries[0]
+	if ok.Transport != TransportStdio || ok.Session != server.session.ID() || ok.Name != "audited" || ok.Status != outcomeOK {
+		t.Errorf("Unexpected entry for the successful call: %+v", ok)
+	}
+	if ok.Arguments["api_token"] != auditRedacted || ok.Feature != "002-search" || ok.Workspace == "" {
+		t.Errorf("Expected redacted arguments, the feature and the workspace, got %+v", ok)
+	}
+	if failed := entries[1]; failed.Status != outcomeError || failed.Error != "it broke" {
+		t.Errorf("Expected the failure in the result to be recorded, got %+v", failed)
+	}
+	if prompt := entries[2]; prompt.Method != "prompts/get" || prompt.Status != outcomeError {
+		t.Errorf("Expected the unknown prompt to be recorded as an error, got %+v", prompt)
+	}
+
+	data, _ := os.ReadFile(filepath.Join(dir, auditFileName))
+	if strings.Contains(string(data), "s3cret") {
+		t.Error("Expected the token to be kept out of the log")
+	}
+}
+
+// TestAuditHTTP tests that tool calls over HTTP are recorded with their session
+// and client, on the Streamable HTTP endpoint and the legacy routes
+func TestAuditHTTP(t *testing.T) {
+	dir := t.TempDir()
+	server := NewServer(8080)
+	server.EnableLegacyRoutes()
+	registerAuditedTool(server.handler)
+	closeAudit, err := server.dispatcher.startAudit(AuditConfig{Enabled: true, Dir: dir}, TransportHTTP)
+	if err != nil {
+		t.Fatalf("startAudit failed: %v", err)
+	}
+	defer closeAudit()
+	ts := httptest.NewServer(server.routes())
+	defer ts.Close()
+
+	initialized := sessionRequest(t, http.MethodPost, ts.URL, "", testInitialize)
+	initialized.Body.Close()
+	id := initialized.Header.Get(sessionIDHeader)
+
+	call := sessionRequest(t, http.MethodPost, ts.URL, id, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"audited"}}`)
+	call.Body.Close()
+	legacy, err := http.Post(ts.URL+"/mcp/v1/tools/call", "application/json", strings.NewReader(`{"name":"audited","arguments":{"fail":true}}`))
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	legacy.Body.Close()
+
+	entries := readAudit(t, dir)
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 entries, got %d", len(entries))
+	}
+	if e := entries[0]; e.Transport != TransportHTTP || e.Session != id || e.Client != "test/1" || e.Status != outcomeOK {
+		t.Errorf("Unexpected entry for the session's call: %+v", e)
+	}
+	if e := entries[1]; e.Session != "" || e.Status != outcomeError {
+		t.Errorf("Unexpected entry for the legacy call: %+v", e)
+	}
+}
+
+// TestAuditShutdown tests that a tool call still running when shutdown starts
+// is audited before the log closes
+func TestAuditShutdown(t *testing.T) {
+	dir := t.TempDir()
+	socket := filepath.Join(t.TempDir(), "technocrat.sock")
+	cfg := DefaultConfig()
+	cfg.Listen = "unix://" + socket
+	cfg.Audit.Dir = dir
+	server := NewServerWithConfig(cfg)
+
+	shutdownErr := make(chan error, 1)
+	server.handler.RegisterTool(Tool{
+		Name:        "drain",
+		InputSchema: map[string]interface{}{"type": "object"},
+		Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			go func() { shutdownErr <- server.shutdown(context.Background()) }()
+			time.Sleep(100 * time.Millisecond)
+			return "done", nil
+		},
+	})
+
+	started := make(chan error, 1)
+	go func() { started <- server.Start() }()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+	var id string
+	for deadline := time.Now().Add(2 * time.Second); id == "" && time.Now().Before(deadline); {
+		resp, err := client.Post("http://localhost"+mcpEndpoint, "application/json", strings.NewReader(testInitialize))
+		if err != nil {
+			time.Sleep(10 * time.Millisecond)
+			continue
+		}
+		resp.Body.Close()
+		id = resp.Header.Get(sessionIDHeader)
+	}
+	if id == "" {
+		t.Fatal("Server did not start")
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, "http://localhost"+mcpEndpoint,
+		strings.NewReader(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"drain"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(sessionIDHeader, id)
+	req.Header.Set(protocolVersionHeader, LatestProtocolVersion)
+	if resp, err := client.Do(req); err == nil {
+		resp.Body.Close()
+	}
+
+	if err := waitFor(t, shutdownErr, "shutdown"); err != nil {
+		t.Errorf("Shutdown failed: %v", err)
+	}
+	if err := waitFor(t, started, "Start to return"); !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("Expected ErrServerClosed, got %v", err)
+	}
+
+	entries := readAudit(t, dir)
+	if len(entries) != 1 || entries[0].Name != "drain" {
+		t.Fatalf("Expected the call running at shutdown to be audited, got %+v", entries)
+	}
+}
+
+// TestAuditDisabled tests that no log is written when auditing is off
+func TestAuditDisabled(t *testing.T) {
+	dir := t.TempDir()
+	d := NewDispatcher(NewHandler())
+	closeAudit, err := d.startAudit(AuditConfig{Dir: dir}, TransportStdio)
+	if err != nil {
+		t.Fatalf("startAudit failed: %v", err)
+	}
+	closeAudit()
+
+	if d.audit != nil {
+		t.Error("Expected no audit log")
+	}
+	if _, err := os.Stat(filepath.Join(dir, auditFileName)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected no audit file, got %v", err)
+	}
+}
@@ -0,0 +1,316 @@
+package mcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// protectedResourcePath serves the OAuth protected resource metadata (RFC 9728)
+const protectedResourcePath = "/.well-known/oauth-protected-resource"
+
+// authRealm names the protection space in WWW-Authenticate challenges
+const authRealm = "technocrat"
+
+// ErrInvalidToken is returned for bearer tokens that are unknown, malformed or expired
+var ErrInvalidToken = errors.New("invalid token")
+
+// ErrInsufficientScope is returned for valid access tokens lacking a required scope
+var ErrInsufficientScope = errors.New("insufficient scope")
+
+// Principal is the authenticated caller of an HTTP request
+type Principal struct {
+	Subject  string   // Token name in bearer mode, sub claim in oauth mode
+	ClientID string   // OAuth client the access token was issued to, if known
+	Scopes   []string // Scopes granted to the access token
+}
+
+// Authenticator checks the bearer token of an HTTP request and returns the
+// caller it belongs to. Errors wrap ErrInvalidToken or ErrInsufficientScope.
+type Authenticator interface {
+	Authenticate(token string) (*Principal, error)
+}
+
+// protectedResource is implemented by authenticators of OAuth protected
+// resources, which publish where clients get their tokens
+type protectedResource interface {
+	resourceMetadata() protectedResourceMetadata
+	metadataURL() string
+}
+
+// protectedResourceMetadata is the OAuth protected resource metadata document
+type protectedResourceMetadata struct {
+	Resource               string   `json:"resource"`
+	AuthorizationServers   []string `json:"authorization_servers"`
+	ScopesSupported        []string `json:"scopes_supported,omitempty"`
+	BearerMethodsSupported []string `json:"bearer_methods_supported"`
+	ResourceName           string   `json:"resource_name"`
+}
+
+// principalContextKey is the context key for the authenticated caller
+type principalContextKey struct{}
+
+// ContextWithPrincipal returns a context carrying the authenticated caller
+func ContextWithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, principalContextKey{}, principal)
+}
+
+// PrincipalFromContext returns the authenticated caller of the request, if any
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(principalContextKey{}).(*Principal)
+	return principal, ok && principal != nil
+}
+
+// NewAuthenticator creates the authenticator for the configured mode, or nil
+// when callers are not authenticated
+func NewAuthenticator(cfg AuthConfig) (Authenticator, error) {
+	if err := cfg.validate(); err != nil {
+		return nil, fmt.Errorf("invalid auth config: %w", err)
+	}
+
+	switch cfg.Mode {
+	case AuthModeBearer:
+		return newStaticTokenAuthenticator(cfg.Tokens), nil
+	case AuthModeOAuth:
+		return newOAuthAuthenticator(cfg)
+	}
+	return nil, nil
+}
+
+// staticTokenAuthenticator accepts the bearer tokens listed in the config.
+// Only their SHA-256 hashes are kept, so every comparison is between values of
+// the same length and reveals nothing about the tokens' lengths.
+type staticTokenAuthenticator struct {
+	tokens []hashedToken
+}
+
+// hashedToken is a configured bearer token reduced to its hash
+type hashedToken struct {
+	name string
+	hash [sha256.Size]byte
+}
+
+// newStaticTokenAuthenticator hashes the configured tokens
+func newStaticTokenAuthenticator(tokens []AuthToken) *staticTokenAuthenticator {
+	a := &staticTokenAuthenticator{tokens: make([]hashedToken, len(tokens))}
+	for i, token := range tokens {
+		a.tokens[i] = hashedToken{name: token.Name, hash: sha256.Sum256([]byte(token.Token))}
+	}
+	return a
+}
+
+// Authenticate returns the principal named after the matching token
+func (a *staticTokenAuthenticator) Authenticate(token string) (*Principal, error) {
+	hash := sha256.Sum256([]byte(token))
+	var match *hashedToken
+	for i := range a.tokens {
+		// Compare against every token so the time taken reveals nothing
+		if subtle.ConstantTimeCompare(hash[:], a.tokens[i].hash[:]) == 1 && match == nil {
+			match = &a.tokens[i]
+		}
+	}
+	if match == nil {
+		return nil, ErrInvalidToken
+	}
+
+	name := match.name
+	if name == "" {
+		name = "bearer"
+	}
+	return &Principal{Subject: name}, nil
+}
+
+// oauthAuthenticator validates JWT access tokens issued by an OAuth 2.1
+// authorization server for this resource. The signing keys are read from a
+// local JWKS file, which is re-read when it changes so keys can be rotated
+// without a restart.
+type oauthAuthenticator struct {
+	cfg      AuthConfig
+	issuer   string
+	audience string
+	now      func() time.Time
+
+	mu       sync.Mutex
+	keys     *jwkSet
+	keysTime time.Time // Modification time of the JWKS file the keys were read from
+}
+
+// newOAuthAuthenticator reads the signing keys and resolves the expected issuer and audience
+func newOAuthAuthenticator(cfg AuthConfig) (*oauthAuthenticator, error) {
+	a := &oauthAuthenticator{
+		cfg:      cfg,
+		issuer:   cfg.Issuer,
+		audience: cfg.Audience,
+		now:      time.Now,
+	}
+	if a.issuer == "" {
+		a.issuer = cfg.AuthorizationServers[0]
+	}
+	if a.audience == "" {
+		a.audience = cfg.Resource
+	}
+
+	info, err := os.Stat(cfg.JWKSFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWKS file: %w", err)
+	}
+	if a.keys, err = loadJWKS(cfg.JWKSFile); err != nil {
+		return nil, err
+	}
+	a.keysTime = info.ModTime()
+	return a, nil
+}
+
+// Authenticate validates an access token and returns its subject
+func (a *oauthAuthenticator) Authenticate(token string) (*Principal, error) {
+	claims, err := verifyJWT(token, a.currentKeys(), a.now())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if claims.Issuer != a.issuer {
+		return nil, fmt.Errorf("%w: issued by %q", ErrInvalidToken, claims.Issuer)
+	}
+	if !claims.Audience.contains(a.audience) {
+		return nil, fmt.Errorf("%w: not issued for this resource", ErrInvalidToken)
+	}
+
+	scopes := claims.scopes()
+	for _, required := range a.cfg.RequiredScopes {
+		if !containsString(scopes, required) {
+			return nil, fmt.Errorf("%w: missing scope %q", ErrInsufficientScope, required)
+		}
+	}
+
+	return &Principal{Subject: claims.Subject, ClientID: claims.ClientID, Scopes: scopes}, nil
+}
+
+// currentKeys returns the signing keys, re-reading the JWKS file when it
+// changed. A file that became unreadable or invalid leaves the old keys in use.
+func (a *oauthAuthenticator) currentKeys() *jwkSet {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	info, err := os.Stat(a.cfg.JWKSFile)
+	if err != nil || info.ModTime().Equal(a.keysTime) {
+		return a.keys
+	}
+	keys, err := loadJWKS(a.cfg.JWKSFile)
+	if err != nil {
+		logger.Warn("Keeping previous signing keys", "error", err)
+		return a.keys
+	}
+	a.keys = keys
+	a.keysTime = info.ModTime()
+	logger.Info("Reloaded signing keys", "file", a.cfg.JWKSFile)
+	return a.keys
+}
+
+// resourceMetadata describes the resource and where clients get access tokens for it
+func (a *oauthAuthenticator) resourceMetadata() protectedResourceMetadata {
+	return protectedResourceMetadata{
+		Resource:               a.cfg.Resource,
+		AuthorizationServers:   a.cfg.AuthorizationServers,
+		ScopesSupported:        a.cfg.RequiredScopes,
+		BearerMethodsSupported: []string{"header"},
+		ResourceName:           ServerName,
+	}
+}
+
+// metadataURL returns the URL of the resource's metadata document, with the
+// resource's path appended to the well-known path as RFC 9728 describes
+func (a *oauthAuthenticator) metadataURL() string {
+	resource, _ := url.Parse(a.cfg.Resource) // Validated with the config
+	return resource.Scheme + "://" + resource.Host + protectedResourcePath + strings.TrimSuffix(resource.Path, "/")
+}
+
+// containsString reports whether list holds value
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// SetAuthenticator requires HTTP clients to authenticate with a bearer token.
+// A nil authenticator accepts every caller.
+func (s *Server) SetAuthenticator(auth Authenticator) {
+	s.auth = auth
+}
+
+// requireAuth rejects requests without a valid bearer token and passes the
+// caller to the next handler through the request context
+func (s *Server) requireAuth(next http.HandlerFunc) http.HandlerFunc {
+	if s.auth == nil {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := bearerToken(r)
+		if !ok {
+			s.challenge(w, http.StatusUnauthorized, "", "")
+			return
+		}
+
+		principal, err := s.auth.Authenticate(token)
+		if errors.Is(err, ErrInsufficientScope) {
+			logger.Info("Rejected request with insufficient scope", "remote", r.RemoteAddr, "error", err)
+			s.challenge(w, http.StatusForbidden, "insufficient_scope", err.Error())
+			return
+		}
+		if err != nil {
+			logger.Info("Rejected request with invalid token", "remote", r.RemoteAddr, "error", err)
+			s.challenge(w, http.StatusUnauthorized, "invalid_token", err.Error())
+			return
+		}
+
+		next(w, r.WithContext(ContextWithPrincipal(r.Context(), principal)))
+	}
+}
+
+// challenge answers an unauthenticated request with a Bearer challenge
+// (RFC 6750) pointing OAuth clients at the resource metadata
+func (s *Server) challenge(w http.ResponseWriter, status int, code, description string) {
+	params := []string{fmt.Sprintf("realm=%q", authRealm)}
+	if resource, ok := s.auth.(protectedResource); ok {
+		params = append(params, fmt.Sprintf("resource_metadata=%q", resource.metadataURL()))
+		if code == "insufficient_scope" {
+			params = append(params, fmt.Sprintf("scope=%q", strings.Join(resource.resourceMetadata().ScopesSupported, " ")))
+		}
+	}
+	if code != "" {
+		params = append(params, fmt.Sprintf("error=%q", code), fmt.Sprintf("error_description=%q", description))
+	}
+
+	w.Header().Set("WWW-Authenticate", "Bearer "+strings.Join(params, ", "))
+	http.Error(w, http.StatusText(status), status)
+}
+
+// bearerToken returns the token of the request's Authorization header
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// handleProtectedResource serves the OAuth protected resource metadata
+func (s *Server) handleProtectedResource(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.respondJSON(w, http.StatusOK, s.auth.(protectedResource).resourceMetadata())
+}
@@ -0,0 +1,261 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// registerBlockingTool registers a tool that blocks until its context is done and
+// reports the context error on the returned channel
+func registerBlockingTool(h *Handler) (started chan struct{}, stopped chan error) {
+	started = make(chan struct{}, 1)
+	stopped = make(chan error, 1)
+	h.RegisterTool(Tool{
+		Name:        "block",
+		InputSchema: map[string]interface{}{"type": "object"},
+		Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			started <- struct{}{}
+			<-ctx.Done()
+			stopped <- ctx.Err()
+			return nil, ctx.Err()
+		},
+	})
+	return started, stopped
+}
+
+// waitFor receives from ch or fails the test after a timeout
+func waitFor[T any](t *testing.T, ch <-chan T, what string) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(2 * time.Second):
+		t.Fatalf("Timed out waiting for %s", what)
+		var zero T
+		return zero
+	}
+}
+
+// TestRequestTimeout tests that the configured deadline stops slow handlers
+func TestRequestTimeout(t *testing.T) {
+	h := NewHandler()
+	_, stopped := registerBlockingTool(h)
+	d := NewDispatcher(h)
+	d.SetRequestTimeout(20 * time.Millisecond)
+
+	resp := d.Handle(context.Background(), newTestRequest(t, 1, "tools/call", CallToolParams{Name: "block"}))
+	if resp == nil || resp.Error == nil || resp.Error.Code != CodeInternalError {
+		t.Fatalf("Expected internal error on timeout, got %#v", resp)
+	}
+	if !strings.Contains(resp.Error.Message, "timed out") {
+		t.Errorf("Expected timeout message, got %q", resp.Error.Message)
+	}
+	if err := waitFor(t, stopped, "handler to stop"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected handler to see DeadlineExceeded, got %v", err)
+	}
+}
+
+// TestToolTimeout tests that a tool's own timeout replaces the request timeout
+func TestToolTimeout(t *testing.T) {
+	h := NewHandler()
+	h.RegisterTool(Tool{
+		Name:        "wait",
+		InputSchema: map[string]interface{}{"type": "object"},
+		Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			select {
+			case <-time.After(100 * time.Millisecond):
+				return "done", nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		},
+		Timeout: 2 * time.Second,
+	})
+	d := NewDispatcher(h)
+	d.SetRequestTimeout(20 * time.Millisecond)
+
+	resp := d.Handle(context.Background(), newTestRequest(t, 1, "tools/call", CallToolParams{Name: "wait"}))
+	if resp == nil || resp.Error != nil {
+		t.Fatalf("Expected the tool to outlive the request timeout, got %#v", resp)
+	}
+
+	// Other requests keep the request timeout
+	if timeout := d.timeoutFor(newTestRequest(t, 2, "tools/call", CallToolParams{Name: "echo"})); timeout != 20*time.Millisecond {
+		t.Errorf("Expected the request timeout for other tools, got %s", timeout)
+	}
+
+	// A disabled request timeout stays disabled
+	d.SetRequestTimeout(0)
+	if timeout := d.timeoutFor(newTestRequest(t, 3, "tools/call", CallToolParams{Name: "wait"})); timeout != 0 {
+		t.Errorf("Expected no deadline, got %s", timeout)
+	}
+}
+
+// TestCancelledNotification tests that notifications/cancelled stops the matching request
+func TestCancelledNotification(t *testing.T) {
+	h := NewHandler()
+	started, stopped := registerBlockingTool(h)
+	d := NewDispatcher(h)
+	ctx := ContextWithSession(context.Background(), NewSession())
+
+	responses := make(chan *Response, 1)
+	go func() {
+		responses <- d.Handle(ctx, newTestRequest(t, "req-1", "tools/call", CallToolParams{Name: "block"}))
+	}()
+	waitFor(t, started, "handler to start")
+
+	// Cancelling a request from another session has no effect
+	other := ContextWithSession(context.Background(), NewSession())
+	d.Handle(other, newTestRequest(t, nil, "notifications/cancelled", CancelledParams{RequestID: json.RawMessage(`"req-1"`)}))
+	select {
+	case <-stopped:
+		t.Fatal("Cancellation from another session stopped the request")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	d.Handle(ctx, newTestRequest(t, nil, "notifications/cancelled", CancelledParams{RequestID: json.RawMessage(`"req-1"`), Reason: "user aborted"}))
+
+	if err := waitFor(t, stopped, "handler to stop"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected handler to see Canceled, got %v", err)
+	}
+	if resp := waitFor(t, responses, "response"); resp != nil {
+		t.Errorf("Expected no response for a cancelled request, got %#v", resp)
+	}
+}
+
+// TestLegacyHandler tests the adapter for context-free handlers
+func TestLegacyHandler(t *testing.T) {
+	handler := LegacyHandler(func(args map[string]interface{}) (interface{}, error) {
+		return args["value"], nil
+	})
+
+	value, err := handler(context.Background(), map[string]interface{}{"value": "ok"})
+	if err != nil || value != "ok" {
+		t.Errorf("Expected legacy handler result, got %v, %v", value, err)
+	}
+
+	release := make(chan struct{})
+	defer close(release)
+	slow := LegacyHandler(func(args map[string]interface{}) (interface{}, error) {
+		<-release
+		return nil, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := slow(ctx, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected caller to stop waiting on deadline, got %v", err)
+	}
+}
+
+// TestStdioServerCancellation tests cancellation of a running request over stdio
+func TestStdioServerCancellation(t *testing.T) {
+	server := NewStdioServer()
+	started, stopped := registerBlockingTool(server.handler)
+
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	served := make(chan error, 1)
+	go func() {
+		served <- server.serve(context.Background(), inReader, outWriter)
+		outWriter.Close()
+	}()
+
+	io.WriteString(inWriter, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"block"}}`+"\n")
+	waitFor(t, started, "handler to start")
+
+	io.WriteString(inWriter, `{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":1}}`+"\n")
+	waitFor(t, stopped, "handler to stop")
+
+	io.WriteString(inWriter, `{"jsonrpc":"2.0","id":2,"method":"ping"}`+"\n")
+	line, err := bufio.NewReader(outReader).ReadString('\n')
+	if err != nil {
+		t.Fatalf("Failed to read reply: %v", err)
+	}
+	if response := decodeReply(t, []byte(line)); response["id"] != float64(2) {
+		t.Errorf("Expected only the ping reply, got %s", line)
+	}
+
+	inWriter.Close()
+	if err := waitFor(t, served, "serve to return"); err != nil {
+		t.Errorf("serve returned error: %v", err)
+	}
+}
+
+// TestStreamableHTTPCancellation tests cancellation of a running request over HTTP
+func TestStreamableHTTPCancellation(t *testing.T) {
+	server := NewServer(8080)
+	started, stopped := registerBlockingTool(server.handler)
+	ts := httptest.NewServer(server.routes())
+	defer ts.Close()
+
+	id := initSession(t, server)
+	post := func(body string) *http.Response {
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+mcpEndpoint, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(sessionIDHeader, id)
+		req.Header.Set(protocolVersionHeader, LatestProtocolVersion)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Errorf("POST failed: %v", err)
+			return nil
+		}
+		return resp
+	}
+
+	statuses := make(chan int, 1)
+	go func() {
+		if resp := post(`{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"block"}}`); resp != nil {
+			resp.Body.Close()
+			statuses <- resp.StatusCode
+		}
+	}()
+	waitFor(t, started, "handler to start")
+
+	if resp := post(`{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":7}}`); resp != nil {
+		resp.Body.Close()
+	}
+
+	if err := waitFor(t, stopped, "handler to stop"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected handler to see Canceled, got %v", err)
+	}
+	if status := waitFor(t, statuses, "cancelled POST to finish"); status != http.StatusAccepted {
+		t.Errorf("Expected 202 for the cancelled request, got %d", status)
+	}
+}
+
+// TestServerShutdownCancelsRequests tests that cancelling the base context stops running handlers
+func TestServerShutdownCancelsRequests(t *testing.T) {
+	server := NewServer(8080)
+	started, stopped := registerBlockingTool(server.handler)
+	ts := httptest.NewUnstartedServer(server.routes())
+	ts.Config.BaseContext = server.baseContext
+	ts.Start()
+	defer ts.Close()
+
+	id := initSession(t, server)
+	go func() {
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+mcpEndpoint,
+			strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"block"}}`))
+		req.Header.Set(sessionIDHeader, id)
+		req.Header.Set(protocolVersionHeader, LatestProtocolVersion)
+		resp, err := http.DefaultClient.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+	waitFor(t, started, "handler to start")
+
+	server.cancelBase()
+	if err := waitFor(t, stopped, "handler to stop"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected handler to see Canceled, got %v", err)
+	}
+}
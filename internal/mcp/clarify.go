@@ -0,0 +1,329 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// maxClarifyQuestions is the most questions a clarify session may ask, as in
+// the clarify workflow
+const maxClarifyQuestions = 5
+
+// clarifyTimeout bounds a clarify session. It replaces the request timeout,
+// which is too short for a person to answer several questions.
+const clarifyTimeout = 15 * time.Minute
+
+// clarifyQuestion is one question for the user, with optional choices
+type clarifyQuestion struct {
+	Question    string
+	Options     []string
+	Recommended string
+}
+
+// clarifyAnswer is a question the user answered
+type clarifyAnswer struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+}
+
+// clarifyResult is the structured result of the clarify_spec tool
+type clarifyResult struct {
+	Feature   string          `json:"feature"`
+	Spec      string          `json:"spec"`
+	Answered  []clarifyAnswer `json:"answered"`
+	Declined  int             `json:"declined"`
+	Cancelled bool            `json:"cancelled"`
+}
+
+// clarifySpecTool asks the user the clarify workflow's questions through
+// elicitation and records the answers in the spec's Clarifications section
+func (h *Handler) clarifySpecTool() Tool {
+	return Tool{
+		Name: "clarify_spec",
+		Description: "Asks the user up to five clarification questions about a feature spec and records " +
+			"each accepted answer in the spec's Clarifications section. Requires a client that supports elicitation.",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"feature": map[string]interface{}{
+					"type":        "string",
+					"description": "Feature directory under specs/, defaulting to the current one",
+				},
+				"questions": map[string]interface{}{
+					"type":     "array",
+					"minItems": 1,
+					"maxItems": maxClarifyQuestions,
+					"items": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"question": map[string]interface{}{"type": "string", "minLength": 1},
+							"options": map[string]interface{}{
+								"type":        "array",
+								"description": "2-5 mutually exclusive choices; omit for a short free-text answer",
+								"items":       map[string]interface{}{"type": "string"},
+								"minItems":    2,
+								"maxItems":    5,
+								"uniqueItems": true,
+							},
+							"recommended": map[string]interface{}{
+								"type":        "string",
+								"description": "The suggested answer, shown to the user",
+							},
+						},
+						"required": []string{"question"},
+					},
+				},
+			},
+			"required": []string{"questions"},
+		},
+		OutputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"feature": map[string]interface{}{"type": "string"},
+				"spec":    map[string]interface{}{"type": "string"},
+				"answered": map[string]interface{}{
+					"type": "array",
+					"items": map[string]interface{}{
+						"type": "object",
+						"properties": map[string]interface{}{
+							"question": map[string]interface{}{"type": "string"},
+							"answer":   map[string]interface{}{"type": "string"},
+						},
+						"required": []string{"question", "answer"},
+					},
+				},
+				"declined":  map[string]interface{}{"type": "integer"},
+				"cancelled": map[string]interface{}{"type": "boolean"},
+			},
+			"required": []string{"feature", "spec", "answered", "declined", "cancelled"},
+		},
+		Handler: h.clarifySpec,
+		Timeout: clarifyTimeout,
+	}
+}
+
+// clarifySpec asks each question in turn. Declined questions are skipped, and
+// a cancelled one ends the session. The spec is saved after every answer so
+// nothing is lost if the session ends early, and read again before each save
+// to keep edits made while the user was answering.
+func (h *Handler) clarifySpec(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+	feature, _ := args["feature"].(string)
+	if feature == "" {
+		feature = WorkspaceFromContext(ctx).FeatureName
+	}
+	if feature == "" {
+		return nil, errors.New("no feature given and none detected from the workspace")
+	}
+	if !validSegment(feature) {
+		return nil, fmt.Errorf("invalid feature name %q", feature)
+	}
+
+	specPath := filepath.Join(h.workspaceFor(ctx).Root, "specs", feature, "spec.md")
+	if _, err := os.Stat(specPath); err != nil {
+		return nil, fmt.Errorf("failed to read spec for %s: %w", feature, err)
+	}
+
+	result := clarifyResult{Feature: feature, Spec: featureURI(feature, "spec"), Answered: []clarifyAnswer{}}
+	date := time.Now().Format("2006-01-02")
+
+	for _, question := range clarifyQuestions(args["questions"]) {
+		answer, err := Elicit(ctx, question.message(), question.schema())
+		if errors.Is(err, ErrElicitationUnsupported) {
+			return nil, fmt.Errorf("%w: ask the questions in chat instead", err)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if answer.Action == ElicitActionCancel {
+			result.Cancelled = true
+			break
+		}
+		if answer.Action == ElicitActionDecline {
+			result.Declined++
+			continue
+		}
+
+		text := strings.TrimSpace(fmt.Sprint(answer.Content["answer"]))
+		data, err := os.ReadFile(specPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read spec for %s: %w", feature, err)
+		}
+		spec := addClarification(string(data), date, question.Question, text)
+		if err := writeFileAtomic(specPath, []byte(spec)); err != nil {
+			return nil, err
+		}
+		result.Answered = append(result.Answered, clarifyAnswer{Question: question.Question, Answer: text})
+	}
+
+	return result, nil
+}
+
+// clarifyQuestions reads the questions argument, already validated against the input schema
+func clarifyQuestions(value interface{}) []clarifyQuestion {
+	items, _ := value.([]interface{})
+	questions := make([]clarifyQuestion, 0, len(items))
+	for _, item := range items {
+		fields, _ := item.(map[string]interface{})
+		question := clarifyQuestion{}
+		question.Question, _ = fields["question"].(string)
+		question.Recommended, _ = fields["recommended"].(string)
+		options, _ := fields["options"].([]interface{})
+		for _, option := range options {
+			if text, ok := option.(string); ok {
+				question.Options = append(question.Options, text)
+			}
+		}
+		questions = append(questions, question)
+	}
+	return questions
+}
+
+// message is the text shown to the user for the question
+func (q clarifyQuestion) message() string {
+	if q.Recommended == "" {
+		return q.Question
+	}
+	return fmt.Sprintf("%s\n\nRecommended: %s", q.Question, q.Recommended)
+}
+
+// schema asks for a single answer: one of the options, or a short phrase
+func (q clarifyQuestion) schema() map[string]interface{} {
+	answer := map[string]interface{}{
+		"type":  "string",
+		"title": "Answer",
+	}
+	if len(q.Options) > 0 {
+		answer["enum"] = q.Options
+	} else {
+		answer["description"] = "Short answer (5 words or fewer)"
+		answer["minLength"] = 1
+		answer["maxLength"] = 100
+	}
+
+	return map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"answer": answer},
+		"required":   []string{"answer"},
+	}
+}
+
+// addClarification records an answer in the spec as "- Q: <question> → A: <answer>"
+// under "## Clarifications" and "### Session <date>", creating either heading
+// when missing. A new Clarifications section goes before the spec's first
+// second-level heading, right after its title and overview.
+func addClarification(spec, date, question, answer string) string {
+	lines := strings.Split(spec, "\n")
+	bullet := fmt.Sprintf("- Q: %s → A: %s", question, answer)
+	session := "### Session " + date
+
+	section := findHeading(lines, 0, len(lines), "## Clarifications")
+	if section < 0 {
+		insertAt := len(lines)
+		for i, line := range lines {
+			if strings.HasPrefix(line, "## ") {
+				insertAt = i
+				break
+			}
+		}
+		block := []string{"## Clarifications", "", session, "", bullet, ""}
+		if insertAt == len(lines) {
+			insertAt = lastContentLine(lines, 0, len(lines)) + 1
+			block = append([]string{""}, block[:len(block)-1]...)
+		}
+		return strings.Join(insertLines(lines, insertAt, block...), "\n")
+	}
+
+	sectionEnd := nextHeading(lines, section+1, len(lines), "## ")
+	subsection := findHeading(lines, section+1, sectionEnd, session)
+	if subsection < 0 {
+		at := lastContentLine(lines, section, sectionEnd) + 1
+		return strings.Join(insertLines(lines, at, "", session, "", bullet), "\n")
+	}
+
+	subsectionEnd := nextHeading(lines, subsection+1, sectionEnd, "## ", "### ")
+	at := lastContentLine(lines, subsection, subsectionEnd) + 1
+	if at == subsection+1 {
+		return strings.Join(insertLines(lines, at, "", bullet), "\n")
+	}
+	return strings.Join(insertLines(lines, at, bullet), "\n")
+}
+
+// findHeading returns the index of the line in [from, to) equal to heading, or -1
+func findHeading(lines []string, from, to int, heading string) int {
+	for i := from; i < to; i++ {
+		if strings.TrimSpace(lines[i]) == heading {
+			return i
+		}
+	}
+	return -1
+}
+
+// nextHeading returns the index of the first line in [from, to) starting with
+// one of the prefixes, or to
+func nextHeading(lines []string, from, to int, prefixes ...string) int {
+	for i := from; i < to; i++ {
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(lines[i], prefix) {
+				return i
+			}
+		}
+	}
+	return to
+}
+
+// lastContentLine returns the index of the last non-blank line in [from, to),
+// or from - 1 when there is none
+func lastContentLine(lines []string, from, to int) int {
+	for i := to - 1; i >= from; i-- {
+		if strings.TrimSpace(lines[i]) != "" {
+			return i
+		}
+	}
+	return from - 1
+}
+
+// insertLines inserts lines at index at
+func insertLines(lines []string, at int, inserted ...string) []string {
+	result := make([]string, 0, len(lines)+len(inserted))
+	result = append(result, lines[:at]...)
+	result = append(result, inserted...)
+	return append(result, lines[at:]...)
+}
+
+// writeFileAtomic replaces a file through a temporary file in the same
+// directory, keeping its permissions
+func writeFileAtomic(path string, data []byte) error {
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	tmpFile, err := os.CreateTemp(filepath.Dir(path), ".tchncrt-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	defer os.Remove(tmpPath) // Clean up temp file if we fail
+
+	if _, err := tmpFile.Write(data); err != nil {
+		tmpFile.Close()
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+	if err := os.Chmod(tmpPath, mode); err != nil {
+		return fmt.Errorf("failed to set permissions on temp file: %w", err)
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", path, err)
+	}
+	return nil
+}
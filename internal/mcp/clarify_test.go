@@ -0,0 +1,124 @@
+package mcp
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestAddClarification tests recording answers in a spec's Clarifications section
+func TestAddClarification(t *testing.T) {
+	tests := []struct {
+		name     string
+		spec     string
+		expected string
+	}{
+		{
+			name: "New section before the first second-level heading",
+			spec: "# Feature Specification: Auth\n\n**Status**: Draft\n\n## User Scenarios\n\nStories\n",
+			expected: "# Feature Specification: Auth\n\n**Status**: Draft\n\n## Clarifications\n\n### Session 2026-01-05\n\n" +
+				"- Q: Which IdP? → A: SAML\n\n## User Scenarios\n\nStories\n",
+		},
+		{
+			name:     "New section in a spec without sections",
+			spec:     "# Auth spec\n",
+			expected: "# Auth spec\n\n## Clarifications\n\n### Session 2026-01-05\n\n- Q: Which IdP? → A: SAML\n",
+		},
+		{
+			name: "New session in an existing section",
+			spec: "# Spec\n\n## Clarifications\n\n### Session 2026-01-01\n\n- Q: Old? → A: Yes\n\n## Requirements\n",
+			expected: "# Spec\n\n## Clarifications\n\n### Session 2026-01-01\n\n- Q: Old? → A: Yes\n\n" +
+				"### Session 2026-01-05\n\n- Q: Which IdP? → A: SAML\n\n## Requirements\n",
+		},
+		{
+			name: "Appended to today's session",
+			spec: "# Spec\n\n## Clarifications\n\n### Session 2026-01-05\n\n- Q: First? → A: One\n\n## Requirements\n",
+			expected: "# Spec\n\n## Clarifications\n\n### Session 2026-01-05\n\n- Q: First? → A: One\n" +
+				"- Q: Which IdP? → A: SAML\n\n## Requirements\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := addClarification(tt.spec, "2026-01-05", "Which IdP?", "SAML"); got != tt.expected {
+				t.Errorf("Expected:\n%s\nGot:\n%s", tt.expected, got)
+			}
+		})
+	}
+}
+
+// TestClarifySpecTool tests asking questions and recording the accepted answers
+func TestClarifySpecTool(t *testing.T) {
+	root := newTestWorkspace(t)
+	h := NewHandler()
+	h.SetWorkspaceRoot(root)
+
+	responses := []string{
+		`{"action":"accept","content":{"answer":"SAML"}}`,
+		`{"action":"decline"}`,
+		`{"action":"accept","content":{"answer":"30 days"}}`,
+		`{"action":"cancel"}`,
+		`{"action":"accept","content":{"answer":"never asked"}}`,
+	}
+	specPath := filepath.Join(root, "specs", "001-auth", "spec.md")
+	var asked []ElicitParams
+	session := elicitingSession(t, func(params ElicitParams) string {
+		asked = append(asked, params)
+		// An edit made while the user answers must survive the next save
+		if len(asked) == 2 {
+			data, _ := os.ReadFile(specPath)
+			os.WriteFile(specPath, append(data, "\nEdited while answering\n"...), 0644)
+		}
+		return responses[len(asked)-1]
+	})
+	ctx := ContextWithSession(context.Background(), session)
+
+	result, err := h.CallToolContext(ctx, "clarify_spec", map[string]interface{}{
+		"feature": "001-auth",
+		"questions": []interface{}{
+			map[string]interface{}{"question": "Which identity provider?", "options": []interface{}{"OAuth", "SAML"}, "recommended": "OAuth"},
+			map[string]interface{}{"question": "Support MFA?"},
+			map[string]interface{}{"question": "How long are sessions kept?"},
+			map[string]interface{}{"question": "Audit logins?"},
+			map[string]interface{}{"question": "Lockout threshold?"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if result.IsError {
+		t.Fatalf("Tool failed: %s", resultText(result))
+	}
+
+	if len(asked) != 4 {
+		t.Fatalf("Expected the cancel to end the session after 4 questions, asked %d", len(asked))
+	}
+	if !strings.Contains(asked[0].Message, "Recommended: OAuth") {
+		t.Errorf("Expected the recommendation in the message, got %q", asked[0].Message)
+	}
+	if enum := asked[0].RequestedSchema["properties"].(map[string]interface{})["answer"].(map[string]interface{})["enum"]; enum == nil {
+		t.Error("Expected the options as an enum")
+	}
+
+	data, _ := os.ReadFile(specPath)
+	spec := string(data)
+	for _, expected := range []string{"## Clarifications", "Edited while answering", "- Q: Which identity provider? → A: SAML", "- Q: How long are sessions kept? → A: 30 days"} {
+		if !strings.Contains(spec, expected) {
+			t.Errorf("Expected %q in the spec, got:\n%s", expected, spec)
+		}
+	}
+	if strings.Contains(spec, "MFA") {
+		t.Errorf("Declined questions must not be recorded:\n%s", spec)
+	}
+
+	// Clients without elicitation are told to ask in chat
+	result, err = h.CallToolContext(context.Background(), "clarify_spec", map[string]interface{}{
+		"feature":   "001-auth",
+		"questions": []interface{}{map[string]interface{}{"question": "Anything?"}},
+	})
+	if err != nil || !result.IsError || !strings.Contains(resultText(result), "ask the questions in chat") {
+		t.Errorf("Expected a tool error for a client without elicitation, got %v %+v", err, result)
+	}
+}
@@ -0,0 +1,219 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/lithammer/fuzzysearch/fuzzy"
+)
+
+// Completer suggests values for a prompt argument or resource template
+// variable. value is what the user has typed so far, and arguments holds the
+// values already chosen for the other arguments, when the client sends them.
+// Completers may return every candidate: the handler ranks them against value.
+type Completer func(ctx context.Context, value string, arguments map[string]string) ([]string, error)
+
+// maxCompletionValues is the most values a completion result may carry
+const maxCompletionValues = 100
+
+// taskIDPattern matches task IDs such as T001 in tasks.md
+var taskIDPattern = regexp.MustCompile(`\bT\d{3,}\b`)
+
+// Complete suggests values for an argument of a prompt or a variable of a
+// resource template. References to unknown prompts or templates fail with
+// ErrPromptNotFound or ErrResourceNotFound; arguments without a completer
+// have no suggestions.
+func (h *Handler) Complete(ctx context.Context, ref CompletionReference, argument CompletionArgument, arguments map[string]string) (*Completion, error) {
+	var completer Completer
+
+	switch ref.Type {
+	case CompletionRefPrompt:
+		prompt, exists := h.prompts[ref.Name]
+		if !exists {
+			return nil, fmt.Errorf("%w: %s", ErrPromptNotFound, ref.Name)
+		}
+		for _, arg := range prompt.Arguments {
+			if arg.Name == argument.Name {
+				completer = arg.Completer
+			}
+		}
+
+	case CompletionRefResource:
+		template, exists := h.resourceTemplates[ref.URI]
+		if !exists {
+			return nil, fmt.Errorf("%w: %s", ErrResourceNotFound, ref.URI)
+		}
+		completer = template.Completers[argument.Name]
+
+	default:
+		return nil, fmt.Errorf("unknown completion reference type %q", ref.Type)
+	}
+
+	if completer == nil {
+		return &Completion{Values: []string{}}, nil
+	}
+
+	candidates, err := completer(ctx, argument.Value, arguments)
+	if err != nil {
+		return nil, err
+	}
+
+	values := rankCompletions(argument.Value, candidates)
+	completion := &Completion{Values: values, Total: len(values)}
+	if len(values) > maxCompletionValues {
+		completion.Values = values[:maxCompletionValues]
+		completion.HasMore = true
+	}
+	return completion, nil
+}
+
+// rankCompletions keeps the candidates that fuzzy-match value, ignoring case
+// and diacritics, and orders them with prefix matches first, then by how
+// closely they match. An empty value keeps every candidate in its given order.
+func rankCompletions(value string, candidates []string) []string {
+	if value == "" {
+		return append([]string{}, candidates...)
+	}
+
+	ranks := fuzzy.RankFindNormalizedFold(value, candidates)
+	lowerValue := strings.ToLower(value)
+	sort.SliceStable(ranks, func(i, j int) bool {
+		iPrefix := strings.HasPrefix(strings.ToLower(ranks[i].Target), lowerValue)
+		jPrefix := strings.HasPrefix(strings.ToLower(ranks[j].Target), lowerValue)
+		if iPrefix != jPrefix {
+			return iPrefix
+		}
+		if ranks[i].Distance != ranks[j].Distance {
+			return ranks[i].Distance < ranks[j].Distance
+		}
+		return ranks[i].OriginalIndex < ranks[j].OriginalIndex
+	})
+
+	values := make([]string, 0, len(ranks))
+	for _, rank := range ranks {
+		values = append(values, rank.Target)
+	}
+	return values
+}
+
+// Features returns the names of the feature directories under specs/
+func (w Workspace) Features() []string {
+	if w.Root == "" {
+		return nil
+	}
+	entries, err := os.ReadDir(filepath.Join(w.Root, "specs"))
+	if err != nil {
+		return nil
+	}
+
+	var features []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			features = append(features, entry.Name())
+		}
+	}
+	return features
+}
+
+// Artifacts returns the documents that exist for a feature, or every document
+// name when feature is empty. Names that are not a single path segment have none.
+func (w Workspace) Artifacts(feature string) []string {
+	if feature == "" || w.Root == "" {
+		return append([]string{}, featureDocuments...)
+	}
+	if !validSegment(feature) {
+		return nil
+	}
+
+	var artifacts []string
+	for _, document := range featureDocuments {
+		if _, err := os.Stat(filepath.Join(w.Root, "specs", feature, document+".md")); err == nil {
+			artifacts = append(artifacts, document)
+		}
+	}
+	return artifacts
+}
+
+// TaskIDs returns the IDs of the tasks in a feature's tasks.md, in order
+func (w Workspace) TaskIDs(feature string) []string {
+	if !validSegment(feature) || w.Root == "" {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(w.Root, "specs", feature, "tasks.md"))
+	if err != nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var ids []string
+	for _, id := range taskIDPattern.FindAllString(string(data), -1) {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// completeFeatures suggests feature directory names
+func (h *Handler) completeFeatures(ctx context.Context, value string, arguments map[string]string) ([]string, error) {
+	return h.workspaceFor(ctx).Features(), nil
+}
+
+// completeArtifacts suggests the documents of the feature chosen in arguments
+func (h *Handler) completeArtifacts(ctx context.Context, value string, arguments map[string]string) ([]string, error) {
+	return h.workspaceFor(ctx).Artifacts(arguments["feature"]), nil
+}
+
+// completeContracts suggests the contract files of the feature chosen in arguments
+func (h *Handler) completeContracts(ctx context.Context, value string, arguments map[string]string) ([]string, error) {
+	prefix := featureURI(arguments["feature"], "contracts/")
+	var paths []string
+	for _, resource := range h.workspaceFor(ctx).Resources() {
+		if path, ok := strings.CutPrefix(resource.URI, prefix); ok {
+			if path, err := url.PathUnescape(path); err == nil {
+				paths = append(paths, path)
+			}
+		}
+	}
+	return paths, nil
+}
+
+// completeMemory suggests the documents in memory/
+func (h *Handler) completeMemory(ctx context.Context, value string, arguments map[string]string) ([]string, error) {
+	var documents []string
+	for _, resource := range h.workspaceFor(ctx).Resources() {
+		if document, ok := strings.CutPrefix(resource.URI, memoryURI("")); ok {
+			if document, err := url.PathUnescape(document); err == nil {
+				documents = append(documents, document)
+			}
+		}
+	}
+	return documents, nil
+}
+
+// completeTemplates suggests the names of the embedded templates
+func (h *Handler) completeTemplates(ctx context.Context, value string, arguments map[string]string) ([]string, error) {
+	var names []string
+	for _, resource := range templateResources() {
+		names = append(names, strings.TrimPrefix(resource.URI, ResourceScheme+"://templates/"))
+	}
+	return names, nil
+}
+
+// completeWorkflowInput suggests task IDs of the chosen or current feature,
+// followed by feature names, for the free-text input of command prompts
+func (h *Handler) completeWorkflowInput(ctx context.Context, value string, arguments map[string]string) ([]string, error) {
+	workspace := h.workspaceFor(ctx)
+	feature := arguments["feature"]
+	if feature == "" {
+		feature = workspace.FeatureName
+	}
+	return append(workspace.TaskIDs(feature), workspace.Features()...), nil
+}
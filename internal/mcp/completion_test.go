@@ -0,0 +1,204 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestRankCompletions tests fuzzy matching and ordering of candidates
+func TestRankCompletions(t *testing.T) {
+	candidates := []string{"001-auth", "007-payment-retry", "012-pay-later", "003-api"}
+
+	tests := []struct {
+		value    string
+		expected []string
+	}{
+		{"", candidates},
+		{"pay", []string{"012-pay-later", "007-payment-retry"}},
+		{"PAYMENT", []string{"007-payment-retry"}},
+		{"00", []string{"003-api", "001-auth", "007-payment-retry"}},
+		{"prt", []string{"007-payment-retry"}},
+		{"zzz", []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			values := rankCompletions(tt.value, candidates)
+			if strings.Join(values, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("Expected %v, got %v", tt.expected, values)
+			}
+		})
+	}
+}
+
+// TestWorkspaceCompletionSources tests listing features, artifacts and task IDs
+func TestWorkspaceCompletionSources(t *testing.T) {
+	root := newTestWorkspace(t)
+	writeWorkspaceFile(t, root, "specs/007-payment-retry/tasks.md", "- [ ] T001 Add retry\n- [x] T002 Backoff (after T001)\n- [ ] T010 Docs\n")
+	workspace := Workspace{Root: root}
+
+	if features := strings.Join(workspace.Features(), ","); features != "001-auth,007-payment-retry" {
+		t.Errorf("Unexpected features: %s", features)
+	}
+	if artifacts := strings.Join(workspace.Artifacts("001-auth"), ","); artifacts != "spec,plan,data-model" {
+		t.Errorf("Unexpected artifacts: %s", artifacts)
+	}
+	if artifacts := workspace.Artifacts(""); len(artifacts) != len(featureDocuments) {
+		t.Errorf("Expected every document name without a feature, got %v", artifacts)
+	}
+	if ids := strings.Join(workspace.TaskIDs("007-payment-retry"), ","); ids != "T001,T002,T010" {
+		t.Errorf("Unexpected task IDs: %s", ids)
+	}
+	if ids := workspace.TaskIDs("001-auth"); len(ids) != 0 {
+		t.Errorf("Expected no task IDs without tasks.md, got %v", ids)
+	}
+
+	// Feature names never reach outside specs/
+	writeWorkspaceFile(t, root, "tasks.md", "- [ ] T099 Outside specs\n")
+	writeWorkspaceFile(t, root, "spec.md", "# Outside specs\n")
+	for _, feature := range []string{"..", "../../..", "001-auth/../..", `..\..`} {
+		if artifacts := workspace.Artifacts(feature); artifacts != nil {
+			t.Errorf("Expected no artifacts for %q, got %v", feature, artifacts)
+		}
+		if ids := workspace.TaskIDs(feature); ids != nil {
+			t.Errorf("Expected no task IDs for %q, got %v", feature, ids)
+		}
+	}
+}
+
+// TestCompletionComplete tests completion/complete over JSON-RPC
+func TestCompletionComplete(t *testing.T) {
+	root := newTestWorkspace(t)
+	writeWorkspaceFile(t, root, "specs/007-payment-retry/tasks.md", "- [ ] T001 Add retry\n- [ ] T002 Backoff\n")
+	h := NewHandler()
+	h.SetWorkspaceRoot(root)
+
+	many := make([]string, 150)
+	for i := range many {
+		many[i] = fmt.Sprintf("option-%03d", i)
+	}
+	h.RegisterPrompt(Prompt{
+		Name: "many",
+		Arguments: []PromptArgument{{
+			Name: "choice",
+			Completer: func(ctx context.Context, value string, arguments map[string]string) ([]string, error) {
+				return many, nil
+			},
+		}},
+	})
+	d := NewDispatcher(h)
+
+	tests := []struct {
+		name           string
+		params         CompleteParams
+		expectedValues string
+		expectedCode   int
+		expectHasMore  bool
+	}{
+		{
+			name:           "Template feature",
+			params:         CompleteParams{Ref: CompletionReference{Type: CompletionRefResource, URI: "tchncrt://features/{feature}/{artifact}"}, Argument: CompletionArgument{Name: "feature", Value: "pay"}},
+			expectedValues: "007-payment-retry",
+		},
+		{
+			name: "Template artifact for chosen feature",
+			params: CompleteParams{
+				Ref:      CompletionReference{Type: CompletionRefResource, URI: "tchncrt://features/{feature}/{artifact}"},
+				Argument: CompletionArgument{Name: "artifact", Value: "p"},
+				Context:  &CompletionContext{Arguments: map[string]string{"feature": "001-auth"}},
+			},
+			expectedValues: "plan,spec",
+		},
+		{
+			name: "Prompt input with task IDs",
+			params: CompleteParams{
+				Ref:      CompletionReference{Type: CompletionRefPrompt, Name: "implement"},
+				Argument: CompletionArgument{Name: "user_input", Value: "T00"},
+				Context:  &CompletionContext{Arguments: map[string]string{"feature": "007-payment-retry"}},
+			},
+			expectedValues: "T001,T002",
+		},
+		{
+			name:           "Argument without completer",
+			params:         CompleteParams{Ref: CompletionReference{Type: CompletionRefPrompt, Name: "welcome"}, Argument: CompletionArgument{Name: "name", Value: "a"}},
+			expectedValues: "",
+		},
+		{
+			name:           "Truncated to the maximum",
+			params:         CompleteParams{Ref: CompletionReference{Type: CompletionRefPrompt, Name: "many"}, Argument: CompletionArgument{Name: "choice"}},
+			expectedValues: strings.Join(many[:maxCompletionValues], ","),
+			expectHasMore:  true,
+		},
+		{
+			name:         "Unknown prompt",
+			params:       CompleteParams{Ref: CompletionReference{Type: CompletionRefPrompt, Name: "missing"}, Argument: CompletionArgument{Name: "x"}},
+			expectedCode: CodeInvalidParams,
+		},
+		{
+			name:         "Unknown template",
+			params:       CompleteParams{Ref: CompletionReference{Type: CompletionRefResource, URI: "tchncrt://nowhere/{x}"}, Argument: CompletionArgument{Name: "x"}},
+			expectedCode: CodeInvalidParams,
+		},
+		{
+			name:         "Invalid reference type",
+			params:       CompleteParams{Ref: CompletionReference{Type: "ref/tool", Name: "echo"}, Argument: CompletionArgument{Name: "message"}},
+			expectedCode: CodeInvalidParams,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := d.Handle(context.Background(), newTestRequest(t, i+1, "completion/complete", tt.params))
+			if tt.expectedCode != 0 {
+				if resp.Error == nil || resp.Error.Code != tt.expectedCode {
+					t.Errorf("Expected error code %d, got %#v", tt.expectedCode, resp)
+				}
+				return
+			}
+			if resp.Error != nil {
+				t.Fatalf("Unexpected error: %v", resp.Error)
+			}
+
+			completion := resp.Result.(*CompleteResult).Completion
+			if values := strings.Join(completion.Values, ","); values != tt.expectedValues {
+				t.Errorf("Expected values %q, got %q", tt.expectedValues, values)
+			}
+			if completion.HasMore != tt.expectHasMore {
+				t.Errorf("Expected hasMore %v, got %v", tt.expectHasMore, completion.HasMore)
+			}
+		})
+	}
+}
+
+// TestCompletionWorkflowInputFeature tests that prompt input completion
+// defaults to the feature of the client's workspace
+func TestCompletionWorkflowInputFeature(t *testing.T) {
+	root := newTestWorkspace(t)
+	writeWorkspaceFile(t, root, "specs/007-payment-retry/tasks.md", "- [ ] T001 Add retry\n- [ ] T002 Backoff\n")
+	h := NewHandler()
+	session := NewSession()
+	session.setRoots([]Root{{URI: fileURI(filepath.Join(root, "specs", "007-payment-retry"))}}, nil)
+	ctx := ContextWithSession(context.Background(), session)
+
+	values, err := h.completeWorkflowInput(ctx, "T", nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(values) < 2 || values[0] != "T001" || values[1] != "T002" {
+		t.Errorf("Expected the tasks of 007-payment-retry first, got %v", values)
+	}
+}
+
+// TestCompletionsCapability tests that only clients on 2025-03-26 or later are offered completions
+func TestCompletionsCapability(t *testing.T) {
+	d := NewDispatcher(NewHandler())
+	if d.InitializeResult("2025-06-18").Capabilities.Completions == nil {
+		t.Error("Expected the completions capability for 2025-06-18")
+	}
+	if d.InitializeResult("2024-11-05").Capabilities.Completions != nil {
+		t.Error("Expected no completions capability for 2024-11-05")
+	}
+}
@@ -0,0 +1,277 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the MCP server settings read from a JSON config file
+// (see config.example.json)
+type Config struct {
+	Bind            string `json:"bind"` // Address to listen on in HTTP mode; "" or "0.0.0.0" for every interface
+	Port            int    `json:"port"`
+	Listen          string `json:"listen"`            // "unix:///path" or "host:port", replacing bind and port when set
+	TimeoutSeconds  int    `json:"timeout_seconds"`   // Per-request deadline; 0 disables it
+	StdioWorkers    int    `json:"stdio_workers"`     // Requests handled concurrently in stdio mode
+	MaxMessageBytes int    `json:"max_message_bytes"` // Largest JSON-RPC message accepted
+	WatchIntervalMs int    `json:"watch_interval_ms"` // How often the workspace is polled for changes; 0 disables it
+	WatchDebounceMs int    `json:"watch_debounce_ms"` // Quiet period before changes are reported
+	LogLevel        string `json:"log_level"`         // Minimum MCP log level written to stderr, such as "info"
+
+	SessionIdleTimeoutSeconds int `json:"session_idle_timeout_seconds"` // HTTP sessions unused this long expire; 0 keeps them
+	MaxSessions               int `json:"max_sessions"`                 // Live HTTP sessions at once; 0 leaves them unlimited
+
+	MaxBodyBytes       int             `json:"max_body_bytes"`       // Largest HTTP request body accepted
+	MaxConcurrentTools int             `json:"max_concurrent_tools"` // Tool calls run at once; 0 leaves them unlimited
+	RateLimit          RateLimitConfig `json:"rate_limit"`           // Requests each HTTP session or client may make
+
+	AllowedHosts   []string `json:"allowed_hosts"`   // Host header names accepted besides loopback ones and the bind address
+	AllowedOrigins []string `json:"allowed_origins"` // Browser origins accepted besides loopback ones
+	AllowedRoots   []string `json:"allowed_roots"`   // Directories HTTP clients' roots may select besides the startup workspace
+
+	TLSCert     string `json:"tls_cert"`      // PEM certificate chain; serves HTTPS when set
+	TLSKey      string `json:"tls_key"`       // PEM private key of the certificate
+	TLSClientCA string `json:"tls_client_ca"` // PEM CA bundle; clients must present a certificate it signed
+
+	Auth AuthConfig `json:"auth"` // How HTTP clients authenticate
+
+	Audit AuditConfig `json:"audit"` // Where tool calls and prompt renders are recorded
+}
+
+// AuditConfig holds the settings of the audit log of tool calls and prompt renders
+type AuditConfig struct {
+	Enabled         bool     `json:"enabled"`
+	Dir             string   `json:"dir"`              // Defaults to .tchncrt/audit in the workspace, relative to the config file
+	MaxFileBytes    int      `json:"max_file_bytes"`   // Size at which the log is rotated; 0 never rotates
+	MaxFiles        int      `json:"max_files"`        // Rotated files kept; 0 keeps them all
+	RedactArguments []string `json:"redact_arguments"` // Argument names logged as [REDACTED] besides the built-in secret names
+}
+
+// RateLimitConfig holds the token-bucket settings applied to each HTTP
+// session, or to each client before it has a session
+type RateLimitConfig struct {
+	RequestsPerSecond float64 `json:"requests_per_second"` // Sustained rate; 0 disables rate limiting
+	Burst             int     `json:"burst"`               // Requests that may be made at once after a quiet period
+}
+
+// Authentication modes of the HTTP server
+const (
+	AuthModeNone   = "none"   // Every caller is accepted
+	AuthModeBearer = "bearer" // Callers present one of the configured static tokens
+	AuthModeOAuth  = "oauth"  // Callers present access tokens from an OAuth 2.1 authorization server
+)
+
+// AuthConfig holds the authentication settings of the HTTP server
+type AuthConfig struct {
+	Mode string `json:"mode"` // "none", "bearer" or "oauth"
+
+	// Tokens are the static bearer tokens accepted in bearer mode
+	Tokens []AuthToken `json:"tokens"`
+
+	// Resource is the canonical URL of the MCP endpoint, as clients address it.
+	// Access tokens in oauth mode must be issued for it.
+	Resource             string   `json:"resource"`
+	AuthorizationServers []string `json:"authorization_servers"` // Issuers clients may get tokens from
+	Issuer               string   `json:"issuer"`                // Required iss claim; defaults to the only authorization server
+	Audience             string   `json:"audience"`              // Required aud claim; defaults to the resource
+	JWKSFile             string   `json:"jwks_file"`             // Signing keys of the authorization server, relative to the config file
+	RequiredScopes       []string `json:"required_scopes"`       // Scopes every access token must carry
+}
+
+// AuthToken is a static bearer token and the name requests made with it are attributed to
+type AuthToken struct {
+	Name  string `json:"name"`
+	Token string `json:"token"`
+}
+
+// DefaultConfig returns the settings used when no config file is given
+func DefaultConfig() Config {
+	return Config{
+		Bind:            "127.0.0.1",
+		Port:            8080,
+		TimeoutSeconds:  60,
+		StdioWorkers:    8,
+		MaxMessageBytes: 16 << 20,
+		WatchIntervalMs: 1000,
+		WatchDebounceMs: 250,
+		LogLevel:        "info",
+
+		SessionIdleTimeoutSeconds: 1800,
+		MaxSessions:               1000,
+
+		MaxBodyBytes:       4 << 20,
+		MaxConcurrentTools: 16,
+		RateLimit:          RateLimitConfig{RequestsPerSecond: 10, Burst: 50},
+
+		Auth: AuthConfig{Mode: AuthModeNone},
+
+		Audit: AuditConfig{Enabled: true, MaxFileBytes: 10 << 20},
+	}
+}
+
+// LoadConfig reads a JSON config file, keeping defaults for settings it omits
+func LoadConfig(path string) (Config, error) {
+	cfg := DefaultConfig()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("failed to read config file: %w", err)
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	if cfg.TimeoutSeconds < 0 {
+		return cfg, fmt.Errorf("invalid config file %s: timeout_seconds must not be negative", path)
+	}
+	if cfg.StdioWorkers < 1 {
+		return cfg, fmt.Errorf("invalid config file %s: stdio_workers must be at least 1", path)
+	}
+	if cfg.MaxMessageBytes < 1 {
+		return cfg, fmt.Errorf("invalid config file %s: max_message_bytes must be at least 1", path)
+	}
+	if cfg.WatchIntervalMs < 0 || cfg.WatchDebounceMs < 0 {
+		return cfg, fmt.Errorf("invalid config file %s: watch_interval_ms and watch_debounce_ms must not be negative", path)
+	}
+	if _, err := ParseLogLevel(cfg.LogLevel); err != nil {
+		return cfg, fmt.Errorf("invalid config file %s: log_level: %w", path, err)
+	}
+	if cfg.SessionIdleTimeoutSeconds < 0 {
+		return cfg, fmt.Errorf("invalid config file %s: session_idle_timeout_seconds must not be negative", path)
+	}
+	if cfg.MaxSessions < 0 {
+		return cfg, fmt.Errorf("invalid config file %s: max_sessions must not be negative", path)
+	}
+	if cfg.MaxBodyBytes < 1 {
+		return cfg, fmt.Errorf("invalid config file %s: max_body_bytes must be at least 1", path)
+	}
+	if cfg.MaxConcurrentTools < 0 {
+		return cfg, fmt.Errorf("invalid config file %s: max_concurrent_tools must not be negative", path)
+	}
+	if cfg.RateLimit.RequestsPerSecond < 0 || (cfg.RateLimit.RequestsPerSecond > 0 && cfg.RateLimit.Burst < 1) {
+		return cfg, fmt.Errorf("invalid config file %s: rate_limit needs a positive requests_per_second and a burst of at least 1, or 0 requests_per_second to turn it off", path)
+	}
+	if cfg.Audit.MaxFileBytes < 0 || cfg.Audit.MaxFiles < 0 {
+		return cfg, fmt.Errorf("invalid config file %s: audit max_file_bytes and max_files must not be negative", path)
+	}
+	if strings.ContainsAny(cfg.Bind, "/ ") || (strings.Contains(cfg.Bind, ":") && net.ParseIP(cfg.Bind) == nil) {
+		return cfg, fmt.Errorf("invalid config file %s: bind must be a host name or IP address without a port", path)
+	}
+	for _, origin := range cfg.AllowedOrigins {
+		if u, err := url.Parse(origin); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || strings.Trim(u.Path, "/") != "" {
+			return cfg, fmt.Errorf("invalid config file %s: allowed_origins: %q is not an origin such as https://example.com", path, origin)
+		}
+	}
+	if err := cfg.ValidateListener(); err != nil {
+		return cfg, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+	if err := cfg.Auth.validate(); err != nil {
+		return cfg, fmt.Errorf("invalid config file %s: auth: %w", path, err)
+	}
+	// Files named in the config are relative to it
+	files := []*string{&cfg.Auth.JWKSFile, &cfg.TLSCert, &cfg.TLSKey, &cfg.TLSClientCA, &cfg.Audit.Dir}
+	for i := range cfg.AllowedRoots {
+		files = append(files, &cfg.AllowedRoots[i])
+	}
+	for _, file := range files {
+		if *file != "" && !filepath.IsAbs(*file) {
+			*file = filepath.Join(filepath.Dir(path), *file)
+		}
+	}
+
+	return cfg, nil
+}
+
+// ListenAddress returns the network and address the HTTP server listens on:
+// Listen when set, and Bind and Port otherwise
+func (c Config) ListenAddress() (network, address string, err error) {
+	if c.Listen == "" {
+		return "tcp", net.JoinHostPort(c.Bind, strconv.Itoa(c.Port)), nil
+	}
+	return ParseListenAddress(c.Listen)
+}
+
+// ValidateListener checks the listen address and TLS files, which command-line
+// flags may set after the config file is loaded
+func (c Config) ValidateListener() error {
+	if c.Listen != "" {
+		if _, _, err := ParseListenAddress(c.Listen); err != nil {
+			return fmt.Errorf("listen: %w", err)
+		}
+	}
+	return c.validateTLS()
+}
+
+// validateTLS checks that the TLS files come as a usable set
+func (c Config) validateTLS() error {
+	if (c.TLSCert == "") != (c.TLSKey == "") {
+		return errors.New("tls_cert and tls_key must be set together")
+	}
+	if c.TLSClientCA != "" && c.TLSCert == "" {
+		return errors.New("tls_client_ca requires tls_cert and tls_key")
+	}
+	return nil
+}
+
+// RequestTimeout returns the deadline applied to each request, or 0 for none
+func (c Config) RequestTimeout() time.Duration {
+	return time.Duration(c.TimeoutSeconds) * time.Second
+}
+
+// WatchInterval returns how often the workspace is polled for changes, or 0 when watching is off
+func (c Config) WatchInterval() time.Duration {
+	return time.Duration(c.WatchIntervalMs) * time.Millisecond
+}
+
+// WatchDebounce returns how long the workspace must stay unchanged before changes are reported
+func (c Config) WatchDebounce() time.Duration {
+	return time.Duration(c.WatchDebounceMs) * time.Millisecond
+}
+
+// SessionIdleTimeout returns how long an HTTP session may go unused before it expires, or 0 for never
+func (c Config) SessionIdleTimeout() time.Duration {
+	return time.Duration(c.SessionIdleTimeoutSeconds) * time.Second
+}
+
+// validate checks that the settings of the selected mode are complete
+func (a AuthConfig) validate() error {
+	switch a.Mode {
+	case AuthModeNone:
+		if len(a.Tokens) > 0 || a.Resource != "" {
+			return fmt.Errorf("tokens and resource are ignored unless mode is %q or %q", AuthModeBearer, AuthModeOAuth)
+		}
+		return nil
+	case AuthModeBearer:
+		if len(a.Tokens) == 0 {
+			return errors.New("bearer mode requires at least one token")
+		}
+		for i, token := range a.Tokens {
+			if len(token.Token) < 16 {
+				return fmt.Errorf("token %d must be at least 16 characters", i+1)
+			}
+		}
+		return nil
+	case AuthModeOAuth:
+		if u, err := url.Parse(a.Resource); err != nil || !u.IsAbs() || u.Host == "" || u.Fragment != "" {
+			return errors.New("oauth mode requires resource to be the absolute URL of the MCP endpoint")
+		}
+		if len(a.AuthorizationServers) == 0 {
+			return errors.New("oauth mode requires at least one authorization server")
+		}
+		if a.Issuer == "" && len(a.AuthorizationServers) > 1 {
+			return errors.New("issuer is required with more than one authorization server")
+		}
+		if a.JWKSFile == "" {
+			return errors.New("oauth mode requires jwks_file")
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown mode %q (use %q, %q or %q)", a.Mode, AuthModeNone, AuthModeBearer, AuthModeOAuth)
+}
@@ -0,0 +1,226 @@
+package mcp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestLoadConfig tests reading server settings from a JSON config file
+func TestLoadConfig(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		name            string
+		content         string
+		expectError     bool
+		expectedPort    int
+		expectedTimeout time.Duration
+	}{
+		{
+			name:            "Full config",
+			content:         `{"port": 9090, "timeout_seconds": 5, "log_level": "debug"}`,
+			expectedPort:    9090,
+			expectedTimeout: 5 * time.Second,
+		},
+		{
+			name:            "Defaults for missing settings",
+			content:         `{"port": 9090}`,
+			expectedPort:    9090,
+			expectedTimeout: 60 * time.Second,
+		},
+		{
+			name:            "Timeout disabled",
+			content:         `{"timeout_seconds": 0}`,
+			expectedPort:    8080,
+			expectedTimeout: 0,
+		},
+		{
+			name:        "Negative timeout",
+			content:     `{"timeout_seconds": -1}`,
+			expectError: true,
+		},
+		{
+			name:        "No stdio workers",
+			content:     `{"stdio_workers": 0}`,
+			expectError: true,
+		},
+		{
+			name:        "No message size",
+			content:     `{"max_message_bytes": 0}`,
+			expectError: true,
+		},
+		{
+			name:        "Negative watch interval",
+			content:     `{"watch_interval_ms": -1}`,
+			expectError: true,
+		},
+		{
+			name:        "Unknown log level",
+			content:     `{"log_level": "verbose"}`,
+			expectError: true,
+		},
+		{
+			name:        "Negative session idle timeout",
+			content:     `{"session_idle_timeout_seconds": -1}`,
+			expectError: true,
+		},
+		{
+			name:        "Negative session limit",
+			content:     `{"max_sessions": -1}`,
+			expectError: true,
+		},
+		{
+			name:        "Bind address with a port",
+			content:     `{"bind": "127.0.0.1:8080"}`,
+			expectError: true,
+		},
+		{
+			name:        "Allowed origin with a path",
+			content:     `{"allowed_origins": ["https://app.example.com/mcp"]}`,
+			expectError: true,
+		},
+		{
+			name:        "No body size",
+			content:     `{"max_body_bytes": 0}`,
+			expectError: true,
+		},
+		{
+			name:        "Negative tool concurrency",
+			content:     `{"max_concurrent_tools": -1}`,
+			expectError: true,
+		},
+		{
+			name:        "Rate limit without a burst",
+			content:     `{"rate_limit": {"requests_per_second": 5, "burst": 0}}`,
+			expectError: true,
+		},
+		{
+			name:            "Rate limiting off",
+			content:         `{"rate_limit": {"requests_per_second": 0}}`,
+			expectedPort:    8080,
+			expectedTimeout: 60 * time.Second,
+		},
+		{
+			name:        "Relative unix socket",
+			content:     `{"listen": "unix://technocrat.sock"}`,
+			expectError: true,
+		},
+		{
+			name:        "TLS key without a certificate",
+			content:     `{"tls_key": "server.key"}`,
+			expectError: true,
+		},
+		{
+			name:        "Client CA without a certificate",
+			content:     `{"tls_client_ca": "clients.pem"}`,
+			expectError: true,
+		},
+		{
+			name:        "Negative audit file count",
+			content:     `{"audit": {"max_files": -1}}`,
+			expectError: true,
+		},
+		{
+			name:            "Audit off",
+			content:         `{"audit": {"enabled": false}}`,
+			expectedPort:    8080,
+			expectedTimeout: 60 * time.Second,
+		},
+		{
+			name:            "Bearer auth",
+			content:         `{"auth": {"mode": "bearer", "tokens": [{"name": "ci", "token": "0123456789abcdef"}]}}`,
+			expectedPort:    8080,
+			expectedTimeout: 60 * time.Second,
+		},
+		{
+			name:        "Bearer auth without tokens",
+			content:     `{"auth": {"mode": "bearer"}}`,
+			expectError: true,
+		},
+		{
+			name:        "Short bearer token",
+			content:     `{"auth": {"mode": "bearer", "tokens": [{"token": "secret"}]}}`,
+			expectError: true,
+		},
+		{
+			name:        "Auth tokens without a mode",
+			content:     `{"auth": {"tokens": [{"token": "0123456789abcdef"}]}}`,
+			expectError: true,
+		},
+		{
+			name:        "OAuth without a resource",
+			content:     `{"auth": {"mode": "oauth", "authorization_servers": ["https://auth.example.com"], "jwks_file": "jwks.json"}}`,
+			expectError: true,
+		},
+		{
+			name:        "Unknown auth mode",
+			content:     `{"auth": {"mode": "basic"}}`,
+			expectError: true,
+		},
+		{
+			name:        "Invalid JSON",
+			content:     `{"port":`,
+			expectError: true,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, "config"+string(rune('a'+i))+".json")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("Failed to write config: %v", err)
+			}
+
+			cfg, err := LoadConfig(path)
+			if tt.expectError {
+				if err == nil {
+					t.Error("Expected an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadConfig failed: %v", err)
+			}
+			if cfg.Port != tt.expectedPort {
+				t.Errorf("Expected port %d, got %d", tt.expectedPort, cfg.Port)
+			}
+			if cfg.RequestTimeout() != tt.expectedTimeout {
+				t.Errorf("Expected timeout %s, got %s", tt.expectedTimeout, cfg.RequestTimeout())
+			}
+		})
+	}
+
+	if _, err := LoadConfig(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+}
+
+// TestValidateListener tests checking listen and TLS settings set after loading
+func TestValidateListener(t *testing.T) {
+	tests := []struct {
+		name        string
+		cfg         Config
+		expectError bool
+	}{
+		{name: "Defaults", cfg: DefaultConfig()},
+		{name: "Unix socket", cfg: Config{Listen: "unix:///tmp/technocrat.sock"}},
+		{name: "Relative socket path", cfg: Config{Listen: "unix://technocrat.sock"}, expectError: true},
+		{name: "TLS", cfg: Config{TLSCert: "cert.pem", TLSKey: "key.pem", TLSClientCA: "ca.pem"}},
+		{name: "Certificate without key", cfg: Config{TLSCert: "cert.pem"}, expectError: true},
+		{name: "Client CA without certificate", cfg: Config{TLSClientCA: "ca.pem"}, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.ValidateListener()
+			if tt.expectError && err == nil {
+				t.Error("Expected an error")
+			}
+			if !tt.expectError && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
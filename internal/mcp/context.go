@@ -1,9 +1,11 @@
 package mcp
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 )
 
 // WorkspaceContext holds detected workspace information
@@ -16,13 +18,33 @@ type WorkspaceContext struct {
 // DetectWorkspaceContext analyzes the current working directory to extract
 // project and feature context information
 func DetectWorkspaceContext() WorkspaceContext {
-	ctx := WorkspaceContext{}
-
 	// Get current working directory
 	cwd, err := os.Getwd()
 	if err != nil {
-		return ctx // Return empty context on error
+		return WorkspaceContext{} // Return empty context on error
+	}
+
+	start := time.Now()
+	defer func() { metrics.workspaceDetect.observe(time.Since(start), "cwd") }()
+	return detectWorkspaceContext(cwd)
+}
+
+// WorkspaceFromContext returns the workspace of the client behind a request:
+// the project found among its roots when it shared any, and the one around
+// the working directory otherwise. Tools and prompts that touch project files
+// should use it rather than DetectWorkspaceContext.
+func WorkspaceFromContext(ctx context.Context) WorkspaceContext {
+	if session, ok := SessionFromContext(ctx); ok {
+		if workspace, ok := session.Workspace(); ok {
+			return workspace
+		}
 	}
+	return DetectWorkspaceContext()
+}
+
+// detectWorkspaceContext extracts project and feature information for cwd
+func detectWorkspaceContext(cwd string) WorkspaceContext {
+	ctx := WorkspaceContext{}
 
 	// Find workspace root (look for memory/ directory or .git/)
 	ctx.Root = findWorkspaceRoot(cwd)
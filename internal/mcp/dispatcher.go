@@ -0,0 +1,666 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+)
+
+// MethodHandler handles the params of a single JSON-RPC method and returns its result
+type MethodHandler func(ctx context.Context, params json.RawMessage) (interface{}, error)
+
+// NotificationHandler handles the params of a JSON-RPC notification
+type NotificationHandler func(ctx context.Context, params json.RawMessage)
+
+// Dispatcher routes JSON-RPC requests to MCP method handlers independently of the transport
+type Dispatcher struct {
+	handler        *Handler
+	methods        map[string]MethodHandler
+	notifications  map[string]NotificationHandler
+	requestTimeout time.Duration
+
+	// toolSlots holds a token for each running tool call; nil leaves them unlimited
+	toolSlots chan struct{}
+
+	// watchingResources reports whether a watcher sends resource change notifications
+	watchingResources bool
+
+	// watchers, when set, watch the workspaces of sessions that subscribe to
+	// resources; servers with a single session run their own watcher instead
+	watchers *sharedWatchers
+
+	// allowedRoots are the directories besides the handler's workspace that
+	// client roots may select when restrictRoots is set
+	allowedRoots  []string
+	restrictRoots bool
+
+	// audit records tool calls and prompt renders; nil turns auditing off
+	audit     *AuditLog
+	transport string
+
+	mu       sync.Mutex
+	inflight map[inflightKey]*inflightRequest
+	sessions map[*Session]struct{}
+}
+
+// inflightKey identifies a running request; ids are only unique within a session
+type inflightKey struct {
+	session *Session
+	id      string
+}
+
+// inflightRequest tracks how to stop a running request
+type inflightRequest struct {
+	cancel          context.CancelFunc
+	cancelledByPeer bool
+}
+
+// NewDispatcher creates a dispatcher serving the given handler
+func NewDispatcher(handler *Handler) *Dispatcher {
+	d := &Dispatcher{
+		handler:       handler,
+		methods:       make(map[string]MethodHandler),
+		notifications: make(map[string]NotificationHandler),
+		inflight:      make(map[inflightKey]*inflightRequest),
+		sessions:      make(map[*Session]struct{}),
+	}
+
+	d.methods["initialize"] = d.initialize
+	d.methods["ping"] = d.ping
+	d.methods["tools/list"] = d.listTools
+	d.methods["tools/call"] = d.callTool
+	d.methods["resources/list"] = d.listResources
+	d.methods["resources/read"] = d.readResource
+	d.methods["resources/templates/list"] = d.listResourceTemplates
+	d.methods["resources/subscribe"] = d.subscribe
+	d.methods["resources/unsubscribe"] = d.unsubscribe
+	d.methods["prompts/list"] = d.listPrompts
+	d.methods["prompts/get"] = d.getPrompt
+	d.methods["completion/complete"] = d.complete
+	d.methods["logging/setLevel"] = d.setLevel
+
+	d.notifications["notifications/initialized"] = d.initialized
+	d.notifications["notifications/cancelled"] = d.cancelled
+	d.notifications["notifications/roots/list_changed"] = d.rootsListChanged
+
+	return d
+}
+
+// SetRequestTimeout sets the deadline applied to every request; 0 disables it
+func (d *Dispatcher) SetRequestTimeout(timeout time.Duration) {
+	d.requestTimeout = timeout
+}
+
+// SetAllowedRoots limits the workspaces client roots may select to the
+// handler's workspace and dirs; roots elsewhere are ignored
+func (d *Dispatcher) SetAllowedRoots(dirs []string) {
+	d.allowedRoots = dirs
+	d.restrictRoots = true
+}
+
+// rootDirs returns the directories client roots may select, or nil when any
+// root is followed
+func (d *Dispatcher) rootDirs() []string {
+	if !d.restrictRoots {
+		return nil
+	}
+	return append([]string{d.handler.workspace.Root}, d.allowedRoots...)
+}
+
+// SetMaxConcurrentTools caps the tool calls run at once; calls beyond the cap
+// fail with CodeServerBusy. 0 removes the cap.
+func (d *Dispatcher) SetMaxConcurrentTools(max int) {
+	d.toolSlots = nil
+	if max > 0 {
+		d.toolSlots = make(chan struct{}, max)
+	}
+}
+
+// acquireToolSlot reserves a slot for a tool call without waiting. It reports
+// false when every slot is taken; otherwise release must be called when the
+// call completes.
+func (d *Dispatcher) acquireToolSlot() (release func(), ok bool) {
+	if d.toolSlots == nil {
+		return func() {}, true
+	}
+	select {
+	case d.toolSlots <- struct{}{}:
+		return func() { <-d.toolSlots }, true
+	default:
+		return nil, false
+	}
+}
+
+// HandleMessage processes a raw JSON-RPC payload, which may be a single message
+// or a batch, and returns the encoded reply. It returns nil when nothing needs to be
+// sent back, i.e. when the payload held only notifications and responses.
+func (d *Dispatcher) HandleMessage(ctx context.Context, data []byte) []byte {
+	if !json.Valid(data) {
+		return encodeReply(newErrorResponse(nil, NewError(CodeParseError, "Parse error")))
+	}
+
+	if !isBatch(data) {
+		response := d.handleRaw(ctx, data)
+		if response == nil {
+			return nil
+		}
+		return encodeReply(response)
+	}
+
+	var batch []json.RawMessage
+	if err := json.Unmarshal(data, &batch); err != nil || len(batch) == 0 {
+		return encodeReply(newErrorResponse(nil, NewError(CodeInvalidRequest, "Invalid request: empty batch")))
+	}
+
+	responses := make([]*Response, 0, len(batch))
+	for _, raw := range batch {
+		if response := d.handleRaw(ctx, raw); response != nil {
+			responses = append(responses, response)
+		}
+	}
+
+	if len(responses) == 0 {
+		return nil
+	}
+	return encodeReply(responses)
+}
+
+// handleRaw processes a single raw message within a payload
+func (d *Dispatcher) handleRaw(ctx context.Context, data json.RawMessage) *Response {
+	msg, rpcErr := parseMessage(data)
+	if rpcErr != nil {
+		return newErrorResponse(msg.ID, rpcErr)
+	}
+
+	if msg.isResponse() {
+		if session, ok := SessionFromContext(ctx); ok && session.deliver(msg) {
+			return nil
+		}
+		logger.DebugContext(ctx, "Ignoring unsolicited client response", "id", string(msg.ID))
+		return nil
+	}
+
+	return d.Handle(ctx, msg.request())
+}
+
+// encodeReply marshals a response or batch of responses
+func encodeReply(v interface{}) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		logger.Error("Failed to encode response", "error", err)
+		data, _ = json.Marshal(newErrorResponse(nil, NewError(CodeInternalError, "Internal error: failed to encode response")))
+	}
+	return data
+}
+
+// Handle dispatches a request and returns its response. Notifications never
+// produce a response, and neither do requests the client cancelled, so nil is
+// returned for them.
+func (d *Dispatcher) Handle(ctx context.Context, req *Request) *Response {
+	if req.IsNotification() {
+		d.handleNotification(ctx, req)
+		return nil
+	}
+
+	if req.JSONRPC != jsonRPCVersion || req.Method == "" {
+		return newErrorResponse(req.ID, NewError(CodeInvalidRequest, "Invalid request: missing method"))
+	}
+
+	method, exists := d.methods[req.Method]
+	if !exists {
+		return newErrorResponse(req.ID, NewError(CodeMethodNotFound, "Method not found: %s", req.Method))
+	}
+
+	timeout := d.timeoutFor(req)
+	ctx, finish := d.track(ctx, req, timeout)
+	if token := progressToken(req.Params); token != nil {
+		ctx = contextWithProgress(ctx, token)
+	}
+	start := time.Now()
+	result, err := method(ctx, req.Params)
+	metrics.requestDuration.observe(time.Since(start), req.Method)
+	metrics.requests.inc(req.Method, outcomeOf(err))
+	if cancelledByPeer := finish(); cancelledByPeer {
+		logger.DebugContext(ctx, "Dropping response to cancelled request", "id", string(req.ID), "method", req.Method)
+		return nil
+	}
+
+	if err != nil {
+		var rpcErr *Error
+		switch {
+		case errors.As(err, &rpcErr):
+			return newErrorResponse(req.ID, rpcErr)
+		case errors.Is(err, context.DeadlineExceeded):
+			return newErrorResponse(req.ID, NewError(CodeInternalError, "Request timed out after %s", timeout))
+		case errors.Is(err, context.Canceled):
+			return newErrorResponse(req.ID, NewError(CodeInternalError, "Request cancelled: server is shutting down"))
+		}
+		return newErrorResponse(req.ID, NewError(CodeInternalError, "Internal error: %v", err))
+	}
+
+	if result == nil {
+		result = struct{}{}
+	}
+	return newResultResponse(req.ID, result)
+}
+
+// timeoutFor returns the deadline for a request: the tool's own for calls to a
+// tool that sets one, and the request timeout otherwise. A disabled request
+// timeout stays disabled.
+func (d *Dispatcher) timeoutFor(req *Request) time.Duration {
+	if d.requestTimeout <= 0 || req.Method != "tools/call" {
+		return d.requestTimeout
+	}
+	var p CallToolParams
+	if err := json.Unmarshal(req.Params, &p); err != nil {
+		return d.requestTimeout
+	}
+	if tool, exists := d.handler.tools[p.Name]; exists && tool.Timeout > 0 {
+		return tool.Timeout
+	}
+	return d.requestTimeout
+}
+
+// track derives the context a request runs under, applying the timeout
+// and registering it so notifications/cancelled can stop it. The returned
+// function must be called when the request completes; it reports whether the
+// client cancelled the request.
+func (d *Dispatcher) track(ctx context.Context, req *Request, timeout time.Duration) (context.Context, func() bool) {
+	var cancel context.CancelFunc
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+
+	session, _ := SessionFromContext(ctx)
+	key := inflightKey{session: session, id: string(req.ID)}
+	entry := &inflightRequest{cancel: cancel}
+
+	d.mu.Lock()
+	d.inflight[key] = entry
+	d.mu.Unlock()
+
+	return ctx, func() bool {
+		d.mu.Lock()
+		if d.inflight[key] == entry {
+			delete(d.inflight, key)
+		}
+		cancelledByPeer := entry.cancelledByPeer
+		d.mu.Unlock()
+
+		cancel()
+		return cancelledByPeer
+	}
+}
+
+// cancelRequest stops a running request on behalf of the client
+func (d *Dispatcher) cancelRequest(session *Session, id json.RawMessage) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	entry, exists := d.inflight[inflightKey{session: session, id: string(id)}]
+	if !exists {
+		return false
+	}
+	entry.cancelledByPeer = true
+	entry.cancel()
+	return true
+}
+
+// handleNotification runs the handler for a notification, ignoring unknown ones
+func (d *Dispatcher) handleNotification(ctx context.Context, req *Request) {
+	if notify, exists := d.notifications[req.Method]; exists {
+		notify(ctx, req.Params)
+	}
+}
+
+// attachSession registers a connected session to receive notifications that
+// belong to no request
+func (d *Dispatcher) attachSession(session *Session) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.sessions[session] = struct{}{}
+}
+
+// detachSession stops notifications to a session that disconnected
+func (d *Dispatcher) detachSession(session *Session) {
+	d.mu.Lock()
+	delete(d.sessions, session)
+	d.mu.Unlock()
+
+	if d.watchers != nil {
+		d.watchers.unwatch(session)
+	}
+}
+
+// attachedSessions returns the sessions currently connected
+func (d *Dispatcher) attachedSessions() []*Session {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	sessions := make([]*Session, 0, len(d.sessions))
+	for session := range d.sessions {
+		sessions = append(sessions, session)
+	}
+	return sessions
+}
+
+// InitializeResult returns the server's answer to an initialize request
+// that negotiated the given protocol version
+func (d *Dispatcher) InitializeResult(version string) *InitializeResult {
+	result := &InitializeResult{
+		ProtocolVersion: version,
+		Capabilities: ServerCapabilities{
+			Tools:     &ToolsCapability{},
+			Resources: &ResourcesCapability{Subscribe: d.watchingResources, ListChanged: d.watchingResources},
+			Prompts:   &PromptsCapability{},
+			Logging:   &LoggingCapability{},
+		},
+		ServerInfo: Implementation{
+			Name:    ServerName,
+			Version: ServerVersion,
+		},
+	}
+	if protocolFeatures[version].Completions {
+		result.Capabilities.Completions = &CompletionsCapability{}
+	}
+	return result
+}
+
+// initialize handles the initialize method
+func (d *Dispatcher) initialize(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p InitializeParams
+	if err := decodeParams(params, &p); err != nil {
+		return nil, err
+	}
+
+	version := NegotiateProtocolVersion(p.ProtocolVersion)
+	if session, ok := SessionFromContext(ctx); ok {
+		session.begin(version, p)
+	}
+
+	if version != p.ProtocolVersion {
+		logger.WarnContext(ctx, "Client requested unsupported protocol version",
+			"client", p.ClientInfo.Name, "requested", p.ProtocolVersion, "offered", version)
+	}
+
+	return d.InitializeResult(version), nil
+}
+
+// ping handles the ping method
+func (d *Dispatcher) ping(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	return struct{}{}, nil
+}
+
+// initialized handles the notifications/initialized notification
+func (d *Dispatcher) initialized(ctx context.Context, params json.RawMessage) {
+	if session, ok := SessionFromContext(ctx); ok {
+		session.markInitialized()
+		logger.InfoContext(ctx, "MCP client completed initialization",
+			"client", session.ClientInfo().Name, "protocol", session.ProtocolVersion())
+
+		if session.ClientSupports("roots") {
+			// Over HTTP the request needs the client's GET stream, which may not
+			// be open yet; refreshRoots then defers it until the stream opens
+			go d.refreshRoots(session)
+		}
+	}
+}
+
+// cancelled handles the notifications/cancelled notification
+func (d *Dispatcher) cancelled(ctx context.Context, params json.RawMessage) {
+	var p CancelledParams
+	if err := decodeParams(params, &p); err != nil || len(p.RequestID) == 0 {
+		return
+	}
+
+	session, _ := SessionFromContext(ctx)
+	if !d.cancelRequest(session, p.RequestID) {
+		logger.DebugContext(ctx, "Ignoring cancellation of unknown or completed request", "id", string(p.RequestID))
+		return
+	}
+	logger.InfoContext(ctx, "MCP client cancelled request", "id", string(p.RequestID), "reason", p.Reason)
+}
+
+// listTools handles the tools/list method
+func (d *Dispatcher) listTools(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	tools := d.handler.ListTools()
+	if !featuresFromContext(ctx).StructuredToolOutput {
+		for i := range tools {
+			tools[i].OutputSchema = nil
+		}
+	}
+	return &ListToolsResult{Tools: tools}, nil
+}
+
+// callTool handles the tools/call method
+func (d *Dispatcher) callTool(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p CallToolParams
+	if err := decodeRequiredParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Name == "" {
+		return nil, NewError(CodeInvalidParams, "Missing tool name")
+	}
+
+	release, ok := d.acquireToolSlot()
+	if !ok {
+		logger.WarnContext(ctx, "Rejecting tool call: too many running", "tool", p.Name, "max", cap(d.toolSlots))
+		return nil, NewError(CodeServerBusy, "Server busy: %d tool calls are already running, retry later", cap(d.toolSlots))
+	}
+	defer release()
+
+	// Tool execution failures come back in the result so the model can see them
+	start := time.Now()
+	result, err := d.handler.CallToolContext(ctx, p.Name, p.Arguments)
+	d.record(ctx, "tools/call", p.Name, p.Arguments, start, toolError(result, err))
+	if errors.Is(err, ErrToolNotFound) {
+		return nil, NewError(CodeInvalidParams, "Unknown tool: %s", p.Name)
+	}
+	var schemaErr *SchemaError
+	if errors.Is(err, ErrInvalidArguments) && errors.As(err, &schemaErr) {
+		rpcErr := NewError(CodeInvalidParams, "Invalid arguments for tool %s: %v", p.Name, schemaErr)
+		rpcErr.Data = map[string]interface{}{"violations": schemaErr.Violations}
+		return nil, rpcErr
+	}
+	if errors.Is(err, ErrInvalidToolOutput) {
+		return nil, NewError(CodeInternalError, "%v", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return adaptToolResult(result, featuresFromContext(ctx)), nil
+}
+
+// listResources handles the resources/list method
+func (d *Dispatcher) listResources(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	return &ListResourcesResult{Resources: d.handler.ListResourcesContext(ctx)}, nil
+}
+
+// readResource handles the resources/read method
+func (d *Dispatcher) readResource(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p ReadResourceParams
+	if err := decodeRequiredParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.URI == "" {
+		return nil, NewError(CodeInvalidParams, "Missing resource URI")
+	}
+
+	result, err := d.handler.ReadResourceContext(ctx, p.URI)
+	if errors.Is(err, ErrResourceNotFound) {
+		return nil, &Error{Code: CodeResourceNotFound, Message: "Resource not found", Data: map[string]string{"uri": p.URI}}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// subscribe handles the resources/subscribe method
+func (d *Dispatcher) subscribe(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	session, uri, err := d.subscriptionTarget(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	if !d.handler.resourceExists(uri) {
+		return nil, &Error{Code: CodeResourceNotFound, Message: "Resource not found", Data: map[string]string{"uri": uri}}
+	}
+
+	session.subscribe(uri)
+	if d.watchers != nil {
+		d.watchers.watch(session)
+	}
+	return struct{}{}, nil
+}
+
+// unsubscribe handles the resources/unsubscribe method
+func (d *Dispatcher) unsubscribe(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	session, uri, err := d.subscriptionTarget(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	session.unsubscribe(uri)
+	return struct{}{}, nil
+}
+
+// subscriptionTarget decodes the URI of a subscription request and finds the session it belongs to
+func (d *Dispatcher) subscriptionTarget(ctx context.Context, params json.RawMessage) (*Session, string, error) {
+	var p SubscribeParams
+	if err := decodeRequiredParams(params, &p); err != nil {
+		return nil, "", err
+	}
+	if p.URI == "" {
+		return nil, "", NewError(CodeInvalidParams, "Missing resource URI")
+	}
+
+	session, ok := SessionFromContext(ctx)
+	if !ok {
+		return nil, "", NewError(CodeInternalError, "Subscriptions require a session")
+	}
+	return session, p.URI, nil
+}
+
+// listResourceTemplates handles the resources/templates/list method
+func (d *Dispatcher) listResourceTemplates(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	return &ListResourceTemplatesResult{ResourceTemplates: d.handler.ListResourceTemplates()}, nil
+}
+
+// listPrompts handles the prompts/list method
+func (d *Dispatcher) listPrompts(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	return &ListPromptsResult{Prompts: d.handler.ListPrompts()}, nil
+}
+
+// getPrompt handles the prompts/get method
+func (d *Dispatcher) getPrompt(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p GetPromptParams
+	if err := decodeRequiredParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Name == "" {
+		return nil, NewError(CodeInvalidParams, "Missing prompt name")
+	}
+
+	start := time.Now()
+	result, err := d.handler.GetPromptContext(ctx, p.Name, p.Arguments)
+	d.record(ctx, "prompts/get", p.Name, p.Arguments, start, err)
+	if errors.Is(err, ErrPromptNotFound) {
+		return nil, NewError(CodeInvalidParams, "Unknown prompt: %s", p.Name)
+	}
+	return result, err
+}
+
+// setLevel handles the logging/setLevel method
+func (d *Dispatcher) setLevel(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p SetLevelParams
+	if err := decodeRequiredParams(params, &p); err != nil {
+		return nil, err
+	}
+	level, err := ParseLogLevel(p.Level)
+	if err != nil {
+		return nil, NewError(CodeInvalidParams, "Invalid log level: %q", p.Level)
+	}
+
+	session, ok := SessionFromContext(ctx)
+	if !ok {
+		return nil, NewError(CodeInternalError, "Logging requires a session")
+	}
+	session.setLogLevel(level)
+	return struct{}{}, nil
+}
+
+// complete handles the completion/complete method
+func (d *Dispatcher) complete(ctx context.Context, params json.RawMessage) (interface{}, error) {
+	var p CompleteParams
+	if err := decodeRequiredParams(params, &p); err != nil {
+		return nil, err
+	}
+	if p.Ref.Type != CompletionRefPrompt && p.Ref.Type != CompletionRefResource {
+		return nil, NewError(CodeInvalidParams, "Invalid completion reference type: %q", p.Ref.Type)
+	}
+	if p.Argument.Name == "" {
+		return nil, NewError(CodeInvalidParams, "Missing argument name")
+	}
+
+	var arguments map[string]string
+	if p.Context != nil {
+		arguments = p.Context.Arguments
+	}
+	if arguments == nil {
+		arguments = map[string]string{}
+	}
+
+	completion, err := d.handler.Complete(ctx, p.Ref, p.Argument, arguments)
+	switch {
+	case errors.Is(err, ErrPromptNotFound):
+		return nil, NewError(CodeInvalidParams, "Unknown prompt: %s", p.Ref.Name)
+	case errors.Is(err, ErrResourceNotFound):
+		return nil, NewError(CodeInvalidParams, "Unknown resource template: %s", p.Ref.URI)
+	case err != nil:
+		return nil, err
+	}
+	return &CompleteResult{Completion: *completion}, nil
+}
+
+// progressToken returns the _meta.progressToken of request params, or nil when absent
+func progressToken(params json.RawMessage) json.RawMessage {
+	if len(params) == 0 {
+		return nil
+	}
+
+	var p struct {
+		Meta *RequestMeta `json:"_meta"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil || p.Meta == nil {
+		return nil
+	}
+
+	token := p.Meta.ProgressToken
+	if len(token) == 0 || string(token) == "null" || !validID(token) {
+		return nil
+	}
+	return token
+}
+
+// decodeParams unmarshals optional method params into v
+func decodeParams(params json.RawMessage, v interface{}) error {
+	if len(params) == 0 || string(params) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return NewError(CodeInvalidParams, "Invalid params: %v", err)
+	}
+	return nil
+}
+
+// decodeRequiredParams unmarshals method params into v, rejecting missing params
+func decodeRequiredParams(params json.RawMessage, v interface{}) error {
+	if len(params) == 0 || string(params) == "null" {
+		return NewError(CodeInvalidParams, "Invalid params")
+	}
+	return decodeParams(params, v)
+}
@@ -0,0 +1,248 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+// newTestRequest builds a typed request with the given id, method and params
+func newTestRequest(t *testing.T, id interface{}, method string, params interface{}) *Request {
+	t.Helper()
+
+	req := &Request{
+		JSONRPC: jsonRPCVersion,
+		Method:  method,
+	}
+	if id != nil {
+		data, err := json.Marshal(id)
+		if err != nil {
+			t.Fatalf("Failed to marshal id: %v", err)
+		}
+		req.ID = data
+	}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			t.Fatalf("Failed to marshal params: %v", err)
+		}
+		req.Params = data
+	}
+	return req
+}
+
+// TestDispatcherMethods tests every MCP method through the shared dispatcher
+func TestDispatcherMethods(t *testing.T) {
+	d := NewDispatcher(NewHandler())
+
+	tests := []struct {
+		name         string
+		method       string
+		params       interface{}
+		expectedCode int
+		checkResult  func(t *testing.T, result interface{})
+	}{
+		{
+			name:   "initialize",
+			method: "initialize",
+			params: InitializeParams{ProtocolVersion: LatestProtocolVersion},
+			checkResult: func(t *testing.T, result interface{}) {
+				initResult, ok := result.(*InitializeResult)
+				if !ok {
+					t.Fatalf("Expected *InitializeResult, got %T", result)
+				}
+				if initResult.ServerInfo.Name != ServerName || initResult.ServerInfo.Version != ServerVersion {
+					t.Errorf("Unexpected serverInfo: %+v", initResult.ServerInfo)
+				}
+			},
+		},
+		{
+			name:   "tools/list",
+			method: "tools/list",
+			checkResult: func(t *testing.T, result interface{}) {
+				if list, ok := result.(*ListToolsResult); !ok || len(list.Tools) == 0 {
+					t.Errorf("Expected tools, got %#v", result)
+				}
+			},
+		},
+		{
+			name:   "tools/call",
+			method: "tools/call",
+			params: CallToolParams{Name: "echo", Arguments: map[string]interface{}{"message": "hi"}},
+		},
+		{
+			name:         "tools/call without params",
+			method:       "tools/call",
+			expectedCode: CodeInvalidParams,
+		},
+		{
+			name:   "resources/list",
+			method: "resources/list",
+			checkResult: func(t *testing.T, result interface{}) {
+				if _, ok := result.(*ListResourcesResult); !ok {
+					t.Errorf("Expected *ListResourcesResult, got %T", result)
+				}
+			},
+		},
+		{
+			name:   "resources/read",
+			method: "resources/read",
+			params: ReadResourceParams{URI: "info://server"},
+			checkResult: func(t *testing.T, result interface{}) {
+				read, ok := result.(*ReadResourceResult)
+				if !ok || len(read.Contents) != 1 || read.Contents[0].URI != "info://server" {
+					t.Errorf("Unexpected read result: %#v", result)
+				}
+			},
+		},
+		{
+			name:         "resources/read unknown URI",
+			method:       "resources/read",
+			params:       ReadResourceParams{URI: "tchncrt://nonexistent"},
+			expectedCode: CodeResourceNotFound,
+		},
+		{
+			name:   "prompts/list",
+			method: "prompts/list",
+			checkResult: func(t *testing.T, result interface{}) {
+				if list, ok := result.(*ListPromptsResult); !ok || len(list.Prompts) == 0 {
+					t.Errorf("Expected prompts, got %#v", result)
+				}
+			},
+		},
+		{
+			name:   "prompts/get",
+			method: "prompts/get",
+			params: GetPromptParams{Name: "welcome", Arguments: map[string]interface{}{"name": "Ada"}},
+			checkResult: func(t *testing.T, result interface{}) {
+				prompt, ok := result.(*GetPromptResult)
+				if !ok || len(prompt.Messages) == 0 {
+					t.Fatalf("Unexpected prompt result: %#v", result)
+				}
+				if prompt.Messages[0].Content.Text != "Hello, Ada! Welcome to Technocrat MCP Server." {
+					t.Errorf("Unexpected prompt text: %q", prompt.Messages[0].Content.Text)
+				}
+			},
+		},
+		{
+			name:         "unknown method",
+			method:       "unknown/method",
+			expectedCode: CodeMethodNotFound,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := d.Handle(context.Background(), newTestRequest(t, i+1, tt.method, tt.params))
+
+			if resp.JSONRPC != jsonRPCVersion {
+				t.Errorf("Expected jsonrpc %q, got %q", jsonRPCVersion, resp.JSONRPC)
+			}
+			if want, _ := json.Marshal(i + 1); string(resp.ID) != string(want) {
+				t.Errorf("Expected id %s, got %s", want, resp.ID)
+			}
+
+			if tt.expectedCode != 0 {
+				if resp.Error == nil {
+					t.Fatalf("Expected error code %d, got result %#v", tt.expectedCode, resp.Result)
+				}
+				if resp.Error.Code != tt.expectedCode {
+					t.Errorf("Expected error code %d, got %d", tt.expectedCode, resp.Error.Code)
+				}
+				return
+			}
+
+			if resp.Error != nil {
+				t.Fatalf("Unexpected error: %v", resp.Error)
+			}
+			if tt.checkResult != nil {
+				tt.checkResult(t, resp.Result)
+			}
+		})
+	}
+}
+
+// TestDispatcherInvalidRequest tests rejection of requests without a method or version
+func TestDispatcherInvalidRequest(t *testing.T) {
+	d := NewDispatcher(NewHandler())
+
+	resp := d.Handle(context.Background(), &Request{JSONRPC: "1.0", ID: json.RawMessage("1"), Method: "ping"})
+	if resp.Error == nil || resp.Error.Code != CodeInvalidRequest {
+		t.Errorf("Expected invalid request error, got %#v", resp)
+	}
+
+	resp = d.Handle(context.Background(), &Request{JSONRPC: jsonRPCVersion, ID: json.RawMessage("2")})
+	if resp.Error == nil || resp.Error.Code != CodeInvalidRequest {
+		t.Errorf("Expected invalid request error, got %#v", resp)
+	}
+}
+
+// TestTransportsShareServerInfo tests that HTTP and stdio report the same serverInfo
+func TestTransportsShareServerInfo(t *testing.T) {
+	httpInfo := NewServer(8080).dispatcher.InitializeResult(LatestProtocolVersion).ServerInfo
+	stdioInfo := NewStdioServer().dispatcher.InitializeResult(LatestProtocolVersion).ServerInfo
+
+	if httpInfo != stdioInfo {
+		t.Errorf("serverInfo differs between transports: http=%+v stdio=%+v", httpInfo, stdioInfo)
+	}
+}
+
+// TestResponseIDNull tests that responses without a request id serialize id as null
+func TestResponseIDNull(t *testing.T) {
+	data, err := json.Marshal(newErrorResponse(nil, NewError(CodeParseError, "Parse error")))
+	if err != nil {
+		t.Fatalf("Failed to marshal response: %v", err)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	if id, ok := decoded["id"]; !ok || id != nil {
+		t.Errorf("Expected null id, got %v", decoded["id"])
+	}
+}
+
+// TestDispatcherLifecycleMethods tests ping and resources/templates/list
+func TestDispatcherLifecycleMethods(t *testing.T) {
+	d := NewDispatcher(NewHandler())
+
+	resp := d.Handle(context.Background(), newTestRequest(t, 1, "ping", nil))
+	if resp == nil || resp.Error != nil {
+		t.Fatalf("Expected ping to succeed, got %#v", resp)
+	}
+	if data, _ := json.Marshal(resp.Result); string(data) != "{}" {
+		t.Errorf("Expected empty ping result, got %s", data)
+	}
+
+	resp = d.Handle(context.Background(), newTestRequest(t, 2, "resources/templates/list", nil))
+	if resp == nil || resp.Error != nil {
+		t.Fatalf("Expected resources/templates/list to succeed, got %#v", resp)
+	}
+	if _, ok := resp.Result.(*ListResourceTemplatesResult); !ok {
+		t.Errorf("Expected *ListResourceTemplatesResult, got %T", resp.Result)
+	}
+}
+
+// TestDispatcherNotificationsGetNoResponse tests that id-less messages are never answered
+func TestDispatcherNotificationsGetNoResponse(t *testing.T) {
+	d := NewDispatcher(NewHandler())
+
+	tests := []struct {
+		method string
+		params interface{}
+	}{
+		{"notifications/initialized", nil},
+		{"notifications/cancelled", CancelledParams{RequestID: json.RawMessage("7"), Reason: "user aborted"}},
+		{"notifications/unknown", nil},
+		{"tools/list", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			if resp := d.Handle(context.Background(), newTestRequest(t, nil, tt.method, tt.params)); resp != nil {
+				t.Errorf("Expected no response for notification, got %#v", resp)
+			}
+		})
+	}
+}
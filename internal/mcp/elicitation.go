@@ -0,0 +1,74 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrElicitationUnsupported is returned by Elicit when the client behind the
+// request cannot ask its user for input
+var ErrElicitationUnsupported = errors.New("client does not support elicitation")
+
+// elicitationTypes are the property types an elicitation schema may use
+var elicitationTypes = map[string]bool{"string": true, "number": true, "integer": true, "boolean": true}
+
+// Elicit asks the user of the client behind ctx for input through
+// elicitation/create and waits for the answer. schema is a flat JSON Schema
+// object whose properties are strings (optionally with an enum of choices),
+// numbers, integers or booleans.
+//
+// Accepted answers are validated against schema. Declined and cancelled
+// requests are not errors: check the result's Action. Clients that did not
+// declare the elicitation capability, or negotiated a protocol version without
+// it, fail with ErrElicitationUnsupported, so handlers can fall back to
+// asking through their result.
+func Elicit(ctx context.Context, message string, schema map[string]interface{}) (*ElicitResult, error) {
+	session, ok := SessionFromContext(ctx)
+	if !ok || !session.Features().Elicitation || !session.ClientSupports("elicitation") {
+		return nil, ErrElicitationUnsupported
+	}
+	if err := validateElicitationSchema(schema); err != nil {
+		return nil, err
+	}
+
+	var result ElicitResult
+	if err := session.request(ctx, "elicitation/create", ElicitParams{Message: message, RequestedSchema: schema}, &result); err != nil {
+		return nil, err
+	}
+
+	switch result.Action {
+	case ElicitActionAccept:
+		if err := ValidateSchema(schema, result.Content, "content"); err != nil {
+			return nil, fmt.Errorf("invalid elicitation response: %w", err)
+		}
+	case ElicitActionDecline, ElicitActionCancel:
+	default:
+		return nil, fmt.Errorf("invalid elicitation response: unknown action %q", result.Action)
+	}
+	return &result, nil
+}
+
+// validateElicitationSchema checks that schema is an object of primitive
+// properties, the only form clients are required to render
+func validateElicitationSchema(schema map[string]interface{}) error {
+	if schema["type"] != "object" {
+		return errors.New("invalid elicitation schema: type must be object")
+	}
+
+	properties, ok := schema["properties"].(map[string]interface{})
+	if !ok || len(properties) == 0 {
+		return errors.New("invalid elicitation schema: properties are required")
+	}
+	for name, property := range properties {
+		propertySchema, ok := property.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("invalid elicitation schema: property %q is not a schema", name)
+		}
+		propertyType, _ := propertySchema["type"].(string)
+		if !elicitationTypes[propertyType] {
+			return fmt.Errorf("invalid elicitation schema: property %q must be a string, number, integer or boolean", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// elicitingSession returns a session of a client with elicitation whose
+// requests are answered by respond
+func elicitingSession(t *testing.T, respond func(params ElicitParams) string) *Session {
+	t.Helper()
+	session := NewSession()
+	session.begin(LatestProtocolVersion, InitializeParams{
+		Capabilities: map[string]interface{}{"elicitation": map[string]interface{}{}},
+	})
+	sender := newRecordingSender()
+	session.setNotifier(sender)
+
+	go func() {
+		for data := range sender.sent {
+			var req struct {
+				ID     json.RawMessage `json:"id"`
+				Method string          `json:"method"`
+				Params ElicitParams    `json:"params"`
+			}
+			if err := json.Unmarshal(data, &req); err != nil || req.Method != "elicitation/create" {
+				continue
+			}
+			msg, rpcErr := parseMessage([]byte(`{"jsonrpc":"2.0","id":` + string(req.ID) + `,"result":` + respond(req.Params) + `}`))
+			if rpcErr != nil {
+				t.Errorf("Invalid response: %v", rpcErr)
+				continue
+			}
+			session.deliver(msg)
+		}
+	}()
+	t.Cleanup(func() { close(sender.sent) })
+	return session
+}
+
+// TestElicit tests asking the user for input and validating the answer
+func TestElicit(t *testing.T) {
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"answer": map[string]interface{}{"type": "string", "enum": []string{"OAuth", "SAML"}},
+		},
+		"required": []string{"answer"},
+	}
+
+	tests := []struct {
+		name           string
+		response       string
+		expectedAction string
+		expectError    bool
+	}{
+		{name: "Accepted", response: `{"action":"accept","content":{"answer":"OAuth"}}`, expectedAction: ElicitActionAccept},
+		{name: "Declined", response: `{"action":"decline"}`, expectedAction: ElicitActionDecline},
+		{name: "Cancelled", response: `{"action":"cancel"}`, expectedAction: ElicitActionCancel},
+		{name: "Answer outside the schema", response: `{"action":"accept","content":{"answer":"LDAP"}}`, expectError: true},
+		{name: "Unknown action", response: `{"action":"maybe"}`, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var asked ElicitParams
+			session := elicitingSession(t, func(params ElicitParams) string {
+				asked = params
+				return tt.response
+			})
+			ctx := ContextWithSession(context.Background(), session)
+
+			result, err := Elicit(ctx, "Which identity provider?", schema)
+			if tt.expectError {
+				if err == nil {
+					t.Fatalf("Expected an error, got %+v", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result.Action != tt.expectedAction {
+				t.Errorf("Expected action %s, got %s", tt.expectedAction, result.Action)
+			}
+			if asked.Message != "Which identity provider?" || asked.RequestedSchema["type"] != "object" {
+				t.Errorf("Unexpected request: %+v", asked)
+			}
+		})
+	}
+}
+
+// TestElicitUnsupported tests that clients without elicitation are not asked
+func TestElicitUnsupported(t *testing.T) {
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"answer": map[string]interface{}{"type": "string"}},
+	}
+
+	withoutCapability := NewSession()
+	withoutCapability.begin(LatestProtocolVersion, InitializeParams{Capabilities: map[string]interface{}{}})
+
+	oldProtocol := NewSession()
+	oldProtocol.begin("2025-03-26", InitializeParams{
+		Capabilities: map[string]interface{}{"elicitation": map[string]interface{}{}},
+	})
+
+	for name, ctx := range map[string]context.Context{
+		"No session":          context.Background(),
+		"No capability":       ContextWithSession(context.Background(), withoutCapability),
+		"Protocol 2025-03-26": ContextWithSession(context.Background(), oldProtocol),
+	} {
+		if _, err := Elicit(ctx, "Question?", schema); !errors.Is(err, ErrElicitationUnsupported) {
+			t.Errorf("%s: expected ErrElicitationUnsupported, got %v", name, err)
+		}
+	}
+}
+
+// TestValidateElicitationSchema tests rejecting schemas clients cannot render
+func TestValidateElicitationSchema(t *testing.T) {
+	tests := map[string]struct {
+		schema      map[string]interface{}
+		expectError bool
+	}{
+		"Primitive properties": {schema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"name":  map[string]interface{}{"type": "string"},
+				"count": map[string]interface{}{"type": "integer"},
+				"ok":    map[string]interface{}{"type": "boolean"},
+			},
+		}},
+		"Not an object": {schema: map[string]interface{}{"type": "string"}, expectError: true},
+		"No properties": {schema: map[string]interface{}{"type": "object"}, expectError: true},
+		"Nested object": {schema: map[string]interface{}{
+			"type":       "object",
+			"properties": map[string]interface{}{"nested": map[string]interface{}{"type": "object"}},
+		}, expectError: true},
+	}
+
+	for name, tt := range tests {
+		if err := validateElicitationSchema(tt.schema); (err != nil) != tt.expectError {
+			t.Errorf("%s: expected error=%v, got %v", name, tt.expectError, err)
+		}
+	}
+}
+
+// TestStreamableHTTPElicitation tests a tool asking the user a question over
+// the SSE response of its tools/call and receiving the answer in a later POST
+func TestStreamableHTTPElicitation(t *testing.T) {
+	root := newTestWorkspace(t)
+	server := NewServer(8080)
+	server.handler.SetWorkspaceRoot(root)
+	ts := httptest.NewServer(server.routes())
+	defer ts.Close()
+
+	var sessionID string
+	post := func(body, accept string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+mcpEndpoint, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", accept)
+		if sessionID != "" {
+			req.Header.Set(sessionIDHeader, sessionID)
+			req.Header.Set(protocolVersionHeader, LatestProtocolVersion)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("POST failed: %v", err)
+		}
+		return resp
+	}
+
+	resp := post(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{"elicitation":{}},"clientInfo":{"name":"test","version":"1"}}}`, "application/json")
+	resp.Body.Close()
+	sessionID = resp.Header.Get(sessionIDHeader)
+
+	call := post(`{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"clarify_spec","arguments":{"feature":"001-auth","questions":[{"question":"Which identity provider?","options":["OAuth","SAML"]}]}}}`, "application/json, text/event-stream")
+	defer call.Body.Close()
+	events := bufio.NewReader(call.Body)
+
+	nextEvent := func() map[string]interface{} {
+		t.Helper()
+		for {
+			line, err := events.ReadString('\n')
+			if err != nil {
+				t.Fatalf("Stream ended: %v", err)
+			}
+			if data, ok := strings.CutPrefix(strings.TrimSpace(line), "data: "); ok {
+				return decodeReply(t, []byte(data))
+			}
+		}
+	}
+
+	request := nextEvent()
+	if request["method"] != "elicitation/create" {
+		t.Fatalf("Expected an elicitation request, got %v", request)
+	}
+	id, _ := json.Marshal(request["id"])
+	answer := post(`{"jsonrpc":"2.0","id":`+string(id)+`,"result":{"action":"accept","content":{"answer":"SAML"}}}`, "application/json")
+	answer.Body.Close()
+	if answer.StatusCode != http.StatusAccepted {
+		t.Errorf("Expected 202 for the response, got %d", answer.StatusCode)
+	}
+
+	reply := nextEvent()
+	result, ok := reply["result"].(map[string]interface{})
+	if !ok || result["isError"] == true {
+		t.Fatalf("Expected a successful tool result, got %v", reply)
+	}
+	structured := result["structuredContent"].(map[string]interface{})
+	if answered := structured["answered"].([]interface{}); len(answered) != 1 {
+		t.Errorf("Expected one answer, got %v", structured)
+	}
+
+	spec, _ := os.ReadFile(filepath.Join(root, "specs", "001-auth", "spec.md"))
+	if !strings.Contains(string(spec), "- Q: Which identity provider? → A: SAML") {
+		t.Errorf("Expected the answer in the spec, got %q", spec)
+	}
+	io.Copy(io.Discard, call.Body)
+}
@@ -1,57 +1,130 @@
 package mcp
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
 	"technocrat/internal/templates"
 )
 
+// Errors returned when a requested tool, resource or prompt is not registered
+var (
+	ErrToolNotFound     = errors.New("tool not found")
+	ErrResourceNotFound = errors.New("resource not found")
+	ErrPromptNotFound   = errors.New("prompt not found")
+)
+
+// Errors returned when tool arguments or tool output do not match the tool's schemas
+var (
+	ErrInvalidArguments  = errors.New("invalid arguments")
+	ErrInvalidToolOutput = errors.New("invalid tool output")
+)
+
+// HandlerFunc executes a tool or prompt. ctx is cancelled when the client
+// cancels the request, the request deadline passes or the server shuts down.
+type HandlerFunc func(ctx context.Context, args map[string]interface{}) (interface{}, error)
+
+// LegacyHandler adapts a handler written against the original context-free
+// signature. The wrapped handler cannot observe cancellation, but callers stop
+// waiting for it as soon as ctx is done.
+func LegacyHandler(fn func(map[string]interface{}) (interface{}, error)) HandlerFunc {
+	return func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		type outcome struct {
+			value interface{}
+			err   error
+		}
+		done := make(chan outcome, 1)
+		go func() {
+			value, err := fn(args)
+			done <- outcome{value, err}
+		}()
+
+		select {
+		case o := <-done:
+			return o.value, o.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
 // Handler manages MCP protocol operations
 type Handler struct {
-	tools     map[string]Tool
-	resources map[string]Resource
-	prompts   map[string]Prompt
+	tools             map[string]Tool
+	resources         map[string]Resource
+	resourceTemplates map[string]ResourceTemplate
+	prompts           map[string]Prompt
+	workspace         Workspace
 }
 
-// Tool represents an MCP tool
+// Tool represents an MCP tool. Handler may return a *CallToolResult built with
+// NewToolResult, a string for plain text, or any other value, which is reported
+// as structured content. When OutputSchema is set, the structured content of
+// every successful call is validated against it.
 type Tool struct {
-	Name        string                                            `json:"name"`
-	Description string                                            `json:"description"`
-	InputSchema map[string]interface{}                            `json:"inputSchema"`
-	Handler     func(map[string]interface{}) (interface{}, error) `json:"-"`
+	Name         string                 `json:"name"`
+	Description  string                 `json:"description"`
+	InputSchema  map[string]interface{} `json:"inputSchema"`
+	OutputSchema map[string]interface{} `json:"outputSchema,omitempty"`
+	Handler      HandlerFunc            `json:"-"`
+
+	// Timeout replaces the server's request timeout for calls to the tool,
+	// for tools that wait on the user; 0 keeps the request timeout
+	Timeout time.Duration `json:"-"`
 }
 
-// Resource represents an MCP resource
+// Resource represents an MCP resource. Registered resources provide their
+// contents through Reader; workspace resources are read from disk.
 type Resource struct {
-	URI         string `json:"uri"`
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	MimeType    string `json:"mimeType"`
+	URI         string         `json:"uri"`
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	MimeType    string         `json:"mimeType"`
+	Size        int64          `json:"size,omitempty"`
+	Reader      ResourceReader `json:"-"`
 }
 
+// ResourceReader returns the contents of a registered resource
+type ResourceReader func(ctx context.Context, uri string) (*ResourceContents, error)
+
+// TemplateReader returns the contents of a resource matched by a resource
+// template, given the values of the template's variables
+type TemplateReader func(ctx context.Context, uri string, params map[string]string) (*ResourceContents, error)
+
 // Prompt represents an MCP prompt
 type Prompt struct {
-	Name        string                                            `json:"name"`
-	Description string                                            `json:"description"`
-	Arguments   []PromptArgument                                  `json:"arguments"`
-	Handler     func(map[string]interface{}) (interface{}, error) `json:"-"`
+	Name        string           `json:"name"`
+	Description string           `json:"description"`
+	Arguments   []PromptArgument `json:"arguments"`
+	Handler     HandlerFunc      `json:"-"`
 }
 
-// PromptArgument represents a prompt argument
+// PromptArgument represents a prompt argument. Completer, when set, suggests
+// values for the argument through completion/complete.
 type PromptArgument struct {
-	Name        string `json:"name"`
-	Description string `json:"description"`
-	Required    bool   `json:"required"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Required    bool      `json:"required"`
+	Completer   Completer `json:"-"`
 }
 
 // NewHandler creates a new MCP handler
 func NewHandler() *Handler {
+	detected := DetectWorkspaceContext()
 	h := &Handler{
-		tools:     make(map[string]Tool),
-		resources: make(map[string]Resource),
-		prompts:   make(map[string]Prompt),
+		tools:             make(map[string]Tool),
+		resources:         make(map[string]Resource),
+		resourceTemplates: make(map[string]ResourceTemplate),
+		prompts:           make(map[string]Prompt),
+		workspace:         Workspace{Root: detected.Root, FeatureName: detected.FeatureName},
 	}
 
 	// Register default tools
@@ -62,7 +135,7 @@ func NewHandler() *Handler {
 	// Register command prompts from templates
 	if err := h.RegisterCommandPrompts(); err != nil {
 		// Log error but don't fail - default prompts still available
-		fmt.Printf("Warning: Failed to register command prompts: %v\n", err)
+		logger.Warn("Failed to register command prompts", "error", err)
 	}
 
 	return h
@@ -84,13 +157,16 @@ func (h *Handler) registerDefaultTools() {
 			},
 			"required": []string{"message"},
 		},
-		Handler: func(args map[string]interface{}) (interface{}, error) {
-			message, ok := args["message"].(string)
-			if !ok {
-				return nil, fmt.Errorf("message must be a string")
-			}
+		OutputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"echoed": map[string]interface{}{"type": "string"},
+			},
+			"required": []string{"echoed"},
+		},
+		Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
 			return map[string]interface{}{
-				"echoed": message,
+				"echoed": args["message"].(string),
 			}, nil
 		},
 	}
@@ -103,24 +179,84 @@ func (h *Handler) registerDefaultTools() {
 			"type":       "object",
 			"properties": map[string]interface{}{},
 		},
-		Handler: func(args map[string]interface{}) (interface{}, error) {
+		OutputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"server":  map[string]interface{}{"type": "string"},
+				"version": map[string]interface{}{"type": "string"},
+				"status":  map[string]interface{}{"type": "string"},
+			},
+			"required": []string{"server", "version", "status"},
+		},
+		Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
 			return map[string]interface{}{
-				"server":  "technocrat",
-				"version": "1.0.0",
+				"server":  ServerName,
+				"version": ServerVersion,
 				"status":  "running",
 			}, nil
 		},
 	}
+	h.tools["clarify_spec"] = h.clarifySpecTool()
 }
 
 // registerDefaultResources registers the default resources
 func (h *Handler) registerDefaultResources() {
-	h.resources["info://server"] = Resource{
-		URI:         "info://server",
+	h.resources[serverInfoURI] = Resource{
+		URI:         serverInfoURI,
 		Name:        "Server Information",
 		Description: "Information about the Technocrat MCP server",
 		MimeType:    "application/json",
+		Reader: func(ctx context.Context, uri string) (*ResourceContents, error) {
+			return h.serverInfo(ctx)
+		},
 	}
+
+	// Workspace files are read through templates so every feature is covered
+	// without registering its artifacts one by one
+	h.mustRegisterResourceTemplate(ResourceTemplate{
+		URITemplate: ResourceScheme + "://features/{feature}/{artifact}",
+		Name:        "Feature document",
+		Description: "A feature's spec, plan, tasks, research, data-model or quickstart document",
+		MimeType:    "text/markdown",
+		Reader: func(ctx context.Context, uri string, params map[string]string) (*ResourceContents, error) {
+			return h.workspaceFor(ctx).ReadDocument(params["feature"], params["artifact"])
+		},
+		Completers: map[string]Completer{
+			"feature":  h.completeFeatures,
+			"artifact": h.completeArtifacts,
+		},
+	})
+	h.mustRegisterResourceTemplate(ResourceTemplate{
+		URITemplate: ResourceScheme + "://features/{feature}/contracts/{+path}",
+		Name:        "Feature contract",
+		Description: "A file in a feature's contracts directory",
+		Reader: func(ctx context.Context, uri string, params map[string]string) (*ResourceContents, error) {
+			return h.workspaceFor(ctx).ReadContract(params["feature"], params["path"])
+		},
+		Completers: map[string]Completer{
+			"feature": h.completeFeatures,
+			"path":    h.completeContracts,
+		},
+	})
+	h.mustRegisterResourceTemplate(ResourceTemplate{
+		URITemplate: ResourceScheme + "://memory/{document}",
+		Name:        "Project memory",
+		Description: "A document in memory/, such as the constitution",
+		MimeType:    "text/markdown",
+		Reader: func(ctx context.Context, uri string, params map[string]string) (*ResourceContents, error) {
+			return h.workspaceFor(ctx).ReadMemory(params["document"])
+		},
+		Completers: map[string]Completer{"document": h.completeMemory},
+	})
+	h.mustRegisterResourceTemplate(ResourceTemplate{
+		URITemplate: ResourceScheme + "://templates/{name}",
+		Name:        "Template",
+		Description: "A template embedded in technocrat",
+		Reader: func(ctx context.Context, uri string, params map[string]string) (*ResourceContents, error) {
+			return h.workspaceFor(ctx).ReadTemplate(params["name"])
+		},
+		Completers: map[string]Completer{"name": h.completeTemplates},
+	})
 }
 
 // registerDefaultPrompts registers the default prompts
@@ -135,16 +271,16 @@ func (h *Handler) registerDefaultPrompts() {
 				Required:    false,
 			},
 		},
-		Handler: func(args map[string]interface{}) (interface{}, error) {
+		Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
 			name := "there"
 			if n, ok := args["name"].(string); ok && n != "" {
 				name = n
 			}
-			return map[string]interface{}{
-				"messages": []map[string]string{
+			return &GetPromptResult{
+				Messages: []PromptMessage{
 					{
-						"role":    "user",
-						"content": fmt.Sprintf("Hello, %s! Welcome to Technocrat MCP Server.", name),
+						Role:    "user",
+						Content: TextContent(fmt.Sprintf("Hello, %s! Welcome to Technocrat MCP Server.", name)),
 					},
 				},
 			}, nil
@@ -158,46 +294,194 @@ func (h *Handler) ListTools() []Tool {
 	for _, tool := range h.tools {
 		// Don't include the handler in the response
 		tools = append(tools, Tool{
-			Name:        tool.Name,
-			Description: tool.Description,
-			InputSchema: tool.InputSchema,
+			Name:         tool.Name,
+			Description:  tool.Description,
+			InputSchema:  tool.InputSchema,
+			OutputSchema: tool.OutputSchema,
 		})
 	}
 	return tools
 }
 
-// CallTool executes a tool by name
-func (h *Handler) CallTool(name string, args map[string]interface{}) (interface{}, error) {
+// CallTool executes a tool without a deadline or cancellation
+func (h *Handler) CallTool(name string, args map[string]interface{}) (*CallToolResult, error) {
+	return h.CallToolContext(context.Background(), name, args)
+}
+
+// CallToolContext validates the arguments against the tool's input schema and
+// executes the tool. Failures inside the tool are reported in the result with
+// IsError set; the returned error is reserved for unknown tools, arguments that
+// violate the input schema, output that violates the output schema and calls
+// stopped because ctx was cancelled or timed out.
+func (h *Handler) CallToolContext(ctx context.Context, name string, args map[string]interface{}) (*CallToolResult, error) {
+	// Names of unknown tools come from clients and are not used as labels
+	label := "unknown"
+	if _, exists := h.tools[name]; exists {
+		label = name
+	}
+
+	start := time.Now()
+	result, err := h.callTool(ctx, name, args)
+	metrics.toolDuration.observe(time.Since(start), label)
+	outcome := outcomeOf(err)
+	if err == nil && result.IsError {
+		outcome = outcomeError
+	}
+	metrics.toolCalls.inc(label, outcome)
+	return result, err
+}
+
+// callTool validates the arguments and executes the tool for CallToolContext
+func (h *Handler) callTool(ctx context.Context, name string, args map[string]interface{}) (*CallToolResult, error) {
 	tool, exists := h.tools[name]
 	if !exists {
-		return nil, fmt.Errorf("tool not found: %s", name)
+		return nil, fmt.Errorf("%w: %s", ErrToolNotFound, name)
+	}
+
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	if err := ValidateSchema(tool.InputSchema, args, "arguments"); err != nil {
+		return nil, fmt.Errorf("%w for %s: %w", ErrInvalidArguments, name, err)
+	}
+
+	value, err := tool.Handler(ctx, args)
+	if err != nil {
+		if isContextError(err) {
+			return nil, err
+		}
+		return ErrorResult(err), nil
+	}
+
+	result := toToolResult(value)
+	if err := validateToolOutput(tool, result); err != nil {
+		return nil, err
 	}
-	return tool.Handler(args)
+	return result, nil
 }
 
-// ListResources returns all registered resources
+// ListResources returns all registered resources followed by the files and
+// templates of the handler's workspace
 func (h *Handler) ListResources() []Resource {
+	return h.ListResourcesContext(context.Background())
+}
+
+// ListResourcesContext returns all registered resources followed by the files
+// and templates of the workspace of the client behind ctx
+func (h *Handler) ListResourcesContext(ctx context.Context) []Resource {
 	resources := make([]Resource, 0, len(h.resources))
 	for _, resource := range h.resources {
+		// Don't include the reader in the response
+		resource.Reader = nil
 		resources = append(resources, resource)
 	}
-	return resources
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].URI < resources[j].URI
+	})
+	return append(resources, h.workspaceFor(ctx).Resources()...)
 }
 
-// ReadResource reads a resource by URI
-func (h *Handler) ReadResource(uri string) (interface{}, error) {
-	resource, exists := h.resources[uri]
-	if !exists {
-		return nil, fmt.Errorf("resource not found: %s", uri)
+// ReadResource reads a resource by URI without a deadline or cancellation
+func (h *Handler) ReadResource(uri string) (*ReadResourceResult, error) {
+	return h.ReadResourceContext(context.Background(), uri)
+}
+
+// ReadResourceContext reads a registered resource, or else the resource of the
+// first template the URI matches
+func (h *Handler) ReadResourceContext(ctx context.Context, uri string) (*ReadResourceResult, error) {
+	// Reads are labelled by resource or template rather than by URI, which
+	// would give every file its own series
+	label := "unknown"
+	if _, exists := h.resources[uri]; exists {
+		label = uri
+	} else if template, _, ok := h.ResolveResourceTemplate(uri); ok {
+		label = template.URITemplate
 	}
 
-	// For this example, return basic info
-	return map[string]interface{}{
-		"uri":      resource.URI,
-		"name":     resource.Name,
-		"mimeType": resource.MimeType,
-		"text":     "This is the Technocrat MCP server, a Spec Driven Development Framework.",
-	}, nil
+	start := time.Now()
+	result, err := h.readResource(ctx, uri)
+	metrics.resourceLatency.observe(time.Since(start), label)
+	metrics.resourceReads.inc(label, outcomeOf(err))
+	return result, err
+}
+
+// readResource reads a registered or templated resource for ReadResourceContext
+func (h *Handler) readResource(ctx context.Context, uri string) (*ReadResourceResult, error) {
+	var contents *ResourceContents
+	var err error
+
+	if resource, exists := h.resources[uri]; exists {
+		if resource.Reader == nil {
+			return nil, fmt.Errorf("resource %s has no reader", uri)
+		}
+		contents, err = resource.Reader(ctx, uri)
+	} else if template, params, ok := h.ResolveResourceTemplate(uri); ok {
+		contents, err = template.Reader(ctx, uri, params)
+	} else {
+		return nil, fmt.Errorf("%w: %s", ErrResourceNotFound, uri)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &ReadResourceResult{Contents: []ResourceContents{*contents}}, nil
+}
+
+// resourceExists reports whether uri names a registered resource or matches a
+// resource template, whether or not its file currently exists
+func (h *Handler) resourceExists(uri string) bool {
+	if _, exists := h.resources[uri]; exists {
+		return true
+	}
+	_, _, ok := h.ResolveResourceTemplate(uri)
+	return ok
+}
+
+// ResolveResourceTemplate finds the registered template matching uri and
+// returns it with the values of its variables. When several templates match,
+// the one with the most literal characters wins, so a template such as
+// tchncrt://features/{feature}/contracts/{+path} takes precedence over one
+// that only fixes tchncrt://features/.
+func (h *Handler) ResolveResourceTemplate(uri string) (ResourceTemplate, map[string]string, bool) {
+	var best ResourceTemplate
+	var bestParams map[string]string
+	for _, template := range h.resourceTemplates {
+		params, ok := template.template.Match(uri)
+		if !ok {
+			continue
+		}
+		if bestParams == nil || moreSpecific(template, best) {
+			best, bestParams = template, params
+		}
+	}
+	return best, bestParams, bestParams != nil
+}
+
+// moreSpecific orders matching templates by literal length, then by template
+// text so resolution does not depend on map iteration order
+func moreSpecific(a, b ResourceTemplate) bool {
+	if a.template.literals != b.template.literals {
+		return a.template.literals > b.template.literals
+	}
+	return a.URITemplate < b.URITemplate
+}
+
+// ListResourceTemplates returns all registered resource templates
+func (h *Handler) ListResourceTemplates() []ResourceTemplate {
+	templates := make([]ResourceTemplate, 0, len(h.resourceTemplates))
+	for _, template := range h.resourceTemplates {
+		// Don't include the reader in the response
+		templates = append(templates, ResourceTemplate{
+			URITemplate: template.URITemplate,
+			Name:        template.Name,
+			Description: template.Description,
+			MimeType:    template.MimeType,
+		})
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].URITemplate < templates[j].URITemplate
+	})
+	return templates
 }
 
 // ListPrompts returns all registered prompts
@@ -214,13 +498,34 @@ func (h *Handler) ListPrompts() []Prompt {
 	return prompts
 }
 
-// GetPrompt retrieves and executes a prompt by name
+// GetPrompt retrieves and executes a prompt by name without a deadline or cancellation
 func (h *Handler) GetPrompt(name string, args map[string]interface{}) (interface{}, error) {
+	return h.GetPromptContext(context.Background(), name, args)
+}
+
+// GetPromptContext retrieves and executes a prompt by name
+func (h *Handler) GetPromptContext(ctx context.Context, name string, args map[string]interface{}) (interface{}, error) {
 	prompt, exists := h.prompts[name]
 	if !exists {
-		return nil, fmt.Errorf("prompt not found: %s", name)
+		metrics.promptGets.inc("unknown", outcomeError)
+		return nil, fmt.Errorf("%w: %s", ErrPromptNotFound, name)
 	}
-	return prompt.Handler(args)
+
+	start := time.Now()
+	result, err := prompt.Handler(ctx, args)
+	metrics.promptDuration.observe(time.Since(start), name)
+	metrics.promptGets.inc(name, outcomeOf(err))
+	return result, err
+}
+
+// isContextError reports whether err was caused by a cancelled or expired context
+func isContextError(err error) bool {
+	return errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
+
+// SetWorkspaceRoot changes the project directory whose files are served as resources
+func (h *Handler) SetWorkspaceRoot(root string) {
+	h.workspace = Workspace{Root: root}
 }
 
 // RegisterTool registers a new tool
@@ -233,6 +538,30 @@ func (h *Handler) RegisterResource(resource Resource) {
 	h.resources[resource.URI] = resource
 }
 
+// RegisterResourceTemplate registers a new resource template. It fails when
+// the URI template is not valid RFC 6570 or the template has no reader.
+func (h *Handler) RegisterResourceTemplate(template ResourceTemplate) error {
+	parsed, err := ParseURITemplate(template.URITemplate)
+	if err != nil {
+		return err
+	}
+	if template.Reader == nil {
+		return fmt.Errorf("resource template %s has no reader", template.URITemplate)
+	}
+
+	template.template = parsed
+	h.resourceTemplates[template.URITemplate] = template
+	return nil
+}
+
+// mustRegisterResourceTemplate registers one of the built-in templates, which
+// are known to be valid
+func (h *Handler) mustRegisterResourceTemplate(template ResourceTemplate) {
+	if err := h.RegisterResourceTemplate(template); err != nil {
+		panic(err)
+	}
+}
+
 // RegisterPrompt registers a new prompt
 func (h *Handler) RegisterPrompt(prompt Prompt) {
 	h.prompts[prompt.Name] = prompt
@@ -280,17 +609,27 @@ func (h *Handler) registerCommandPrompt(commandName string) error {
 				Name:        "user_input",
 				Description: "User input to guide the workflow",
 				Required:    false,
+				Completer:   h.completeWorkflowInput,
+			},
+			{
+				Name:        "feature",
+				Description: "Feature directory under specs/ to work on, defaulting to the current one",
+				Required:    false,
+				Completer:   h.completeFeatures,
 			},
 		},
-		Handler: func(args map[string]interface{}) (interface{}, error) {
+		Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
 			// Extract user input
 			userInput := ""
 			if input, ok := args["user_input"].(string); ok {
 				userInput = input
 			}
 
-			// Detect workspace context
-			wsContext := DetectWorkspaceContext()
+			// Use the client's workspace, from its roots or the working directory
+			wsContext := WorkspaceFromContext(ctx)
+			if feature, ok := args["feature"].(string); ok && feature != "" {
+				wsContext.FeatureName = feature
+			}
 
 			// Prepare template data with enhanced metadata
 			templateData := TemplateData{
@@ -314,15 +653,12 @@ func (h *Handler) registerCommandPrompt(commandName string) error {
 				strings.Title(commandName),
 				processedWorkflow)
 
-			return map[string]interface{}{
-				"description": fmt.Sprintf("Technocrat %s workflow", commandName),
-				"messages": []map[string]interface{}{
+			return &GetPromptResult{
+				Description: fmt.Sprintf("Technocrat %s workflow", commandName),
+				Messages: []PromptMessage{
 					{
-						"role": "user",
-						"content": map[string]interface{}{
-							"type": "text",
-							"text": message,
-						},
+						Role:    "user",
+						Content: TextContent(message),
 					},
 				},
 			}, nil
@@ -1,6 +1,7 @@
 package mcp
 
 import (
+	"encoding/json"
 	"testing"
 )
 
@@ -57,12 +58,19 @@ func TestCallTool(t *testing.T) {
 		t.Fatalf("CallTool failed: %v", err)
 	}
 
-	resultMap, ok := result.(map[string]interface{})
-	if !ok {
-		t.Fatal("Result is not a map")
+	if result.IsError {
+		t.Fatalf("Expected echo to succeed, got %#v", result)
+	}
+	if len(result.Content) != 1 || result.Content[0].Type != ContentTypeText {
+		t.Fatalf("Expected a single text block, got %#v", result.Content)
+	}
+
+	var structured map[string]interface{}
+	if err := json.Unmarshal(result.StructuredContent.(json.RawMessage), &structured); err != nil {
+		t.Fatalf("Failed to decode structured content: %v", err)
 	}
 
-	echoed, ok := resultMap["echoed"].(string)
+	echoed, ok := structured["echoed"].(string)
 	if !ok {
 		t.Fatal("echoed field is not a string")
 	}
@@ -98,19 +98,18 @@ func TestTemplateSubstitutionIntegration(t *testing.T) {
 				t.Fatalf("Unexpected error: %v", err)
 			}
 
-			resultMap, ok := result.(map[string]interface{})
+			promptResult, ok := result.(*GetPromptResult)
 			if !ok {
-				t.Fatal("Result is not a map")
+				t.Fatal("Result is not a *GetPromptResult")
 			}
 
-			messages, ok := resultMap["messages"].([]map[string]interface{})
-			if !ok || len(messages) == 0 {
+			if len(promptResult.Messages) == 0 {
 				t.Fatal("Invalid messages structure")
 			}
 
-			content, ok := messages[0]["content"].(string)
-			if !ok {
-				t.Fatal("Content is not a string")
+			content := promptResult.Messages[0].Content.Text
+			if content == "" {
+				t.Fatal("Content text is empty")
 			}
 
 			// Check that expected strings are present
@@ -178,7 +177,7 @@ func TestConditionalRendering(t *testing.T) {
 		t.Fatalf("Error with input: %v", err)
 	}
 
-	contentWithInput := resultWithInput.(map[string]interface{})["messages"].([]map[string]interface{})[0]["content"].(string)
+	contentWithInput := resultWithInput.(*GetPromptResult).Messages[0].Content.Text
 
 	// Test without input
 	resultWithoutInput, err := handler.GetPrompt("spec", map[string]interface{}{})
@@ -186,7 +185,7 @@ func TestConditionalRendering(t *testing.T) {
 		t.Fatalf("Error without input: %v", err)
 	}
 
-	contentWithoutInput := resultWithoutInput.(map[string]interface{})["messages"].([]map[string]interface{})[0]["content"].(string)
+	contentWithoutInput := resultWithoutInput.(*GetPromptResult).Messages[0].Content.Text
 
 	// They should be different
 	if contentWithInput == contentWithoutInput {
@@ -0,0 +1,251 @@
+package mcp
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// jsonRPCVersion is the only JSON-RPC version spoken by MCP
+const jsonRPCVersion = "2.0"
+
+// JSON-RPC 2.0 and MCP error codes
+const (
+	CodeParseError       = -32700
+	CodeInvalidRequest   = -32600
+	CodeMethodNotFound   = -32601
+	CodeInvalidParams    = -32602
+	CodeInternalError    = -32603
+	CodeResourceNotFound = -32002
+	CodeServerBusy       = -32000 // Implementation-defined: too many requests already running
+)
+
+// Request represents a JSON-RPC 2.0 request or notification
+type Request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// IsNotification reports whether the request carries no id and expects no response
+func (r *Request) IsNotification() bool {
+	return len(r.ID) == 0
+}
+
+// message is any JSON-RPC 2.0 message received from a peer: a request,
+// a notification or a response to a request the server sent
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// isResponse reports whether the message answers a server-initiated request
+func (m *message) isResponse() bool {
+	return m.Method == "" && (m.Result != nil || m.Error != nil)
+}
+
+// request returns the message as a request
+func (m *message) request() *Request {
+	return &Request{
+		JSONRPC: m.JSONRPC,
+		ID:      m.ID,
+		Method:  m.Method,
+		Params:  m.Params,
+	}
+}
+
+// validate checks the message against the JSON-RPC 2.0 structure rules
+func (m *message) validate() *Error {
+	if m.JSONRPC != jsonRPCVersion {
+		return NewError(CodeInvalidRequest, "Invalid request: jsonrpc must be \"2.0\"")
+	}
+	if !validID(m.ID) {
+		return NewError(CodeInvalidRequest, "Invalid request: id must be a string, number or null")
+	}
+	if m.Method == "" && !m.isResponse() {
+		return NewError(CodeInvalidRequest, "Invalid request: missing method")
+	}
+	return nil
+}
+
+// parseMessage decodes a single JSON-RPC message. For structurally invalid
+// messages it returns an invalid request error alongside a message carrying
+// the request id, when one could be read.
+func parseMessage(data json.RawMessage) (*message, *Error) {
+	var msg message
+	if err := json.Unmarshal(data, &msg); err != nil {
+		var probe message
+		var idOnly struct {
+			ID json.RawMessage `json:"id"`
+		}
+		if json.Unmarshal(data, &idOnly) == nil && validID(idOnly.ID) {
+			probe.ID = idOnly.ID
+		}
+		return &probe, NewError(CodeInvalidRequest, "Invalid request: %v", err)
+	}
+
+	if err := msg.validate(); err != nil {
+		if !validID(msg.ID) {
+			msg.ID = nil
+		}
+		return &msg, err
+	}
+
+	return &msg, nil
+}
+
+// isNotificationMessage reports whether data is a single, well-formed notification
+func isNotificationMessage(data []byte) bool {
+	if isBatch(data) {
+		return false
+	}
+	msg, err := parseMessage(data)
+	return err == nil && msg.Method != "" && len(msg.ID) == 0
+}
+
+// isResponseMessage reports whether data is a single client response to a
+// request the server sent
+func isResponseMessage(data []byte) bool {
+	if isBatch(data) {
+		return false
+	}
+	msg, err := parseMessage(data)
+	return err == nil && msg.isResponse()
+}
+
+// isInitializeRequest reports whether data is a single initialize request
+func isInitializeRequest(data []byte) bool {
+	if isBatch(data) {
+		return false
+	}
+	msg, err := parseMessage(data)
+	return err == nil && msg.Method == "initialize" && len(msg.ID) > 0
+}
+
+// isToolCallRequest reports whether data is a single tools/call request
+func isToolCallRequest(data []byte) bool {
+	if isBatch(data) {
+		return false
+	}
+	msg, err := parseMessage(data)
+	return err == nil && msg.Method == "tools/call" && len(msg.ID) > 0
+}
+
+// isServerBusyReply reports whether reply is a single error response turning
+// the request away because the server is busy
+func isServerBusyReply(reply []byte) bool {
+	if isBatch(reply) {
+		return false
+	}
+	var response struct {
+		Error *Error `json:"error"`
+	}
+	return json.Unmarshal(reply, &response) == nil && response.Error != nil && response.Error.Code == CodeServerBusy
+}
+
+// isBatch reports whether the payload is a JSON array
+func isBatch(data []byte) bool {
+	trimmed := bytes.TrimLeft(data, " \t\r\n")
+	return len(trimmed) > 0 && trimmed[0] == '['
+}
+
+// validID reports whether raw is absent or a string, number or null
+func validID(raw json.RawMessage) bool {
+	if len(raw) == 0 {
+		return true
+	}
+	switch raw[0] {
+	case '"', '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9', 'n':
+		return true
+	default:
+		return false
+	}
+}
+
+// Response represents a JSON-RPC 2.0 response
+type Response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Notification represents a JSON-RPC 2.0 notification sent by the server
+type Notification struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params,omitempty"`
+}
+
+// encodeNotification marshals a server notification
+func encodeNotification(method string, params interface{}) ([]byte, error) {
+	return json.Marshal(&Notification{
+		JSONRPC: jsonRPCVersion,
+		Method:  method,
+		Params:  params,
+	})
+}
+
+// encodeRequest marshals a server-initiated request
+func encodeRequest(id json.RawMessage, method string, params interface{}) ([]byte, error) {
+	req := &Request{JSONRPC: jsonRPCVersion, ID: id, Method: method}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return nil, err
+		}
+		req.Params = data
+	}
+	return json.Marshal(req)
+}
+
+// Error represents a JSON-RPC 2.0 error object
+type Error struct {
+	Code    int         `json:"code"`
+	Message string      `json:"message"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+}
+
+// NewError creates a JSON-RPC error with a formatted message
+func NewError(code int, format string, args ...interface{}) *Error {
+	return &Error{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// newResultResponse builds a success response for the given request id
+func newResultResponse(id json.RawMessage, result interface{}) *Response {
+	return &Response{
+		JSONRPC: jsonRPCVersion,
+		ID:      responseID(id),
+		Result:  result,
+	}
+}
+
+// newErrorResponse builds an error response for the given request id
+func newErrorResponse(id json.RawMessage, err *Error) *Response {
+	return &Response{
+		JSONRPC: jsonRPCVersion,
+		ID:      responseID(id),
+		Error:   err,
+	}
+}
+
+// responseID returns the id to echo back, using null when the request had none
+func responseID(id json.RawMessage) json.RawMessage {
+	if len(id) == 0 {
+		return json.RawMessage("null")
+	}
+	return id
+}
@@ -0,0 +1,153 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// decodeReply unmarshals a single JSON-RPC reply
+func decodeReply(t *testing.T, reply []byte) map[string]interface{} {
+	t.Helper()
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(reply, &response); err != nil {
+		t.Fatalf("Failed to unmarshal reply %q: %v", reply, err)
+	}
+	return response
+}
+
+// replyErrorCode returns the error code of a decoded reply, or 0 when it succeeded
+func replyErrorCode(response map[string]interface{}) int {
+	errorData, ok := response["error"].(map[string]interface{})
+	if !ok {
+		return 0
+	}
+	return int(errorData["code"].(float64))
+}
+
+// TestHandleMessageErrors tests parse and invalid request errors for single messages
+func TestHandleMessageErrors(t *testing.T) {
+	d := NewDispatcher(NewHandler())
+
+	tests := []struct {
+		name         string
+		payload      string
+		expectedCode int
+		expectedID   interface{}
+	}{
+		{"Malformed JSON", `{"jsonrpc":"2.0","method":`, CodeParseError, nil},
+		{"Not an object", `42`, CodeInvalidRequest, nil},
+		{"Wrong jsonrpc version", `{"jsonrpc":"1.0","id":1,"method":"ping"}`, CodeInvalidRequest, float64(1)},
+		{"Missing jsonrpc version", `{"id":"a","method":"ping"}`, CodeInvalidRequest, "a"},
+		{"Method is not a string", `{"jsonrpc":"2.0","id":2,"method":5}`, CodeInvalidRequest, float64(2)},
+		{"Id is an object", `{"jsonrpc":"2.0","id":{},"method":"ping"}`, CodeInvalidRequest, nil},
+		{"Missing method", `{"jsonrpc":"2.0","id":3}`, CodeInvalidRequest, float64(3)},
+		{"Empty batch", `[]`, CodeInvalidRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reply := d.HandleMessage(context.Background(), []byte(tt.payload))
+			if reply == nil {
+				t.Fatal("Expected an error reply, got none")
+			}
+
+			response := decodeReply(t, reply)
+			if code := replyErrorCode(response); code != tt.expectedCode {
+				t.Errorf("Expected error code %d, got %d", tt.expectedCode, code)
+			}
+			if id, ok := response["id"]; !ok || id != tt.expectedID {
+				t.Errorf("Expected id %v, got %v", tt.expectedID, response["id"])
+			}
+		})
+	}
+}
+
+// TestHandleMessageBatch tests JSON-RPC batch processing
+func TestHandleMessageBatch(t *testing.T) {
+	d := NewDispatcher(NewHandler())
+
+	payload := `[
+		{"jsonrpc":"2.0","id":1,"method":"ping"},
+		{"jsonrpc":"2.0","method":"notifications/initialized"},
+		{"jsonrpc":"2.0","id":2,"method":"unknown/method"},
+		1,
+		{"jsonrpc":"2.0","id":3,"method":"tools/list"}
+	]`
+
+	reply := d.HandleMessage(context.Background(), []byte(payload))
+
+	var responses []map[string]interface{}
+	if err := json.Unmarshal(reply, &responses); err != nil {
+		t.Fatalf("Expected a batch reply, got %q: %v", reply, err)
+	}
+
+	if len(responses) != 4 {
+		t.Fatalf("Expected 4 responses (notification excluded), got %d: %s", len(responses), reply)
+	}
+
+	expectedCodes := []int{0, CodeMethodNotFound, CodeInvalidRequest, 0}
+	for i, response := range responses {
+		if code := replyErrorCode(response); code != expectedCodes[i] {
+			t.Errorf("Response %d: expected error code %d, got %d", i, expectedCodes[i], code)
+		}
+	}
+}
+
+// TestHandleMessageNoReply tests payloads that must not produce a reply
+func TestHandleMessageNoReply(t *testing.T) {
+	d := NewDispatcher(NewHandler())
+
+	payloads := []string{
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		`{"jsonrpc":"2.0","id":"srv-1","result":{}}`,
+		`[{"jsonrpc":"2.0","method":"notifications/initialized"},{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":1}}]`,
+	}
+
+	for _, payload := range payloads {
+		if reply := d.HandleMessage(context.Background(), []byte(payload)); reply != nil {
+			t.Errorf("Expected no reply for %s, got %s", payload, reply)
+		}
+	}
+}
+
+// TestCallToolErrorSemantics tests the split between protocol errors and tool execution errors
+func TestCallToolErrorSemantics(t *testing.T) {
+	h := NewHandler()
+	h.RegisterTool(Tool{
+		Name:        "fail",
+		Description: "Always fails",
+		InputSchema: map[string]interface{}{"type": "object"},
+		Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			return nil, errors.New("disk is full")
+		},
+	})
+	d := NewDispatcher(h)
+
+	// Unknown tools are protocol errors
+	resp := d.Handle(context.Background(), newTestRequest(t, 1, "tools/call", CallToolParams{Name: "missing"}))
+	if resp.Error == nil || resp.Error.Code != CodeInvalidParams {
+		t.Errorf("Expected invalid params error for unknown tool, got %#v", resp)
+	}
+
+	// Failing tools are reported in-band
+	resp = d.Handle(context.Background(), newTestRequest(t, 2, "tools/call", CallToolParams{Name: "fail"}))
+	if resp.Error != nil {
+		t.Fatalf("Expected tool failure in result, got error %v", resp.Error)
+	}
+	result, ok := resp.Result.(*CallToolResult)
+	if !ok {
+		t.Fatalf("Expected *CallToolResult, got %T", resp.Result)
+	}
+	if !result.IsError || len(result.Content) != 1 || result.Content[0].Text != "disk is full" {
+		t.Errorf("Unexpected tool error result: %#v", result)
+	}
+
+	// Unknown prompts are protocol errors
+	resp = d.Handle(context.Background(), newTestRequest(t, 3, "prompts/get", GetPromptParams{Name: "missing"}))
+	if resp.Error == nil || resp.Error.Code != CodeInvalidParams {
+		t.Errorf("Expected invalid params error for unknown prompt, got %#v", resp)
+	}
+}
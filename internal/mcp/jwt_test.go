@@ -0,0 +1,207 @@
+package mcp
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testSigner signs tokens with a key and describes its public half as a JWK
+type testSigner struct {
+	alg string
+	kid string
+	key crypto.Signer
+}
+
+// newTestSigner generates a key for the algorithm
+func newTestSigner(t *testing.T, alg, kid string) *testSigner {
+	t.Helper()
+	var key crypto.Signer
+	var err error
+	switch alg {
+	case "RS256", "PS256":
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+	case "ES256":
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case "EdDSA":
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	}
+	if err != nil {
+		t.Fatalf("Failed to generate %s key: %v", alg, err)
+	}
+	return &testSigner{alg: alg, kid: kid, key: key}
+}
+
+// jwk returns the public key as a JWK
+func (s *testSigner) jwk() map[string]string {
+	encode := func(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+	switch pub := s.key.Public().(type) {
+	case *rsa.PublicKey:
+		return map[string]string{"kty": "RSA", "kid": s.kid, "n": encode(pub.N.Bytes()), "e": encode(big.NewInt(int64(pub.E)).Bytes())}
+	case *ecdsa.PublicKey:
+		return map[string]string{"kty": "EC", "kid": s.kid, "crv": "P-256", "x": encode(pub.X.FillBytes(make([]byte, 32))), "y": encode(pub.Y.FillBytes(make([]byte, 32)))}
+	case ed25519.PublicKey:
+		return map[string]string{"kty": "OKP", "kid": s.kid, "crv": "Ed25519", "x": encode(pub)}
+	}
+	return nil
+}
+
+// sign returns a compact JWS token carrying the claims
+func (s *testSigner) sign(t *testing.T, claims map[string]interface{}) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": s.alg, "kid": s.kid, "typ": "at+jwt"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	digest := signed
+	var opts crypto.SignerOpts = crypto.Hash(0)
+	if s.alg != "EdDSA" {
+		sum := crypto.SHA256.New()
+		sum.Write([]byte(signed))
+		digest = string(sum.Sum(nil))
+		opts = crypto.SHA256
+	}
+	if s.alg == "PS256" {
+		opts = &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: crypto.SHA256}
+	}
+
+	var signature []byte
+	var err error
+	if ecKey, ok := s.key.(*ecdsa.PrivateKey); ok {
+		// JWS uses the fixed-size concatenation of r and s rather than ASN.1
+		var r, sig *big.Int
+		r, sig, err = ecdsa.Sign(rand.Reader, ecKey, []byte(digest))
+		signature = append(r.FillBytes(make([]byte, 32)), sig.FillBytes(make([]byte, 32))...)
+	} else {
+		signature, err = s.key.Sign(rand.Reader, []byte(digest), opts)
+	}
+	if err != nil {
+		t.Fatalf("Failed to sign token: %v", err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// testJWKS returns a JWKS document holding the signers' public keys
+func testJWKS(signers ...*testSigner) []byte {
+	keys := make([]map[string]string, 0, len(signers))
+	for _, s := range signers {
+		keys = append(keys, s.jwk())
+	}
+	data, _ := json.Marshal(map[string]interface{}{"keys": keys})
+	return data
+}
+
+// TestVerifyJWT tests checking token signatures and time claims
+func TestVerifyJWT(t *testing.T) {
+	now := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+	signers := map[string]*testSigner{}
+	for _, alg := range []string{"RS256", "PS256", "ES256", "EdDSA"} {
+		signers[alg] = newTestSigner(t, alg, "key-"+alg)
+	}
+	keys, err := parseJWKS(testJWKS(signers["RS256"], signers["PS256"], signers["ES256"], signers["EdDSA"]))
+	if err != nil {
+		t.Fatalf("Failed to parse JWKS: %v", err)
+	}
+	valid := map[string]interface{}{"iss": "https://auth.example.com", "sub": "alice", "exp": now.Add(time.Hour).Unix()}
+
+	for alg, signer := range signers {
+		t.Run(alg, func(t *testing.T) {
+			claims, err := verifyJWT(signer.sign(t, valid), keys, now)
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if claims.Subject != "alice" {
+				t.Errorf("Expected subject alice, got %q", claims.Subject)
+			}
+		})
+	}
+
+	other := newTestSigner(t, "ES256", "key-ES256")
+	rs256 := signers["RS256"]
+	tests := []struct {
+		name        string
+		token       string
+		expectedErr string
+	}{
+		{name: "Expired", token: rs256.sign(t, map[string]interface{}{"exp": now.Add(-2 * time.Minute).Unix()}), expectedErr: "expired"},
+		{name: "Expired within clock skew", token: rs256.sign(t, map[string]interface{}{"exp": now.Add(-30 * time.Second).Unix()})},
+		{name: "No expiry", token: rs256.sign(t, map[string]interface{}{"sub": "alice"}), expectedErr: "no expiry"},
+		{name: "Not valid yet", token: rs256.sign(t, map[string]interface{}{"exp": now.Add(time.Hour).Unix(), "nbf": now.Add(10 * time.Minute).Unix()}), expectedErr: "not valid yet"},
+		{name: "Unknown key", token: other.sign(t, valid), expectedErr: "invalid signature"},
+		{name: "Tampered claims", token: tamper(rs256.sign(t, valid)), expectedErr: "invalid signature"},
+		{name: "Unsigned", token: unsigned(valid), expectedErr: "unsupported algorithm"},
+		{name: "Malformed", token: "not-a-token", expectedErr: "malformed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := verifyJWT(tt.token, keys, now)
+			if tt.expectedErr == "" {
+				if err != nil {
+					t.Errorf("Unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.expectedErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.expectedErr, err)
+			}
+		})
+	}
+}
+
+// tamper replaces a token's claims while keeping its signature
+func tamper(token string) string {
+	parts := strings.Split(token, ".")
+	parts[1] = base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"mallory","exp":9999999999}`))
+	return strings.Join(parts, ".")
+}
+
+// unsigned returns a token with the "none" algorithm
+func unsigned(claims map[string]interface{}) string {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	payload, _ := json.Marshal(claims)
+	return header + "." + base64.RawURLEncoding.EncodeToString(payload) + "."
+}
+
+// TestParseJWKS tests reading signing keys
+func TestParseJWKS(t *testing.T) {
+	tests := map[string]struct {
+		jwks        string
+		expectError bool
+		expectedLen int
+	}{
+		"Encryption keys skipped": {
+			jwks:        `{"keys":[{"kty":"OKP","crv":"Ed25519","use":"enc","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"},{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}]}`,
+			expectedLen: 1,
+		},
+		"Symmetric keys skipped": {
+			jwks:        `{"keys":[{"kty":"oct","k":"c2VjcmV0"},{"kty":"OKP","crv":"Ed25519","x":"11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo"}]}`,
+			expectedLen: 1,
+		},
+		"No usable keys":    {jwks: `{"keys":[{"kty":"oct","k":"c2VjcmV0"}]}`, expectError: true},
+		"Point off curve":   {jwks: `{"keys":[{"kty":"EC","crv":"P-256","x":"AQ","y":"AQ"}]}`, expectError: true},
+		"Short RSA key":     {jwks: `{"keys":[{"kty":"RSA","n":"AQAB","e":"AQAB"}]}`, expectError: true},
+		"Not a JWKS":        {jwks: `[]`, expectError: true},
+		"Unsupported curve": {jwks: `{"keys":[{"kty":"EC","crv":"secp256k1","x":"AQ","y":"AQ"}]}`, expectError: true},
+	}
+
+	for name, tt := range tests {
+		keys, err := parseJWKS([]byte(tt.jwks))
+		if (err != nil) != tt.expectError {
+			t.Errorf("%s: expected error=%v, got %v", name, tt.expectError, err)
+			continue
+		}
+		if err == nil && len(keys.keys) != tt.expectedLen {
+			t.Errorf("%s: expected %d keys, got %d", name, tt.expectedLen, len(keys.keys))
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package mcp
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// rateLimitPruneInterval is how often buckets that have refilled are dropped
+const rateLimitPruneInterval = time.Minute
+
+// readBody reads a request body of at most the configured size. Larger bodies
+// are answered with 413 and unreadable ones with 400; it reports whether the
+// body was read.
+func (s *Server) readBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	defer r.Body.Close()
+
+	// A declared length over the limit is refused before anything is read
+	if r.ContentLength > s.maxBodyBytes {
+		s.rejectBody(w, r)
+		return nil, false
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.maxBodyBytes))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		s.rejectBody(w, r)
+		return nil, false
+	}
+	if err != nil {
+		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+		return nil, false
+	}
+	return body, true
+}
+
+// rejectBody answers a request whose body exceeds the limit
+func (s *Server) rejectBody(w http.ResponseWriter, r *http.Request) {
+	logger.Warn("Rejected oversized request body", "max_bytes", s.maxBodyBytes, "remote", r.RemoteAddr)
+	http.Error(w, fmt.Sprintf("Request body exceeds the maximum size of %d bytes", s.maxBodyBytes), http.StatusRequestEntityTooLarge)
+}
+
+// rateLimiter keeps a token bucket for each key. A bucket holds up to burst
+// tokens, refills at rate tokens per second, and each request takes one.
+type rateLimiter struct {
+	rate  float64
+	burst float64
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// tokenBucket is the state of one key's bucket as of last
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter creates a limiter from the settings, or returns nil when rate
+// limiting is off
+func newRateLimiter(cfg RateLimitConfig) *rateLimiter {
+	if cfg.RequestsPerSecond <= 0 {
+		return nil
+	}
+	return &rateLimiter{
+		rate:    cfg.RequestsPerSecond,
+		burst:   math.Max(float64(cfg.Burst), 1),
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// allow takes a token from the key's bucket. When the bucket is empty it
+// returns false and how long until the next token is available.
+func (l *rateLimiter) allow(key string) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.prune(now)
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / l.rate * float64(time.Second))
+}
+
+// prune drops the buckets that have refilled, which behave like new ones, so
+// clients that come and go do not accumulate
+func (l *rateLimiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < rateLimitPruneInterval {
+		return
+	}
+	l.lastPrune = now
+
+	refill := time.Duration(l.burst / l.rate * float64(time.Second))
+	for key, b := range l.buckets {
+		if now.Sub(b.last) >= refill {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// limitRate answers 429 with Retry-After to callers that have used up their bucket
+func (s *Server) limitRate(next http.HandlerFunc) http.HandlerFunc {
+	if s.limiter == nil {
+		return next
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if ok, wait := s.limiter.allow(s.rateLimitKey(r)); !ok {
+			logger.Warn("Rate limited request", "remote", r.RemoteAddr, "retry_after", wait)
+			tooManyRequests(w, "Too many requests", wait)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// rateLimitKey names the bucket a request draws from: its session, else the
+// authenticated caller, else the client's address, or for Unix socket clients,
+// which have none, the connecting process. Session IDs only count once
+// issued, so made-up IDs cannot open fresh buckets.
+func (s *Server) rateLimitKey(r *http.Request) string {
+	if id := r.Header.Get(sessionIDHeader); id != "" && s.sessions.has(id) {
+		return "session:" + id
+	}
+	if principal, ok := PrincipalFromContext(r.Context()); ok && principal.Subject != "" {
+		return "principal:" + principal.Subject
+	}
+	if peer, ok := r.Context().Value(peerContextKey{}).(string); ok {
+		return "peer:" + peer
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "addr:" + host
+}
+
+// peerContextKey is the context key of the client identity of a Unix socket connection
+type peerContextKey struct{}
+
+// connSeq numbers Unix socket connections whose peer cannot be identified
+var connSeq atomic.Uint64
+
+// connContext records who is at the other end of a Unix socket connection, as
+// such clients share an empty remote address: the peer process where the
+// platform reports it, else the connection itself
+func (s *Server) connContext(ctx context.Context, conn net.Conn) context.Context {
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		conn = tlsConn.NetConn()
+	}
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return ctx
+	}
+	peer, ok := unixPeer(unixConn)
+	if !ok {
+		peer = "conn:" + strconv.FormatUint(connSeq.Add(1), 10)
+	}
+	return context.WithValue(ctx, peerContextKey{}, peer)
+}
+
+// tooManyRequests answers 429, asking the client to wait at least a second
+func tooManyRequests(w http.ResponseWriter, message string, wait time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(max(1, int(math.Ceil(wait.Seconds())))))
+	http.Error(w, message, http.StatusTooManyRequests)
+}
@@ -0,0 +1,253 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMaxBodyBytes tests that oversized request bodies are refused with 413
+func TestMaxBodyBytes(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MaxBodyBytes = 256
+	server := NewServerWithConfig(cfg)
+	server.EnableLegacyRoutes()
+	ts := httptest.NewServer(server.routes())
+	defer ts.Close()
+
+	large := `{"jsonrpc":"2.0","id":1,"method":"ping","params":{"pad":"` + strings.Repeat("x", 512) + `"}}`
+	tests := []struct {
+		name           string
+		path           string
+		body           io.Reader
+		expectedStatus int
+	}{
+		{name: "Within the limit", path: mcpEndpoint, body: strings.NewReader(testInitialize), expectedStatus: http.StatusOK},
+		{name: "Declared length over the limit", path: mcpEndpoint, body: strings.NewReader(large), expectedStatus: http.StatusRequestEntityTooLarge},
+		// Wrapping the reader hides its length, so the body is sent chunked
+		{name: "Chunked body over the limit", path: mcpEndpoint, body: io.MultiReader(strings.NewReader(large)), expectedStatus: http.StatusRequestEntityTooLarge},
+		{name: "Legacy route", path: "/mcp/v1/tools/call", body: strings.NewReader(large), expectedStatus: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, ts.URL+tt.path, tt.body)
+			req.Header.Set("Accept", "application/json")
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("POST failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+		})
+	}
+}
+
+// TestRateLimiter tests refilling and pruning token buckets
+func TestRateLimiter(t *testing.T) {
+	now := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+	limiter := newRateLimiter(RateLimitConfig{RequestsPerSecond: 2, Burst: 3})
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := limiter.allow("a"); !ok {
+			t.Fatalf("Expected request %d within the burst to be allowed", i+1)
+		}
+	}
+	if ok, wait := limiter.allow("a"); ok || wait != 500*time.Millisecond {
+		t.Errorf("Expected a denial with a 500ms wait, got %v %s", ok, wait)
+	}
+	if ok, _ := limiter.allow("b"); !ok {
+		t.Error("Expected another key to have its own bucket")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if ok, _ := limiter.allow("a"); !ok {
+		t.Error("Expected a token after refilling")
+	}
+	if ok, _ := limiter.allow("a"); ok {
+		t.Error("Expected the refilled token to be used up")
+	}
+
+	// Buckets that have refilled are dropped
+	now = now.Add(rateLimitPruneInterval)
+	limiter.allow("c")
+	if len(limiter.buckets) != 1 {
+		t.Errorf("Expected refilled buckets to be pruned, have %d", len(limiter.buckets))
+	}
+
+	if newRateLimiter(RateLimitConfig{}) != nil {
+		t.Error("Expected no limiter when rate limiting is off")
+	}
+}
+
+// TestRateLimit tests that each session and client is throttled separately
+func TestRateLimit(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.RateLimit = RateLimitConfig{RequestsPerSecond: 0.01, Burst: 2}
+	server := NewServerWithConfig(cfg)
+	ts := httptest.NewServer(server.routes())
+	defer ts.Close()
+
+	ping := `{"jsonrpc":"2.0","id":2,"method":"ping"}`
+	post := func(sessionID string) *http.Response {
+		t.Helper()
+		resp := sessionRequest(t, http.MethodPost, ts.URL, sessionID, ping)
+		resp.Body.Close()
+		return resp
+	}
+
+	// Initializing draws on the client's bucket; the session then has its own
+	initialized := sessionRequest(t, http.MethodPost, ts.URL, "", testInitialize)
+	initialized.Body.Close()
+	id := initialized.Header.Get(sessionIDHeader)
+
+	for i := 0; i < 2; i++ {
+		if resp := post(id); resp.StatusCode != http.StatusOK {
+			t.Fatalf("Expected request %d of the session to pass, got %d", i+1, resp.StatusCode)
+		}
+	}
+	resp := post(id)
+	if resp.StatusCode != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 once the session's burst is used, got %d", resp.StatusCode)
+	}
+	if retry := resp.Header.Get("Retry-After"); retry != "100" {
+		t.Errorf("Expected Retry-After of 100 seconds, got %q", retry)
+	}
+
+	second := sessionRequest(t, http.MethodPost, ts.URL, "", testInitialize)
+	second.Body.Close()
+	if second.StatusCode != http.StatusOK {
+		t.Errorf("Expected the client's own bucket to have a token left, got %d", second.StatusCode)
+	}
+	if resp := post("made-up"); resp.StatusCode != http.StatusTooManyRequests {
+		t.Errorf("Expected unknown session IDs to draw on the client's bucket, got %d", resp.StatusCode)
+	}
+
+	// Health checks are never throttled
+	health, _ := http.Get(ts.URL + "/health")
+	health.Body.Close()
+	if health.StatusCode != http.StatusOK {
+		t.Errorf("Expected health checks to pass, got %d", health.StatusCode)
+	}
+}
+
+// TestRateLimitKeyUnixSocket tests that Unix socket clients, which have no
+// remote address, draw on a bucket of their own
+func TestRateLimitKeyUnixSocket(t *testing.T) {
+	server := NewServer(8080)
+	socket := filepath.Join(t.TempDir(), "limits.sock")
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	defer listener.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+	}()
+	client, err := net.Dial("unix", socket)
+	if err != nil {
+		t.Fatalf("Failed to dial: %v", err)
+	}
+	defer client.Close()
+	conn := waitFor(t, accepted, "connection")
+	defer conn.Close()
+
+	r := httptest.NewRequest(http.MethodPost, mcpEndpoint, nil)
+	r.RemoteAddr = "@"
+	key := server.rateLimitKey(r.WithContext(server.connContext(r.Context(), conn)))
+
+	expected := "peer:conn:"
+	if runtime.GOOS == "linux" {
+		expected = "peer:pid:" + strconv.Itoa(os.Getpid())
+	}
+	if !strings.HasPrefix(key, expected) {
+		t.Errorf("Expected a key starting with %q, got %q", expected, key)
+	}
+
+	// TCP clients keep their address
+	if ctx := server.connContext(context.Background(), &net.TCPConn{}); ctx.Value(peerContextKey{}) != nil {
+		t.Error("Expected no peer for a TCP connection")
+	}
+}
+
+// TestMaxConcurrentTools tests that tool calls beyond the cap are turned away
+func TestMaxConcurrentTools(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MaxConcurrentTools = 1
+	server := NewServerWithConfig(cfg)
+	started, stopped := registerBlockingTool(server.handler)
+	ts := httptest.NewServer(server.routes())
+	defer ts.Close()
+
+	initialized := sessionRequest(t, http.MethodPost, ts.URL, "", testInitialize)
+	initialized.Body.Close()
+	id := initialized.Header.Get(sessionIDHeader)
+
+	call := `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"block"}}`
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		req, _ := http.NewRequestWithContext(ctx, http.MethodPost, ts.URL+mcpEndpoint, strings.NewReader(call))
+		req.Header.Set("Accept", "application/json")
+		req.Header.Set(sessionIDHeader, id)
+		req.Header.Set(protocolVersionHeader, LatestProtocolVersion)
+		if resp, err := http.DefaultClient.Do(req); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	waitFor(t, started, "first tool call to start")
+
+	resp := sessionRequest(t, http.MethodPost, ts.URL, id, call)
+	var reply Response
+	json.NewDecoder(resp.Body).Decode(&reply)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") == "" {
+		t.Fatalf("Expected 429 with Retry-After, got %d %q", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+	if reply.Error == nil || reply.Error.Code != CodeServerBusy {
+		t.Errorf("Expected a server busy error, got %#v", reply.Error)
+	}
+
+	// Other methods are unaffected, and the slot frees up when the call ends
+	list := sessionRequest(t, http.MethodPost, ts.URL, id, `{"jsonrpc":"2.0","id":4,"method":"tools/list"}`)
+	list.Body.Close()
+	if list.StatusCode != http.StatusOK {
+		t.Errorf("Expected tools/list to pass, got %d", list.StatusCode)
+	}
+	cancel()
+	waitFor(t, stopped, "first tool call to stop")
+	if !waitForSlot(server.dispatcher) {
+		t.Error("Expected the slot to be released")
+	}
+}
+
+// waitForSlot polls until a tool slot is free, releasing it again
+func waitForSlot(d *Dispatcher) bool {
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if release, ok := d.acquireToolSlot(); ok {
+			release()
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return false
+}
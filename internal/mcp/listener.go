@@ -0,0 +1,146 @@
+package mcp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// unixSocketMode lets the socket's owner and group connect; access is narrowed
+// further through the permissions of the directory holding it
+const unixSocketMode = 0660
+
+// ParseListenAddress splits a listen address into the network and address
+// net.Listen takes. It accepts "unix:///path/to.sock", "tcp://host:port" and
+// "host:port".
+func ParseListenAddress(listen string) (network, address string, err error) {
+	if path, ok := strings.CutPrefix(listen, "unix://"); ok {
+		if !strings.HasPrefix(path, "/") {
+			return "", "", fmt.Errorf("unix socket path in %q must be absolute", listen)
+		}
+		return "unix", path, nil
+	}
+
+	address = strings.TrimPrefix(listen, "tcp://")
+	if strings.Contains(address, "://") {
+		return "", "", fmt.Errorf("unsupported listen address %q (use unix:// or tcp://)", listen)
+	}
+	if _, port, err := net.SplitHostPort(address); err != nil || port == "" {
+		return "", "", fmt.Errorf("listen address %q must be unix:///path or host:port", listen)
+	}
+	return "tcp", address, nil
+}
+
+// listenHost returns the host a TCP listen address binds to, or "localhost"
+// for a Unix socket, which only local processes reach
+func listenHost(network, address string) string {
+	if network == "unix" {
+		return "localhost"
+	}
+	host, _, _ := net.SplitHostPort(address)
+	return host
+}
+
+// listen opens the server's listener, wrapped in TLS when a certificate is configured
+func (s *Server) listen() (net.Listener, error) {
+	if s.listenErr != nil {
+		return nil, s.listenErr
+	}
+
+	var tlsConfig *tls.Config
+	if s.tlsFiles.cert != "" {
+		var err error
+		if tlsConfig, err = loadTLSConfig(s.tlsFiles); err != nil {
+			return nil, err
+		}
+	}
+
+	var listener net.Listener
+	var err error
+	if s.network == "unix" {
+		listener, err = listenUnix(s.address)
+	} else {
+		listener, err = net.Listen(s.network, s.address)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s: %w", s.address, err)
+	}
+
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+	return listener, nil
+}
+
+// listenUnix listens on a Unix socket, replacing a socket file left behind by
+// a server that did not shut down cleanly
+func listenUnix(path string) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode().Type() != os.ModeSocket {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			conn.Close()
+			return nil, errors.New("another server is already listening on the socket")
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+
+	// The socket is created with no access beyond its owner, so it is never
+	// reachable with wider permissions before the chmod
+	var listener net.Listener
+	var err error
+	withUmask(0177, func() {
+		listener, err = net.Listen("unix", path)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, unixSocketMode); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to restrict socket permissions: %w", err)
+	}
+	return listener, nil
+}
+
+// tlsFiles are the PEM files of the server's TLS setup
+type tlsFiles struct {
+	cert     string
+	key      string
+	clientCA string // Clients must present a certificate signed by this CA when set
+}
+
+// loadTLSConfig reads the server certificate and, for mutual TLS, the CA client certificates must chain to
+func loadTLSConfig(files tlsFiles) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(files.cert, files.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load TLS certificate: %w", err)
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if files.clientCA != "" {
+		data, err := os.ReadFile(files.clientCA)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read client CA: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in client CA %s", files.clientCA)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return config, nil
+}
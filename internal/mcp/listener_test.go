@@ -0,0 +1,234 @@
+package mcp
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestParseListenAddress tests splitting listen addresses into network and address
+func TestParseListenAddress(t *testing.T) {
+	tests := []struct {
+		listen          string
+		expectedNetwork string
+		expectedAddress string
+		expectError     bool
+	}{
+		{listen: "unix:///run/user/1000/technocrat.sock", expectedNetwork: "unix", expectedAddress: "/run/user/1000/technocrat.sock"},
+		{listen: "tcp://127.0.0.1:9090", expectedNetwork: "tcp", expectedAddress: "127.0.0.1:9090"},
+		{listen: "[::1]:8080", expectedNetwork: "tcp", expectedAddress: "[::1]:8080"},
+		{listen: ":8443", expectedNetwork: "tcp", expectedAddress: ":8443"},
+		{listen: "unix://technocrat.sock", expectError: true},
+		{listen: "http://localhost:8080", expectError: true},
+		{listen: "localhost", expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.listen, func(t *testing.T) {
+			network, address, err := ParseListenAddress(tt.listen)
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected an error, got %s %s", network, address)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if network != tt.expectedNetwork || address != tt.expectedAddress {
+				t.Errorf("Expected %s %s, got %s %s", tt.expectedNetwork, tt.expectedAddress, network, address)
+			}
+		})
+	}
+}
+
+// serveListener serves the server's routes on its configured listener until the test ends
+func serveListener(t *testing.T, server *Server) net.Listener {
+	t.Helper()
+	listener, err := server.listen()
+	if err != nil {
+		t.Fatalf("Failed to listen: %v", err)
+	}
+	httpServer := &http.Server{Handler: server.routes(), ConnContext: server.connContext}
+	go httpServer.Serve(listener)
+	t.Cleanup(func() { httpServer.Close() })
+	return listener
+}
+
+// initializeStatus POSTs an initialize request with the client and returns the response status
+func initializeStatus(client *http.Client, url string) (int, error) {
+	resp, err := client.Post(url+mcpEndpoint, "application/json", strings.NewReader(testInitialize))
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// TestListenUnix tests serving over a Unix socket
+func TestListenUnix(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "technocrat.sock")
+
+	// A socket left behind by a crashed server is replaced
+	stale, err := net.ListenUnix("unix", &net.UnixAddr{Name: socket, Net: "unix"})
+	if err != nil {
+		t.Fatalf("Failed to create stale socket: %v", err)
+	}
+	stale.SetUnlinkOnClose(false)
+	stale.Close()
+
+	cfg := DefaultConfig()
+	cfg.Listen = "unix://" + socket
+	serveListener(t, NewServerWithConfig(cfg))
+
+	info, err := os.Stat(socket)
+	if err != nil {
+		t.Fatalf("Socket not created: %v", err)
+	}
+	if info.Mode().Perm() != unixSocketMode {
+		t.Errorf("Expected socket mode %o, got %o", unixSocketMode, info.Mode().Perm())
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+		},
+	}}
+	status, err := initializeStatus(client, "http://localhost")
+	if err != nil {
+		t.Fatalf("Request over socket failed: %v", err)
+	}
+	if status != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", status)
+	}
+
+	// A live socket is not taken over, and other files are not removed
+	if _, err := listenUnix(socket); err == nil {
+		t.Error("Expected an error for a socket in use")
+	}
+	file := filepath.Join(t.TempDir(), "notes.txt")
+	os.WriteFile(file, []byte("keep"), 0644)
+	if _, err := listenUnix(file); err == nil {
+		t.Error("Expected an error for a regular file")
+	}
+}
+
+// testCertificate issues a certificate signed by parent, or a self-signed CA when parent is nil
+func testCertificate(t *testing.T, name string, parent *tls.Certificate, usage x509.ExtKeyUsage) tls.Certificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Minute),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	issuer, signer := template, interface{}(key)
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		issuer, signer = parent.Leaf, parent.PrivateKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, &key.PublicKey, signer)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %v", err)
+	}
+	leaf, _ := x509.ParseCertificate(der)
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+// writePEM writes a certificate and its key as PEM files and returns their paths
+func writePEM(t *testing.T, dir, name string, cert tls.Certificate) (certFile, keyFile string) {
+	t.Helper()
+	certFile = filepath.Join(dir, name+".pem")
+	keyFile = filepath.Join(dir, name+".key")
+	keyDER, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %v", err)
+	}
+	os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate[0]}), 0644)
+	os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER}), 0600)
+	return certFile, keyFile
+}
+
+// TestListenTLS tests serving HTTPS with and without client certificates
+func TestListenTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := testCertificate(t, "Test CA", nil, x509.ExtKeyUsageAny)
+	caFile, _ := writePEM(t, dir, "ca", ca)
+	certFile, keyFile := writePEM(t, dir, "server", testCertificate(t, "localhost", &ca, x509.ExtKeyUsageServerAuth))
+	clientCert := testCertificate(t, "editor", &ca, x509.ExtKeyUsageClientAuth)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Leaf)
+	newClient := func(certs ...tls.Certificate) *http.Client {
+		return &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots, Certificates: certs}}}
+	}
+
+	tests := []struct {
+		name        string
+		clientCA    string
+		clientCerts []tls.Certificate
+		expectError bool
+	}{
+		{name: "TLS"},
+		{name: "Mutual TLS with a client certificate", clientCA: caFile, clientCerts: []tls.Certificate{clientCert}},
+		{name: "Mutual TLS without a client certificate", clientCA: caFile, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.Listen = "127.0.0.1:0"
+			cfg.TLSCert, cfg.TLSKey, cfg.TLSClientCA = certFile, keyFile, tt.clientCA
+			listener := serveListener(t, NewServerWithConfig(cfg))
+
+			status, err := initializeStatus(newClient(tt.clientCerts...), "https://"+listener.Addr().String())
+			if tt.expectError {
+				if err == nil {
+					t.Errorf("Expected the handshake to fail, got status %d", status)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			if status != http.StatusOK {
+				t.Errorf("Expected status 200, got %d", status)
+			}
+		})
+	}
+
+	// Plain HTTP is not answered on a TLS listener
+	cfg := DefaultConfig()
+	cfg.Listen = "127.0.0.1:0"
+	cfg.TLSCert, cfg.TLSKey = certFile, keyFile
+	listener := serveListener(t, NewServerWithConfig(cfg))
+	if status, err := initializeStatus(http.DefaultClient, "http://"+listener.Addr().String()); err == nil && status == http.StatusOK {
+		t.Error("Expected plain HTTP to be refused")
+	}
+}
@@ -0,0 +1,213 @@
+package mcp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// loggerName identifies the server in notifications/message
+const loggerName = "technocrat"
+
+// Log levels defined by MCP (the syslog severities of RFC 5424), mapped onto
+// slog levels so debug, info, warning and error line up with slog's own
+const (
+	LevelDebug     = slog.LevelDebug
+	LevelInfo      = slog.LevelInfo
+	LevelNotice    = slog.Level(2)
+	LevelWarning   = slog.LevelWarn
+	LevelError     = slog.LevelError
+	LevelCritical  = slog.Level(12)
+	LevelAlert     = slog.Level(16)
+	LevelEmergency = slog.Level(20)
+)
+
+// logLevelNames lists the MCP level names from least to most severe
+var logLevelNames = []struct {
+	name  string
+	level slog.Level
+}{
+	{"debug", LevelDebug},
+	{"info", LevelInfo},
+	{"notice", LevelNotice},
+	{"warning", LevelWarning},
+	{"error", LevelError},
+	{"critical", LevelCritical},
+	{"alert", LevelAlert},
+	{"emergency", LevelEmergency},
+}
+
+// ParseLogLevel converts an MCP level name such as "warning" to a slog level
+func ParseLogLevel(name string) (slog.Level, error) {
+	for _, l := range logLevelNames {
+		if l.name == name {
+			return l.level, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown log level %q", name)
+}
+
+// logLevelName returns the MCP name of the most severe level at or below level
+func logLevelName(level slog.Level) string {
+	name := logLevelNames[0].name
+	for _, l := range logLevelNames {
+		if level >= l.level {
+			name = l.name
+		}
+	}
+	return name
+}
+
+var (
+	// logLevel is the minimum level written to the log output
+	logLevel = new(slog.LevelVar)
+
+	// logOutput is where log records are written, stderr unless changed
+	logOutput = &logWriter{w: os.Stderr}
+
+	// logger is the package logger. Records logged with a request context are
+	// also forwarded to that request's client when it asked for them.
+	logger = slog.New(&clientLogHandler{
+		next: slog.NewTextHandler(logOutput, &slog.HandlerOptions{Level: logLevel}),
+	})
+)
+
+// Logger returns the server's structured logger. Tool and prompt handlers that
+// log with their request context also reach the client through notifications/message.
+func Logger() *slog.Logger {
+	return logger
+}
+
+// SetLogLevel sets the minimum MCP level, such as "info", written to the log output
+func SetLogLevel(name string) error {
+	level, err := ParseLogLevel(name)
+	if err != nil {
+		return err
+	}
+	logLevel.Set(level)
+	return nil
+}
+
+// SetLogOutput changes where log records are written
+func SetLogOutput(w io.Writer) {
+	logOutput.mu.Lock()
+	defer logOutput.mu.Unlock()
+	logOutput.w = w
+}
+
+// protectStdout keeps every log away from stdout, which carries the JSON-RPC
+// stream in stdio mode: the package logger and the standard log package are
+// sent to stderr if they were pointed at stdout.
+func protectStdout() {
+	logOutput.stdio.Store(true)
+	if log.Writer() == os.Stdout {
+		log.SetOutput(os.Stderr)
+	}
+}
+
+// logWriter serialises writes to a replaceable output
+type logWriter struct {
+	mu    sync.Mutex
+	w     io.Writer
+	stdio atomic.Bool
+}
+
+// Write writes a log record, diverting it to stderr in stdio mode if the
+// output is stdout
+func (lw *logWriter) Write(p []byte) (int, error) {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+
+	w := lw.w
+	if w == os.Stdout && lw.stdio.Load() {
+		w = os.Stderr
+	}
+	return w.Write(p)
+}
+
+// clientLogHandler writes records to the next handler and forwards those
+// logged with a request context to the session's client, at or above the
+// level the client chose with logging/setLevel. Groups only apply to the log
+// output; forwarded records carry their attributes flat.
+type clientLogHandler struct {
+	next  slog.Handler
+	attrs []slog.Attr
+}
+
+// Enabled reports whether the log output or the client wants the level
+func (h *clientLogHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level) || clientWantsLog(ctx, level)
+}
+
+// Handle writes the record and forwards it to the client
+func (h *clientLogHandler) Handle(ctx context.Context, record slog.Record) error {
+	var err error
+	if h.next.Enabled(ctx, record.Level) {
+		err = h.next.Handle(ctx, record)
+	}
+
+	if clientWantsLog(ctx, record.Level) {
+		data := map[string]interface{}{"message": record.Message}
+		for _, attr := range h.attrs {
+			data[attr.Key] = logValue(attr.Value)
+		}
+		record.Attrs(func(attr slog.Attr) bool {
+			data[attr.Key] = logValue(attr.Value)
+			return true
+		})
+
+		params := LogMessageParams{Level: logLevelName(record.Level), Logger: loggerName, Data: data}
+		// Delivery failures are not logged, which could loop back here
+		if notifier, ok := notifierFromContext(ctx); ok {
+			notifier.Notify("notifications/message", params)
+		} else if session, ok := SessionFromContext(ctx); ok {
+			session.notify("notifications/message", params)
+		}
+	}
+	return err
+}
+
+// WithAttrs returns a handler that adds attrs to every record
+func (h *clientLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &clientLogHandler{
+		next:  h.next.WithAttrs(attrs),
+		attrs: append(append([]slog.Attr{}, h.attrs...), attrs...),
+	}
+}
+
+// WithGroup returns a handler that groups the log output's attributes
+func (h *clientLogHandler) WithGroup(name string) slog.Handler {
+	return &clientLogHandler{next: h.next.WithGroup(name), attrs: h.attrs}
+}
+
+// clientWantsLog reports whether the session of the request in ctx asked for records at level
+func clientWantsLog(ctx context.Context, level slog.Level) bool {
+	session, ok := SessionFromContext(ctx)
+	return ok && session.wantsLog(level)
+}
+
+// logValue converts an attribute value to something JSON can encode
+func logValue(value slog.Value) interface{} {
+	value = value.Resolve()
+	switch value.Kind() {
+	case slog.KindAny:
+		if err, ok := value.Any().(error); ok {
+			return err.Error()
+		}
+		return value.Any()
+	case slog.KindGroup:
+		group := make(map[string]interface{})
+		for _, attr := range value.Group() {
+			group[attr.Key] = logValue(attr.Value)
+		}
+		return group
+	case slog.KindDuration, slog.KindTime:
+		return value.String()
+	}
+	return value.Any()
+}
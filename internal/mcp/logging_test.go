@@ -0,0 +1,163 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// logNotifier records the notifications/message notifications sent through it
+type logNotifier struct {
+	mu       sync.Mutex
+	messages []LogMessageParams
+}
+
+// Notify records log messages and ignores other notifications
+func (n *logNotifier) Notify(method string, params interface{}) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if p, ok := params.(LogMessageParams); ok && method == "notifications/message" {
+		n.messages = append(n.messages, p)
+	}
+	return nil
+}
+
+// sent returns the recorded log messages
+func (n *logNotifier) sent() []LogMessageParams {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]LogMessageParams(nil), n.messages...)
+}
+
+// captureLogOutput sends the package logger's output to a buffer for the rest of the test
+func captureLogOutput(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	SetLogOutput(&buf)
+	t.Cleanup(func() { SetLogOutput(os.Stderr) })
+	return &buf
+}
+
+// TestParseLogLevel tests converting MCP level names to slog levels and back
+func TestParseLogLevel(t *testing.T) {
+	for _, name := range []string{"debug", "info", "notice", "warning", "error", "critical", "alert", "emergency"} {
+		level, err := ParseLogLevel(name)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if got := logLevelName(level); got != name {
+			t.Errorf("%s: round trip gave %q", name, got)
+		}
+	}
+
+	for _, name := range []string{"", "warn", "INFO", "verbose"} {
+		if _, err := ParseLogLevel(name); err == nil {
+			t.Errorf("%q: expected an error", name)
+		}
+	}
+
+	if got := logLevelName(LevelWarning + 1); got != "warning" {
+		t.Errorf("Expected levels between names to round down to warning, got %q", got)
+	}
+}
+
+// TestLogForwarding tests that records reach the client only at or above the level it set
+func TestLogForwarding(t *testing.T) {
+	output := captureLogOutput(t)
+
+	session := NewSession()
+	notifier := &logNotifier{}
+	ctx := ContextWithNotifier(ContextWithSession(context.Background(), session), notifier)
+
+	logger.WarnContext(ctx, "before setLevel")
+	if sent := notifier.sent(); len(sent) != 0 {
+		t.Fatalf("Expected nothing forwarded before logging/setLevel, got %v", sent)
+	}
+
+	session.setLogLevel(LevelWarning)
+	logger.InfoContext(ctx, "too quiet")
+	logger.With("component", "test").ErrorContext(ctx, "forwarded", "path", "specs/001-auth")
+
+	sent := notifier.sent()
+	if len(sent) != 1 {
+		t.Fatalf("Expected one forwarded record, got %v", sent)
+	}
+	if sent[0].Level != "error" || sent[0].Logger != loggerName {
+		t.Errorf("Unexpected level or logger: %+v", sent[0])
+	}
+	data := sent[0].Data.(map[string]interface{})
+	if data["message"] != "forwarded" || data["path"] != "specs/001-auth" || data["component"] != "test" {
+		t.Errorf("Unexpected data: %v", data)
+	}
+
+	if !strings.Contains(output.String(), "msg=forwarded") {
+		t.Errorf("Expected the record in the log output, got %q", output.String())
+	}
+
+	// Without a request notifier, records go to the session's own notifier
+	sessionNotifier := &logNotifier{}
+	session.setNotifier(sessionNotifier)
+	logger.WarnContext(ContextWithSession(context.Background(), session), "background")
+	if sent := sessionNotifier.sent(); len(sent) != 1 || sent[0].Level != "warning" {
+		t.Errorf("Expected the record on the session notifier, got %v", sent)
+	}
+}
+
+// TestSetLevel tests the logging/setLevel method
+func TestSetLevel(t *testing.T) {
+	d := NewDispatcher(NewHandler())
+	session := NewSession()
+	ctx := ContextWithSession(context.Background(), session)
+
+	resp := d.Handle(ctx, newTestRequest(t, 1, "logging/setLevel", SetLevelParams{Level: "verbose"}))
+	if resp.Error == nil || resp.Error.Code != CodeInvalidParams {
+		t.Fatalf("Expected invalid params for an unknown level, got %+v", resp)
+	}
+
+	resp = d.Handle(ctx, newTestRequest(t, 2, "logging/setLevel", SetLevelParams{Level: "notice"}))
+	if resp.Error != nil {
+		t.Fatalf("Unexpected error: %v", resp.Error)
+	}
+	if session.wantsLog(LevelInfo) || !session.wantsLog(LevelNotice) {
+		t.Error("Expected the session to want records at notice and above")
+	}
+
+	if d.InitializeResult(LatestProtocolVersion).Capabilities.Logging == nil {
+		t.Error("Expected the logging capability to be advertised")
+	}
+}
+
+// TestLogWriterProtectsStdout tests that stdio mode diverts log output away from stdout
+func TestLogWriterProtectsStdout(t *testing.T) {
+	dir := t.TempDir()
+	stdout, err := os.Create(filepath.Join(dir, "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdout.Close()
+	stderr, err := os.Create(filepath.Join(dir, "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stderr.Close()
+
+	savedStdout, savedStderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = stdout, stderr
+	defer func() { os.Stdout, os.Stderr = savedStdout, savedStderr }()
+
+	lw := &logWriter{w: os.Stdout}
+	lw.Write([]byte("before\n"))
+	lw.stdio.Store(true)
+	lw.Write([]byte("after\n"))
+
+	if data, _ := os.ReadFile(stdout.Name()); string(data) != "before\n" {
+		t.Errorf("Expected only the record written before stdio mode on stdout, got %q", data)
+	}
+	if data, _ := os.ReadFile(stderr.Name()); string(data) != "after\n" {
+		t.Errorf("Expected the record written in stdio mode on stderr, got %q", data)
+	}
+}
@@ -1,6 +1,7 @@
 package mcp
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 	"strings"
@@ -59,7 +60,7 @@ This project provides a comprehensive API integration platform.
 	
 	// Execute prompt handler with user input
 	userInput := "Create REST API endpoints for user management"
-	result, err := prompt.Handler(map[string]interface{}{
+	result, err := prompt.Handler(context.Background(), map[string]interface{}{
 		"user_input": userInput,
 	})
 	if err != nil {
@@ -67,20 +68,16 @@ This project provides a comprehensive API integration platform.
 	}
 	
 	// Extract message content
-	resultMap, ok := result.(map[string]interface{})
+	promptResult, ok := result.(*GetPromptResult)
 	if !ok {
-		t.Fatal("Result is not a map")
+		t.Fatal("Result is not a *GetPromptResult")
 	}
-	
-	messages, ok := resultMap["messages"].([]map[string]interface{})
-	if !ok || len(messages) == 0 {
+
+	if len(promptResult.Messages) == 0 {
 		t.Fatal("No messages in result")
 	}
-	
-	content, ok := messages[0]["content"].(string)
-	if !ok {
-		t.Fatal("Message content is not a string")
-	}
+
+	content := promptResult.Messages[0].Content.Text
 	
 	// Verify user input was substituted
 	if !strings.Contains(content, userInput) {
@@ -0,0 +1,285 @@
+package mcp
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metricsPath is where the metrics are served, and metricsContentType their
+// Prometheus text exposition format
+const (
+	metricsPath        = "/metrics"
+	metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+)
+
+// defaultLatencyBuckets are the histogram bounds in seconds, as used by the
+// Prometheus client libraries
+var defaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Outcomes recorded for requests, tool calls, prompts and resource reads
+const (
+	outcomeOK        = "ok"
+	outcomeError     = "error"
+	outcomeCancelled = "cancelled"
+)
+
+// metrics holds the process-wide counters and histograms served at /metrics
+var metrics = newServerMetrics()
+
+// serverMetrics are the metric families the server records
+type serverMetrics struct {
+	registry *metricsRegistry
+
+	requests        *metricFamily
+	requestDuration *metricFamily
+	toolCalls       *metricFamily
+	toolDuration    *metricFamily
+	promptGets      *metricFamily
+	promptDuration  *metricFamily
+	resourceReads   *metricFamily
+	resourceLatency *metricFamily
+	renderFailures  *metricFamily
+	workspaceDetect *metricFamily
+}
+
+// newServerMetrics registers the server's metric families
+func newServerMetrics() *serverMetrics {
+	r := &metricsRegistry{}
+	return &serverMetrics{
+		registry: r,
+
+		requests: r.counter("technocrat_mcp_requests_total",
+			"MCP requests handled, by method and outcome.", "method", "outcome"),
+		requestDuration: r.histogram("technocrat_mcp_request_duration_seconds",
+			"Time taken to handle MCP requests, by method.", "method"),
+		toolCalls: r.counter("technocrat_tool_calls_total",
+			"Tool calls, by tool and outcome.", "tool", "outcome"),
+		toolDuration: r.histogram("technocrat_tool_call_duration_seconds",
+			"Time taken by tool calls, by tool.", "tool"),
+		promptGets: r.counter("technocrat_prompt_gets_total",
+			"Prompts rendered, by prompt and outcome.", "prompt", "outcome"),
+		promptDuration: r.histogram("technocrat_prompt_get_duration_seconds",
+			"Time taken to render prompts, by prompt.", "prompt"),
+		resourceReads: r.counter("technocrat_resource_reads_total",
+			"Resource reads, by resource or template and outcome.", "resource", "outcome"),
+		resourceLatency: r.histogram("technocrat_resource_read_duration_seconds",
+			"Time taken to read resources, by resource or template.", "resource"),
+		renderFailures: r.counter("technocrat_template_render_failures_total",
+			"Workflow templates that failed to render, by command and phase.", "command", "phase"),
+		workspaceDetect: r.histogram("technocrat_workspace_detection_duration_seconds",
+			"Time taken to detect the workspace, from the working directory or the client's roots.", "source"),
+	}
+}
+
+// outcomeOf classifies the error a request, tool, prompt or read ended with
+func outcomeOf(err error) string {
+	switch {
+	case err == nil:
+		return outcomeOK
+	case isContextError(err):
+		return outcomeCancelled
+	default:
+		return outcomeError
+	}
+}
+
+// metricsRegistry holds metric families in the order they were registered
+type metricsRegistry struct {
+	families []*metricFamily
+}
+
+// metricFamily is a counter or histogram and its series, one for each
+// combination of label values seen
+type metricFamily struct {
+	name    string
+	help    string
+	kind    string // "counter" or "histogram"
+	labels  []string
+	buckets []float64
+
+	mu     sync.Mutex
+	series map[string]*metricSeries
+}
+
+// metricSeries is the state of one labelled series. Histogram counts are per
+// bucket here and made cumulative when written.
+type metricSeries struct {
+	labelValues []string
+	value       float64
+	counts      []uint64
+	sum         float64
+	count       uint64
+}
+
+// counter registers a counter family
+func (r *metricsRegistry) counter(name, help string, labels ...string) *metricFamily {
+	return r.register(&metricFamily{name: name, help: help, kind: "counter", labels: labels})
+}
+
+// histogram registers a latency histogram family with the default buckets
+func (r *metricsRegistry) histogram(name, help string, labels ...string) *metricFamily {
+	return r.register(&metricFamily{name: name, help: help, kind: "histogram", labels: labels, buckets: defaultLatencyBuckets})
+}
+
+// register adds a family to the registry
+func (r *metricsRegistry) register(f *metricFamily) *metricFamily {
+	f.series = make(map[string]*metricSeries)
+	r.families = append(r.families, f)
+	return f
+}
+
+// seriesFor returns the series for the label values, creating it on first use.
+// f.mu must be held.
+func (f *metricFamily) seriesFor(values []string) *metricSeries {
+	key := strings.Join(values, "\xff")
+	s, ok := f.series[key]
+	if !ok {
+		s = &metricSeries{labelValues: append([]string(nil), values...)}
+		if f.kind == "histogram" {
+			s.counts = make([]uint64, len(f.buckets))
+		}
+		f.series[key] = s
+	}
+	return s
+}
+
+// inc adds one to the counter with the label values
+func (f *metricFamily) inc(values ...string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.seriesFor(values).value++
+}
+
+// observe records a duration in the histogram with the label values
+func (f *metricFamily) observe(d time.Duration, values ...string) {
+	seconds := d.Seconds()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s := f.seriesFor(values)
+	if i := sort.SearchFloat64s(f.buckets, seconds); i < len(f.buckets) {
+		s.counts[i]++
+	}
+	s.sum += seconds
+	s.count++
+}
+
+// value returns the counter value, or the histogram's observation count, for
+// the label values
+func (f *metricFamily) value(values ...string) float64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	s, ok := f.series[strings.Join(values, "\xff")]
+	switch {
+	case !ok:
+		return 0
+	case f.kind == "histogram":
+		return float64(s.count)
+	default:
+		return s.value
+	}
+}
+
+// write writes every family in the text exposition format
+func (r *metricsRegistry) write(w io.Writer) error {
+	for _, f := range r.families {
+		if err := f.write(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// write writes the family's series, sorted by label values
+func (f *metricFamily) write(w io.Writer) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# HELP %s %s\n# TYPE %s %s\n", f.name, f.help, f.name, f.kind)
+
+	keys := make([]string, 0, len(f.series))
+	for key := range f.series {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := f.series[key]
+		if f.kind != "histogram" {
+			fmt.Fprintf(&b, "%s%s %s\n", f.name, formatLabels(f.labels, s.labelValues), formatFloat(s.value))
+			continue
+		}
+
+		// The full slice expressions make append copy rather than share arrays
+		bucketLabels := append(f.labels[:len(f.labels):len(f.labels)], "le")
+		values := s.labelValues[:len(s.labelValues):len(s.labelValues)]
+		var cumulative uint64
+		for i, bound := range f.buckets {
+			cumulative += s.counts[i]
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", f.name, formatLabels(bucketLabels, append(values, formatFloat(bound))), cumulative)
+		}
+		fmt.Fprintf(&b, "%s_bucket%s %d\n", f.name, formatLabels(bucketLabels, append(values, "+Inf")), s.count)
+		fmt.Fprintf(&b, "%s_sum%s %s\n", f.name, formatLabels(f.labels, s.labelValues), formatFloat(s.sum))
+		fmt.Fprintf(&b, "%s_count%s %d\n", f.name, formatLabels(f.labels, s.labelValues), s.count)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeGauge writes a single unlabelled gauge in the text exposition format
+func writeGauge(w io.Writer, name, help string, value float64) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %s\n", name, help, name, name, formatFloat(value))
+	return err
+}
+
+// formatLabels renders a label set such as {tool="list_features",outcome="ok"}
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + `="` + labelEscaper.Replace(values[i]) + `"`
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// labelEscaper escapes label values as the exposition format requires
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// formatFloat renders a sample value the way Prometheus does
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// handleMetrics serves the metrics in the Prometheus text exposition format
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", "GET")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", metricsContentType)
+	if err := metrics.registry.write(w); err != nil {
+		logger.Error("Failed to write metrics", "error", err)
+		return
+	}
+	if err := writeGauge(w, "technocrat_sessions_active", "HTTP sessions currently open.", float64(s.sessions.len())); err != nil {
+		logger.Error("Failed to write metrics", "error", err)
+	}
+}
@@ -0,0 +1,180 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestMetricsExposition tests the text exposition format of counters and histograms
+func TestMetricsExposition(t *testing.T) {
+	r := &metricsRegistry{}
+	calls := r.counter("test_calls_total", "Calls made.", "tool", "outcome")
+	latency := r.histogram("test_duration_seconds", "Time taken.", "tool")
+
+	calls.inc("b", outcomeOK)
+	calls.inc("a", outcomeError)
+	calls.inc("a", outcomeError)
+	calls.inc(`quote"back\slash`, outcomeOK)
+	latency.observe(3*time.Millisecond, "a")
+	latency.observe(2*time.Second, "a")
+	latency.observe(20*time.Second, "a")
+
+	var b strings.Builder
+	if err := r.write(&b); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+
+	expected := `# HELP test_calls_total Calls made.
+# TYPE test_calls_total counter
+test_calls_total{tool="a",outcome="error"} 2
+test_calls_total{tool="b",outcome="ok"} 1
+test_calls_total{tool="quote\"back\\slash",outcome="ok"} 1
+# HELP test_duration_seconds Time taken.
+# TYPE test_duration_seconds histogram
+test_duration_seconds_bucket{tool="a",le="0.005"} 1
+test_duration_seconds_bucket{tool="a",le="0.01"} 1
+test_duration_seconds_bucket{tool="a",le="0.025"} 1
+test_duration_seconds_bucket{tool="a",le="0.05"} 1
+test_duration_seconds_bucket{tool="a",le="0.1"} 1
+test_duration_seconds_bucket{tool="a",le="0.25"} 1
+test_duration_seconds_bucket{tool="a",le="0.5"} 1
+test_duration_seconds_bucket{tool="a",le="1"} 1
+test_duration_seconds_bucket{tool="a",le="2.5"} 2
+test_duration_seconds_bucket{tool="a",le="5"} 2
+test_duration_seconds_bucket{tool="a",le="10"} 2
+test_duration_seconds_bucket{tool="a",le="+Inf"} 3
+test_duration_seconds_sum{tool="a"} 22.003
+test_duration_seconds_count{tool="a"} 3
+`
+	if b.String() != expected {
+		t.Errorf("Unexpected exposition:\n%s\nexpected:\n%s", b.String(), expected)
+	}
+}
+
+// TestMetricsRecording tests that tool calls, prompts, reads, render failures
+// and workspace detection are recorded
+func TestMetricsRecording(t *testing.T) {
+	h := NewHandler()
+	h.RegisterTool(Tool{
+		Name:        "metrics_flaky",
+		InputSchema: map[string]interface{}{"type": "object"},
+		Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			if args["fail"] == true {
+				return nil, errors.New("boom")
+			}
+			return "fine", nil
+		},
+	})
+	h.RegisterPrompt(Prompt{
+		Name: "metrics_prompt",
+		Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			return ProcessTemplate("{{.Missing", TemplateData{CommandName: "metrics_prompt"})
+		},
+	})
+	h.RegisterResource(Resource{
+		URI:  "test://metrics",
+		Name: "metrics",
+		Reader: func(ctx context.Context, uri string) (*ResourceContents, error) {
+			return &ResourceContents{URI: uri, Text: "counted"}, nil
+		},
+	})
+
+	tests := []struct {
+		name   string
+		family *metricFamily
+		labels []string
+		action func()
+	}{
+		{name: "Tool success", family: metrics.toolCalls, labels: []string{"metrics_flaky", outcomeOK}, action: func() {
+			h.CallTool("metrics_flaky", nil)
+		}},
+		{name: "Tool error result", family: metrics.toolCalls, labels: []string{"metrics_flaky", outcomeError}, action: func() {
+			h.CallTool("metrics_flaky", map[string]interface{}{"fail": true})
+		}},
+		{name: "Unknown tool", family: metrics.toolCalls, labels: []string{"unknown", outcomeError}, action: func() {
+			h.CallTool("no_such_tool", nil)
+		}},
+		{name: "Tool latency", family: metrics.toolDuration, labels: []string{"metrics_flaky"}, action: func() {
+			h.CallTool("metrics_flaky", nil)
+		}},
+		{name: "Prompt failure", family: metrics.promptGets, labels: []string{"metrics_prompt", outcomeError}, action: func() {
+			h.GetPrompt("metrics_prompt", nil)
+		}},
+		{name: "Render failure", family: metrics.renderFailures, labels: []string{"metrics_prompt", "parse"}, action: func() {
+			h.GetPrompt("metrics_prompt", nil)
+		}},
+		{name: "Resource read", family: metrics.resourceReads, labels: []string{"test://metrics", outcomeOK}, action: func() {
+			h.ReadResource("test://metrics")
+		}},
+		{name: "Unknown resource", family: metrics.resourceReads, labels: []string{"unknown", outcomeError}, action: func() {
+			h.ReadResource("test://nothing")
+		}},
+		{name: "Workspace detection", family: metrics.workspaceDetect, labels: []string{"cwd"}, action: func() {
+			DetectWorkspaceContext()
+		}},
+		{name: "Request", family: metrics.requests, labels: []string{"ping", outcomeOK}, action: func() {
+			NewDispatcher(h).Handle(context.Background(), newTestRequest(t, 1, "ping", nil))
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := tt.family.value(tt.labels...)
+			tt.action()
+			if after := tt.family.value(tt.labels...); after != before+1 {
+				t.Errorf("Expected %s%v to grow by 1, went from %v to %v", tt.family.name, tt.labels, before, after)
+			}
+		})
+	}
+}
+
+// TestMetricsEndpoint tests serving the metrics over HTTP
+func TestMetricsEndpoint(t *testing.T) {
+	server := NewServer(8080)
+	ts := httptest.NewServer(server.routes())
+	defer ts.Close()
+
+	initialized := sessionRequest(t, http.MethodPost, ts.URL, "", testInitialize)
+	initialized.Body.Close()
+
+	resp, err := http.Get(ts.URL + metricsPath)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != metricsContentType {
+		t.Fatalf("Expected metrics, got %d %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	for _, want := range []string{
+		"# TYPE technocrat_mcp_requests_total counter",
+		`technocrat_mcp_requests_total{method="initialize",outcome="ok"}`,
+		`technocrat_mcp_request_duration_seconds_bucket{method="initialize",le="+Inf"}`,
+		"# TYPE technocrat_sessions_active gauge\ntechnocrat_sessions_active 1\n",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("Expected metrics to contain %q", want)
+		}
+	}
+
+	// Metrics sit behind the server's authentication
+	auth, _ := NewAuthenticator(AuthConfig{Mode: AuthModeBearer, Tokens: []AuthToken{{Name: "prometheus", Token: "scrape-token-0123456789"}}})
+	server.SetAuthenticator(auth)
+	protected := httptest.NewServer(server.routes())
+	defer protected.Close()
+	unauthorized, err := http.Get(protected.URL + metricsPath)
+	if err != nil {
+		t.Fatalf("GET failed: %v", err)
+	}
+	unauthorized.Body.Close()
+	if unauthorized.StatusCode != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a token, got %d", unauthorized.StatusCode)
+	}
+}
@@ -0,0 +1,115 @@
+package mcp
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// originPolicy protects a local server from browsers: the Host header check
+// defeats DNS rebinding, where a hostile page resolves its own name to the
+// loopback address, and the Origin check stops pages on other sites from
+// calling the server directly
+type originPolicy struct {
+	// hosts are the accepted Host header names, with or without a port; a
+	// name without a port matches any port. anyHost turns the check off.
+	hosts   map[string]bool
+	anyHost bool
+
+	// origins are the accepted browser origins besides loopback ones
+	origins map[string]bool
+}
+
+// newOriginPolicy accepts the loopback names, the bind address and the
+// configured hosts and origins. Servers bound to every interface accept any
+// Host unless allowed hosts are configured, as their names cannot be known.
+func newOriginPolicy(cfg Config) *originPolicy {
+	p := &originPolicy{
+		hosts:   map[string]bool{"localhost": true, "127.0.0.1": true, "::1": true},
+		origins: make(map[string]bool),
+	}
+	network, address, _ := cfg.ListenAddress()
+	if bind := listenHost(network, address); !isWildcardHost(bind) {
+		p.hosts[strings.ToLower(bind)] = true
+	} else if len(cfg.AllowedHosts) == 0 {
+		p.anyHost = true
+	}
+	for _, host := range cfg.AllowedHosts {
+		p.hosts[strings.ToLower(strings.Trim(host, "[]"))] = true
+	}
+	if resource, err := url.Parse(cfg.Auth.Resource); err == nil && resource.Host != "" {
+		p.hosts[strings.ToLower(resource.Hostname())] = true
+	}
+	for _, origin := range cfg.AllowedOrigins {
+		p.origins[normalizeOrigin(origin)] = true
+	}
+	return p
+}
+
+// allowsHost reports whether the request's Host header names this server
+func (p *originPolicy) allowsHost(hostport string) bool {
+	if p.anyHost {
+		return true
+	}
+	hostport = strings.ToLower(hostport)
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = strings.Trim(hostport, "[]")
+	}
+	return p.hosts[host] || p.hosts[hostport]
+}
+
+// allowsOrigin reports whether a browser page from origin may call the
+// server. Pages served from the loopback interface always may.
+func (p *originPolicy) allowsOrigin(origin string) bool {
+	u, err := url.Parse(origin)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return false
+	}
+	return isLoopbackHost(u.Hostname()) || p.origins[normalizeOrigin(origin)]
+}
+
+// normalizeOrigin lower-cases an origin and drops a trailing slash
+func normalizeOrigin(origin string) string {
+	return strings.TrimSuffix(strings.ToLower(origin), "/")
+}
+
+// isLoopbackHost reports whether host names the loopback interface
+func isLoopbackHost(host string) bool {
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return ip != nil && ip.IsLoopback()
+}
+
+// isWildcardHost reports whether binding to host listens on every interface
+func isWildcardHost(host string) bool {
+	ip := net.ParseIP(strings.Trim(host, "[]"))
+	return host == "" || (ip != nil && ip.IsUnspecified())
+}
+
+// checkOrigin rejects requests whose Host or Origin the policy does not
+// accept. Health checks are exempt so load balancers can reach them by address.
+func (s *Server) checkOrigin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !s.origins.allowsHost(r.Host) {
+			logger.Warn("Rejected request for unknown host", "host", r.Host, "remote", r.RemoteAddr)
+			http.Error(w, "Forbidden: host not allowed", http.StatusForbidden)
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" && !s.origins.allowsOrigin(origin) {
+			logger.Warn("Rejected request from disallowed origin", "origin", origin, "remote", r.RemoteAddr)
+			http.Error(w, "Forbidden: origin not allowed", http.StatusForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
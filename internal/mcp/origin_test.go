@@ -0,0 +1,105 @@
+package mcp
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestOriginPolicy tests which Host and Origin headers are accepted
+func TestOriginPolicy(t *testing.T) {
+	loopback := DefaultConfig()
+
+	lan := DefaultConfig()
+	lan.Bind = "192.168.1.20"
+	lan.AllowedHosts = []string{"devbox.local", "mcp.example.com:8443"}
+	lan.AllowedOrigins = []string{"https://app.example.com/"}
+
+	wildcard := DefaultConfig()
+	wildcard.Bind = "0.0.0.0"
+
+	tests := []struct {
+		name        string
+		cfg         Config
+		host        string
+		origin      string
+		expectHost  bool
+		expectAllow bool
+	}{
+		{name: "Loopback address", cfg: loopback, host: "127.0.0.1:8080", expectHost: true},
+		{name: "Localhost any port", cfg: loopback, host: "LOCALHOST:3000", expectHost: true},
+		{name: "IPv6 loopback", cfg: loopback, host: "[::1]:8080", expectHost: true},
+		{name: "Rebound name", cfg: loopback, host: "attacker.example:8080"},
+		{name: "Bind address", cfg: lan, host: "192.168.1.20:8080", expectHost: true},
+		{name: "Allowed name", cfg: lan, host: "devbox.local:8080", expectHost: true},
+		{name: "Allowed name and port", cfg: lan, host: "mcp.example.com:8443", expectHost: true},
+		{name: "Allowed name on another port", cfg: lan, host: "mcp.example.com:9000"},
+		{name: "Wildcard bind without allowed hosts", cfg: wildcard, host: "anything.example", expectHost: true},
+
+		{name: "Loopback origin", cfg: loopback, origin: "http://localhost:6274", expectAllow: true},
+		{name: "Loopback IP origin", cfg: loopback, origin: "http://127.0.0.1:5173", expectAllow: true},
+		{name: "Foreign origin", cfg: loopback, origin: "https://attacker.example"},
+		{name: "Opaque origin", cfg: loopback, origin: "null"},
+		{name: "Allowed origin", cfg: lan, origin: "https://APP.example.com", expectAllow: true},
+		{name: "Allowed origin over another scheme", cfg: lan, origin: "http://app.example.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := newOriginPolicy(tt.cfg)
+			if tt.host != "" && policy.allowsHost(tt.host) != tt.expectHost {
+				t.Errorf("Expected allowsHost(%q)=%v", tt.host, tt.expectHost)
+			}
+			if tt.origin != "" && policy.allowsOrigin(tt.origin) != tt.expectAllow {
+				t.Errorf("Expected allowsOrigin(%q)=%v", tt.origin, tt.expectAllow)
+			}
+		})
+	}
+}
+
+// TestCheckOrigin tests that requests from rebound names and foreign pages are rejected
+func TestCheckOrigin(t *testing.T) {
+	server := NewServer(8080)
+	ts := httptest.NewServer(server.routes())
+	defer ts.Close()
+
+	tests := []struct {
+		name           string
+		path           string
+		host           string
+		origin         string
+		expectedStatus int
+	}{
+		{name: "Local client", path: mcpEndpoint, expectedStatus: http.StatusOK},
+		{name: "Local page", path: mcpEndpoint, origin: "http://localhost:6274", expectedStatus: http.StatusOK},
+		{name: "DNS rebinding", path: mcpEndpoint, host: "attacker.example", origin: "http://attacker.example", expectedStatus: http.StatusForbidden},
+		{name: "Foreign page", path: mcpEndpoint, origin: "https://attacker.example", expectedStatus: http.StatusForbidden},
+		{name: "Health check by any name", path: "/health", host: "lb.internal", expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := http.MethodPost
+			if tt.path == "/health" {
+				method = http.MethodGet
+			}
+			req, _ := http.NewRequest(method, ts.URL+tt.path, strings.NewReader(testInitialize))
+			req.Header.Set("Accept", "application/json")
+			if tt.host != "" {
+				req.Host = tt.host
+			}
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("Request failed: %v", err)
+			}
+			resp.Body.Close()
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+//go:build linux
+
+package mcp
+
+import (
+	"net"
+	"strconv"
+	"syscall"
+)
+
+// unixPeer identifies the process at the other end of a Unix socket
+// connection by the PID the kernel recorded for it
+func unixPeer(conn *net.UnixConn) (string, bool) {
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return "", false
+	}
+	var cred *syscall.Ucred
+	var credErr error
+	if err := raw.Control(func(fd uintptr) {
+		cred, credErr = syscall.GetsockoptUcred(int(fd), syscall.SOL_SOCKET, syscall.SO_PEERCRED)
+	}); err != nil || credErr != nil {
+		return "", false
+	}
+	return "pid:" + strconv.Itoa(int(cred.Pid)), true
+}
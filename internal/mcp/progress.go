@@ -0,0 +1,130 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"technocrat/internal/ui"
+)
+
+// Notifier delivers server-initiated notifications to the client that sent the current request
+type Notifier interface {
+	Notify(method string, params interface{}) error
+}
+
+// NotifierFunc adapts a function to the Notifier interface
+type NotifierFunc func(method string, params interface{}) error
+
+// Notify calls f(method, params)
+func (f NotifierFunc) Notify(method string, params interface{}) error {
+	return f(method, params)
+}
+
+// notifierContextKey is the context key under which the transport's notifier is stored
+type notifierContextKey struct{}
+
+// ContextWithNotifier returns a context carrying the notifier for the current client
+func ContextWithNotifier(ctx context.Context, notifier Notifier) context.Context {
+	return context.WithValue(ctx, notifierContextKey{}, notifier)
+}
+
+// notifierFromContext returns the notifier carried by ctx, if any
+func notifierFromContext(ctx context.Context) (Notifier, bool) {
+	notifier, ok := ctx.Value(notifierContextKey{}).(Notifier)
+	return notifier, ok
+}
+
+// ProgressReporter sends notifications/progress for a request that carried a
+// progress token. Reporters for requests without a token discard every update.
+type ProgressReporter struct {
+	mu       sync.Mutex
+	notifier Notifier
+	token    json.RawMessage
+	messages bool
+	reported bool
+	last     float64
+}
+
+// progressContextKey is the context key under which the request's progress reporter is stored
+type progressContextKey struct{}
+
+// ProgressFromContext returns the progress reporter for the current request.
+// It never returns nil, so handlers can report progress unconditionally.
+func ProgressFromContext(ctx context.Context) *ProgressReporter {
+	if reporter, ok := ctx.Value(progressContextKey{}).(*ProgressReporter); ok {
+		return reporter
+	}
+	return &ProgressReporter{}
+}
+
+// contextWithProgress attaches a progress reporter for the given token, sending
+// through the notifier the transport put in ctx
+func contextWithProgress(ctx context.Context, token json.RawMessage) context.Context {
+	notifier, ok := notifierFromContext(ctx)
+	if !ok {
+		return ctx
+	}
+
+	return context.WithValue(ctx, progressContextKey{}, &ProgressReporter{
+		notifier: notifier,
+		token:    token,
+		messages: featuresFromContext(ctx).ProgressMessages,
+	})
+}
+
+// Enabled reports whether the client asked for progress on this request
+func (p *ProgressReporter) Enabled() bool {
+	return p.notifier != nil
+}
+
+// Report sends a progress update. total may be 0 when unknown, and message is
+// dropped for clients whose protocol version predates progress messages.
+// Updates that do not increase progress are discarded, as the protocol requires.
+func (p *ProgressReporter) Report(progress, total float64, message string) {
+	if !p.Enabled() {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.reported && progress <= p.last {
+		return
+	}
+	p.reported = true
+	p.last = progress
+
+	params := ProgressParams{
+		ProgressToken: p.token,
+		Progress:      progress,
+		Total:         total,
+	}
+	if p.messages {
+		params.Message = message
+	}
+
+	if err := p.notifier.Notify("notifications/progress", params); err != nil {
+		logger.Warn("Failed to send progress notification", "error", err)
+	}
+}
+
+// Tracker returns a step tracker whose updates are reported as progress, so
+// MCP handlers describe their work with the same step model as the CLI
+func (p *ProgressReporter) Tracker(title string) *ui.StepTracker {
+	tracker := ui.NewStepTracker(title)
+	tracker.AttachRefresh(func() {
+		completed, total := tracker.Progress()
+
+		message := title
+		if step, ok := tracker.Current(); ok {
+			message = step.Label
+			if step.Detail != "" {
+				message += ": " + step.Detail
+			}
+		}
+
+		p.Report(completed, float64(total), message)
+	})
+	return tracker
+}
@@ -0,0 +1,216 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordingNotifier collects the notifications sent through it
+type recordingNotifier struct {
+	mu     sync.Mutex
+	params []ProgressParams
+}
+
+// Notify records progress notifications
+func (n *recordingNotifier) Notify(method string, params interface{}) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if p, ok := params.(ProgressParams); ok && method == "notifications/progress" {
+		n.params = append(n.params, p)
+	}
+	return nil
+}
+
+// registerStepsTool registers a tool that reports progress through a step tracker
+func registerStepsTool(h *Handler) {
+	h.RegisterTool(Tool{
+		Name:        "steps",
+		InputSchema: map[string]interface{}{"type": "object"},
+		Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			tracker := ProgressFromContext(ctx).Tracker("Creating feature")
+			tracker.Add("branch", "Create branch")
+			tracker.Add("spec", "Write spec")
+			tracker.Start("branch")
+			tracker.Complete("branch")
+			tracker.Start("spec", "spec.md")
+			tracker.Complete("spec")
+			return "done", nil
+		},
+	})
+}
+
+// TestProgressToken tests extraction of _meta.progressToken from request params
+func TestProgressToken(t *testing.T) {
+	tests := []struct {
+		params   string
+		expected string
+	}{
+		{`{"name":"x","_meta":{"progressToken":"abc"}}`, `"abc"`},
+		{`{"_meta":{"progressToken":42}}`, `42`},
+		{`{"_meta":{"progressToken":null}}`, ``},
+		{`{"_meta":{"progressToken":{}}}`, ``},
+		{`{"_meta":{}}`, ``},
+		{`{"name":"x"}`, ``},
+		{``, ``},
+	}
+
+	for _, tt := range tests {
+		if got := string(progressToken(json.RawMessage(tt.params))); got != tt.expected {
+			t.Errorf("progressToken(%s) = %q, want %q", tt.params, got, tt.expected)
+		}
+	}
+}
+
+// TestProgressReporter tests the reporter's filtering of updates
+func TestProgressReporter(t *testing.T) {
+	// Without a token, reporting is a no-op
+	ProgressFromContext(context.Background()).Report(1, 2, "ignored")
+	if ProgressFromContext(context.Background()).Enabled() {
+		t.Error("Expected reporter without a token to be disabled")
+	}
+
+	notifier := &recordingNotifier{}
+	session := NewSession()
+	session.begin("2025-03-26", InitializeParams{})
+	ctx := ContextWithNotifier(ContextWithSession(context.Background(), session), notifier)
+	reporter := ProgressFromContext(contextWithProgress(ctx, json.RawMessage(`"tok"`)))
+
+	reporter.Report(1, 3, "one")
+	reporter.Report(1, 3, "repeat")
+	reporter.Report(0.5, 3, "backwards")
+	reporter.Report(2, 3, "two")
+
+	if len(notifier.params) != 2 {
+		t.Fatalf("Expected 2 increasing updates, got %+v", notifier.params)
+	}
+	if string(notifier.params[0].ProgressToken) != `"tok"` || notifier.params[1].Message != "two" {
+		t.Errorf("Unexpected updates: %+v", notifier.params)
+	}
+
+	// Clients on 2024-11-05 do not receive messages
+	old := NewSession()
+	notifier = &recordingNotifier{}
+	ctx = ContextWithNotifier(ContextWithSession(context.Background(), old), notifier)
+	ProgressFromContext(contextWithProgress(ctx, json.RawMessage(`1`))).Report(1, 0, "hidden")
+	if len(notifier.params) != 1 || notifier.params[0].Message != "" {
+		t.Errorf("Expected message to be dropped for 2024-11-05, got %+v", notifier.params)
+	}
+}
+
+// TestProgressTracker tests progress reported through a step tracker
+func TestProgressTracker(t *testing.T) {
+	h := NewHandler()
+	registerStepsTool(h)
+	d := NewDispatcher(h)
+
+	notifier := &recordingNotifier{}
+	ctx := ContextWithNotifier(context.Background(), notifier)
+	resp := d.Handle(ctx, newTestRequest(t, 1, "tools/call", map[string]interface{}{
+		"name":  "steps",
+		"_meta": map[string]interface{}{"progressToken": "feature-1"},
+	}))
+	if resp.Error != nil {
+		t.Fatalf("tools/call failed: %v", resp.Error)
+	}
+
+	expected := []float64{0, 0.5, 1, 1.5, 2}
+	if len(notifier.params) != len(expected) {
+		t.Fatalf("Expected %d progress updates, got %+v", len(expected), notifier.params)
+	}
+	for i, progress := range expected {
+		if notifier.params[i].Progress != progress {
+			t.Errorf("Update %d: expected progress %v, got %v", i, progress, notifier.params[i].Progress)
+		}
+	}
+	if last := notifier.params[len(notifier.params)-1]; last.Total != 2 {
+		t.Errorf("Expected total 2, got %v", last.Total)
+	}
+	if notifier.params[3].Message != "Write spec: spec.md" {
+		t.Errorf("Expected running step as message, got %q", notifier.params[3].Message)
+	}
+
+	// Requests without a token produce no notifications
+	notifier.params = nil
+	d.Handle(ctx, newTestRequest(t, 2, "tools/call", CallToolParams{Name: "steps"}))
+	if len(notifier.params) != 0 {
+		t.Errorf("Expected no progress without a token, got %+v", notifier.params)
+	}
+}
+
+// TestStdioServerProgress tests progress notifications over stdio
+func TestStdioServerProgress(t *testing.T) {
+	server := NewStdioServer()
+	registerStepsTool(server.handler)
+
+	input := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"steps","_meta":{"progressToken":7}}}` + "\n"
+	var out strings.Builder
+	if err := server.serve(context.Background(), strings.NewReader(input), &out); err != nil {
+		t.Fatalf("serve failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 6 {
+		t.Fatalf("Expected 5 notifications and a reply, got %d lines:\n%s", len(lines), out.String())
+	}
+	for _, line := range lines[:5] {
+		if message := decodeReply(t, []byte(line)); message["method"] != "notifications/progress" {
+			t.Errorf("Expected progress notification, got %s", line)
+		}
+	}
+	if reply := decodeReply(t, []byte(lines[5])); reply["id"] != float64(1) {
+		t.Errorf("Expected the reply last, got %s", lines[5])
+	}
+}
+
+// TestStreamableHTTPProgress tests that progress upgrades a POST response to SSE
+func TestStreamableHTTPProgress(t *testing.T) {
+	server := NewServer(8080)
+	registerStepsTool(server.handler)
+	body := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"steps","_meta":{"progressToken":"p"}}}`
+
+	resp := postMCP(t, server, body, "application/json, text/event-stream")
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected SSE response, got %s", ct)
+	}
+
+	var events []map[string]interface{}
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if data, ok := strings.CutPrefix(scanner.Text(), "data: "); ok {
+			events = append(events, decodeReply(t, []byte(data)))
+		}
+	}
+	if len(events) != 6 {
+		t.Fatalf("Expected 5 notifications and a reply, got %d events", len(events))
+	}
+	if events[0]["method"] != "notifications/progress" || events[5]["id"] != float64(1) {
+		t.Errorf("Unexpected event order: %v", events)
+	}
+
+	// JSON-only clients get a plain reply and progress goes to the GET streams
+	id := initSession(t, server)
+	hs, _, _ := server.sessions.get(id)
+	messages := hs.streams.subscribe()
+	defer hs.streams.unsubscribe(messages)
+
+	resp = postMCPSession(t, server, id, body, "application/json")
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/json" {
+		t.Fatalf("Expected JSON reply, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	reply, _ := io.ReadAll(resp.Body)
+	if decodeReply(t, reply)["id"] != float64(1) {
+		t.Errorf("Unexpected reply: %s", reply)
+	}
+	if len(messages) != 5 {
+		t.Errorf("Expected 5 notifications on the GET stream, got %d", len(messages))
+	}
+}
@@ -1,6 +1,7 @@
 package mcp
 
 import (
+	"context"
 	"strings"
 	"testing"
 )
@@ -39,7 +40,7 @@ func TestConstitutionPromptAcceptsArguments(t *testing.T) {
 
 	// Test with user input
 	userInput := "Focus on security and testing principles"
-	result, err := prompt.Handler(map[string]interface{}{
+	result, err := prompt.Handler(context.Background(), map[string]interface{}{
 		"user_input": userInput,
 	})
 	if err != nil {
@@ -47,20 +48,16 @@ func TestConstitutionPromptAcceptsArguments(t *testing.T) {
 	}
 
 	// Extract message content
-	resultMap, ok := result.(map[string]interface{})
+	promptResult, ok := result.(*GetPromptResult)
 	if !ok {
-		t.Fatal("Result is not a map")
+		t.Fatal("Result is not a *GetPromptResult")
 	}
 
-	messages, ok := resultMap["messages"].([]map[string]interface{})
-	if !ok || len(messages) == 0 {
+	if len(promptResult.Messages) == 0 {
 		t.Fatal("No messages in result")
 	}
 
-	content, ok := messages[0]["content"].(string)
-	if !ok {
-		t.Fatal("Message content is not a string")
-	}
+	content := promptResult.Messages[0].Content.Text
 
 	// Verify user input appears in the prompt
 	if !strings.Contains(content, userInput) {
@@ -83,14 +80,12 @@ func TestConstitutionPromptWithoutArguments(t *testing.T) {
 	prompt := handler.prompts["constitution"]
 
 	// Test without user input
-	result, err := prompt.Handler(map[string]interface{}{})
+	result, err := prompt.Handler(context.Background(), map[string]interface{}{})
 	if err != nil {
 		t.Fatalf("Prompt handler failed: %v", err)
 	}
 
-	resultMap := result.(map[string]interface{})
-	messages := resultMap["messages"].([]map[string]interface{})
-	content := messages[0]["content"].(string)
+	content := result.(*GetPromptResult).Messages[0].Content.Text
 
 	// Should show the fallback message
 	if !strings.Contains(content, "No specific user input provided") {
@@ -137,7 +132,7 @@ func TestAllPromptsAcceptArguments(t *testing.T) {
 			}
 
 			// Test that it accepts input
-			result, err := prompt.Handler(map[string]interface{}{
+			result, err := prompt.Handler(context.Background(), map[string]interface{}{
 				"user_input": "test input",
 			})
 			if err != nil {
@@ -88,29 +88,23 @@ func TestPromptExecution(t *testing.T) {
 			}
 
 			// Verify result structure
-			resultMap, ok := result.(map[string]interface{})
+			promptResult, ok := result.(*GetPromptResult)
 			if !ok {
-				t.Error("Result is not a map")
+				t.Error("Result is not a *GetPromptResult")
 				return
 			}
 
-			messages, ok := resultMap["messages"].([]map[string]interface{})
-			if !ok {
-				t.Error("messages field is not a slice of maps")
-				return
-			}
-
-			if len(messages) == 0 {
+			if len(promptResult.Messages) == 0 {
 				t.Error("messages slice is empty")
 				return
 			}
 
 			// Verify message structure
-			firstMsg := messages[0]
-			if _, ok := firstMsg["role"]; !ok {
+			firstMsg := promptResult.Messages[0]
+			if firstMsg.Role == "" {
 				t.Error("message missing role field")
 			}
-			if content, ok := firstMsg["content"].(string); !ok || content == "" {
+			if firstMsg.Content.Type != "text" || firstMsg.Content.Text == "" {
 				t.Error("message missing or empty content field")
 			}
 		})
@@ -127,9 +121,7 @@ func TestPromptContentStructure(t *testing.T) {
 		t.Fatalf("Unexpected error: %v", err)
 	}
 
-	resultMap := result.(map[string]interface{})
-	messages := resultMap["messages"].([]map[string]interface{})
-	content := messages[0]["content"].(string)
+	content := result.(*GetPromptResult).Messages[0].Content.Text
 
 	// Verify content includes expected sections
 	expectedSections := []string{
@@ -0,0 +1,377 @@
+package mcp
+
+import "encoding/json"
+
+// ServerName is the name reported to clients in serverInfo
+const ServerName = "technocrat"
+
+// ServerVersion is the version reported to clients in serverInfo
+var ServerVersion = "1.0.0"
+
+// LatestProtocolVersion is the newest MCP protocol revision the server implements
+const LatestProtocolVersion = "2025-06-18"
+
+// supportedProtocolVersions lists every protocol revision the server can speak, newest first
+var supportedProtocolVersions = []string{
+	"2025-06-18",
+	"2025-03-26",
+	"2024-11-05",
+}
+
+// ProtocolFeatures lists the protocol features whose availability depends on the negotiated version
+type ProtocolFeatures struct {
+	Completions          bool // completion/complete and the completions capability (2025-03-26)
+	ProgressMessages     bool // Human-readable message on progress notifications (2025-03-26)
+	StructuredToolOutput bool // outputSchema and structuredContent on tools (2025-06-18)
+	Elicitation          bool // elicitation/create server requests (2025-06-18)
+	ResourceLinks        bool // resource_link content blocks in tool results (2025-06-18)
+}
+
+// protocolFeatures maps each supported protocol version to the features it enables
+var protocolFeatures = map[string]ProtocolFeatures{
+	"2024-11-05": {},
+	"2025-03-26": {
+		Completions:      true,
+		ProgressMessages: true,
+	},
+	"2025-06-18": {
+		Completions:          true,
+		ProgressMessages:     true,
+		StructuredToolOutput: true,
+		Elicitation:          true,
+		ResourceLinks:        true,
+	},
+}
+
+// IsSupportedProtocolVersion reports whether the server can speak the given protocol version
+func IsSupportedProtocolVersion(version string) bool {
+	_, ok := protocolFeatures[version]
+	return ok
+}
+
+// NegotiateProtocolVersion picks the protocol version to use for a client that
+// requested the given version: the requested one when supported, otherwise the latest
+func NegotiateProtocolVersion(requested string) string {
+	if IsSupportedProtocolVersion(requested) {
+		return requested
+	}
+	return LatestProtocolVersion
+}
+
+// Implementation identifies an MCP client or server
+type Implementation struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// ServerCapabilities describes the optional features the server supports
+type ServerCapabilities struct {
+	Tools       *ToolsCapability       `json:"tools,omitempty"`
+	Resources   *ResourcesCapability   `json:"resources,omitempty"`
+	Prompts     *PromptsCapability     `json:"prompts,omitempty"`
+	Completions *CompletionsCapability `json:"completions,omitempty"`
+	Logging     *LoggingCapability     `json:"logging,omitempty"`
+}
+
+// LoggingCapability announces support for logging/setLevel and notifications/message
+type LoggingCapability struct{}
+
+// CompletionsCapability announces support for completion/complete
+type CompletionsCapability struct{}
+
+// ToolsCapability describes tool support
+type ToolsCapability struct {
+	ListChanged bool `json:"listChanged,omitempty"`
+}
+
+// ResourcesCapability describes resource support
+type ResourcesCapability struct {
+	Subscribe   bool `json:"subscribe,omitempty"`
+	ListChanged bool `json:"listChanged,omitempty"`
+}
+
+// PromptsCapability describes prompt support
+type PromptsCapability struct {
+	ListChanged bool `json:"listChanged,omitempty"`
+}
+
+// InitializeParams holds the parameters of an initialize request
+type InitializeParams struct {
+	ProtocolVersion string                 `json:"protocolVersion"`
+	Capabilities    map[string]interface{} `json:"capabilities"`
+	ClientInfo      Implementation         `json:"clientInfo"`
+}
+
+// InitializeResult is the result of an initialize request
+type InitializeResult struct {
+	ProtocolVersion string             `json:"protocolVersion"`
+	Capabilities    ServerCapabilities `json:"capabilities"`
+	ServerInfo      Implementation     `json:"serverInfo"`
+}
+
+// CancelledParams holds the parameters of a notifications/cancelled notification
+type CancelledParams struct {
+	RequestID json.RawMessage `json:"requestId"`
+	Reason    string          `json:"reason,omitempty"`
+}
+
+// RequestMeta holds the _meta field that may accompany the params of any request
+type RequestMeta struct {
+	ProgressToken json.RawMessage `json:"progressToken,omitempty"`
+}
+
+// ProgressParams holds the parameters of a notifications/progress notification
+type ProgressParams struct {
+	ProgressToken json.RawMessage `json:"progressToken"`
+	Progress      float64         `json:"progress"`
+	Total         float64         `json:"total,omitempty"`
+	Message       string          `json:"message,omitempty"`
+}
+
+// ListToolsResult is the result of a tools/list request
+type ListToolsResult struct {
+	Tools []Tool `json:"tools"`
+}
+
+// CallToolParams holds the parameters of a tools/call request
+type CallToolParams struct {
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+}
+
+// CallToolResult is the result of a tools/call request
+type CallToolResult struct {
+	Content           []Content   `json:"content"`
+	StructuredContent interface{} `json:"structuredContent,omitempty"`
+	IsError           bool        `json:"isError,omitempty"`
+}
+
+// ListResourcesResult is the result of a resources/list request
+type ListResourcesResult struct {
+	Resources []Resource `json:"resources"`
+}
+
+// ReadResourceParams holds the parameters of a resources/read request
+type ReadResourceParams struct {
+	URI string `json:"uri"`
+}
+
+// ReadResourceResult is the result of a resources/read request
+type ReadResourceResult struct {
+	Contents []ResourceContents `json:"contents"`
+}
+
+// SubscribeParams holds the parameters of resources/subscribe and resources/unsubscribe requests
+type SubscribeParams struct {
+	URI string `json:"uri"`
+}
+
+// ResourceUpdatedParams holds the parameters of a notifications/resources/updated notification
+type ResourceUpdatedParams struct {
+	URI string `json:"uri"`
+}
+
+// Root is a directory or file the client makes available to the server
+type Root struct {
+	URI  string `json:"uri"`
+	Name string `json:"name,omitempty"`
+}
+
+// ListRootsResult is the result of a roots/list request sent to the client
+type ListRootsResult struct {
+	Roots []Root `json:"roots"`
+}
+
+// ElicitParams holds the parameters of an elicitation/create request sent to the client
+type ElicitParams struct {
+	Message         string                 `json:"message"`
+	RequestedSchema map[string]interface{} `json:"requestedSchema"`
+}
+
+// Elicitation actions a client responds with
+const (
+	ElicitActionAccept  = "accept"
+	ElicitActionDecline = "decline"
+	ElicitActionCancel  = "cancel"
+)
+
+// ElicitResult is the client's response to an elicitation/create request.
+// Content holds the user's answers when Action is accept.
+type ElicitResult struct {
+	Action  string                 `json:"action"`
+	Content map[string]interface{} `json:"content,omitempty"`
+}
+
+// SetLevelParams holds the parameters of a logging/setLevel request
+type SetLevelParams struct {
+	Level string `json:"level"`
+}
+
+// LogMessageParams holds the parameters of a notifications/message notification
+type LogMessageParams struct {
+	Level  string      `json:"level"`
+	Logger string      `json:"logger,omitempty"`
+	Data   interface{} `json:"data"`
+}
+
+// ResourceTemplate describes a parameterised resource URI. URITemplate is an
+// RFC 6570 template; reads of URIs that match it are routed to Reader with the
+// values of the template's variables.
+type ResourceTemplate struct {
+	URITemplate string         `json:"uriTemplate"`
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	MimeType    string         `json:"mimeType,omitempty"`
+	Reader      TemplateReader `json:"-"`
+
+	// Completers suggest values for the template's variables, keyed by variable name
+	Completers map[string]Completer `json:"-"`
+
+	template *URITemplate
+}
+
+// ListResourceTemplatesResult is the result of a resources/templates/list request
+type ListResourceTemplatesResult struct {
+	ResourceTemplates []ResourceTemplate `json:"resourceTemplates"`
+}
+
+// ResourceContents holds the contents of a single resource
+type ResourceContents struct {
+	URI      string `json:"uri"`
+	MimeType string `json:"mimeType,omitempty"`
+	Text     string `json:"text,omitempty"`
+	Blob     string `json:"blob,omitempty"`
+}
+
+// MarshalJSON encodes the contents, keeping the text of a text resource when
+// it is empty, as the schema requires either text or blob
+func (c ResourceContents) MarshalJSON() ([]byte, error) {
+	type contents ResourceContents
+	if c.Blob != "" {
+		return json.Marshal(contents(c))
+	}
+	return json.Marshal(struct {
+		contents
+		Text string `json:"text"`
+	}{contents(c), c.Text})
+}
+
+// ListPromptsResult is the result of a prompts/list request
+type ListPromptsResult struct {
+	Prompts []Prompt `json:"prompts"`
+}
+
+// GetPromptParams holds the parameters of a prompts/get request
+type GetPromptParams struct {
+	Name      string                 `json:"name"`
+	Arguments map[string]interface{} `json:"arguments,omitempty"`
+}
+
+// GetPromptResult is the result of a prompts/get request
+type GetPromptResult struct {
+	Description string          `json:"description,omitempty"`
+	Messages    []PromptMessage `json:"messages"`
+}
+
+// CompleteParams holds the parameters of a completion/complete request
+type CompleteParams struct {
+	Ref      CompletionReference `json:"ref"`
+	Argument CompletionArgument  `json:"argument"`
+	Context  *CompletionContext  `json:"context,omitempty"`
+}
+
+// Completion reference types
+const (
+	CompletionRefPrompt   = "ref/prompt"
+	CompletionRefResource = "ref/resource"
+)
+
+// CompletionReference identifies the prompt or resource template being completed
+type CompletionReference struct {
+	Type string `json:"type"`
+	Name string `json:"name,omitempty"` // Prompt name for ref/prompt
+	URI  string `json:"uri,omitempty"`  // URI template for ref/resource
+}
+
+// CompletionArgument is the argument being completed and its partial value
+type CompletionArgument struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// CompletionContext holds the values of arguments the user already filled in (2025-06-18)
+type CompletionContext struct {
+	Arguments map[string]string `json:"arguments,omitempty"`
+}
+
+// CompleteResult is the result of a completion/complete request
+type CompleteResult struct {
+	Completion Completion `json:"completion"`
+}
+
+// Completion lists suggested values, best match first
+type Completion struct {
+	Values  []string `json:"values"`
+	Total   int      `json:"total,omitempty"`
+	HasMore bool     `json:"hasMore,omitempty"`
+}
+
+// PromptMessage is a single message returned by a prompt
+type PromptMessage struct {
+	Role    string  `json:"role"`
+	Content Content `json:"content"`
+}
+
+// Content block types
+const (
+	ContentTypeText         = "text"
+	ContentTypeResource     = "resource"
+	ContentTypeResourceLink = "resource_link"
+)
+
+// Content is a content block within a prompt message or tool result.
+// Text blocks use Text, embedded resources use Resource, and resource
+// links use URI, Name, Description and MimeType.
+type Content struct {
+	Type        string            `json:"type"`
+	Text        string            `json:"text,omitempty"`
+	Resource    *ResourceContents `json:"resource,omitempty"`
+	URI         string            `json:"uri,omitempty"`
+	Name        string            `json:"name,omitempty"`
+	Description string            `json:"description,omitempty"`
+	MimeType    string            `json:"mimeType,omitempty"`
+}
+
+// MarshalJSON encodes the block, keeping the text of a text block when it is
+// empty, as the schema requires it
+func (c Content) MarshalJSON() ([]byte, error) {
+	type content Content
+	if c.Type != ContentTypeText {
+		return json.Marshal(content(c))
+	}
+	return json.Marshal(struct {
+		content
+		Text string `json:"text"`
+	}{content(c), c.Text})
+}
+
+// TextContent creates a text content block
+func TextContent(text string) Content {
+	return Content{Type: ContentTypeText, Text: text}
+}
+
+// EmbeddedResourceContent creates a content block embedding the resource contents
+func EmbeddedResourceContent(contents ResourceContents) Content {
+	return Content{Type: ContentTypeResource, Resource: &contents}
+}
+
+// ResourceLinkContent creates a content block pointing at a resource the client can read
+func ResourceLinkContent(resource Resource) Content {
+	return Content{
+		Type:        ContentTypeResourceLink,
+		URI:         resource.URI,
+		Name:        resource.Name,
+		Description: resource.Description,
+		MimeType:    resource.MimeType,
+	}
+}
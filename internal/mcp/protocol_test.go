@@ -0,0 +1,63 @@
+package mcp
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+// TestContentJSON tests that text blocks and text resources keep their text
+// when it is empty, and that other fields are still omitted when empty
+func TestContentJSON(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  Content
+		expected string
+	}{
+		{
+			name:     "Empty text",
+			content:  TextContent(""),
+			expected: `{"type":"text","text":""}`,
+		},
+		{
+			name:     "Text",
+			content:  TextContent("hello"),
+			expected: `{"type":"text","text":"hello"}`,
+		},
+		{
+			name:     "Empty text resource",
+			content:  EmbeddedResourceContent(ResourceContents{URI: "tchncrt://memory/constitution", MimeType: "text/markdown"}),
+			expected: `{"type":"resource","resource":{"uri":"tchncrt://memory/constitution","mimeType":"text/markdown","text":""}}`,
+		},
+		{
+			name:     "Blob resource",
+			content:  EmbeddedResourceContent(ResourceContents{URI: "tchncrt://logo", Blob: "aGk="}),
+			expected: `{"type":"resource","resource":{"uri":"tchncrt://logo","blob":"aGk="}}`,
+		},
+		{
+			name:     "Resource link",
+			content:  Content{Type: ContentTypeResourceLink, URI: "tchncrt://features/001-auth/spec", Name: "spec"},
+			expected: `{"type":"resource_link","uri":"tchncrt://features/001-auth/spec","name":"spec"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := json.Marshal(tt.content)
+			if err != nil {
+				t.Fatalf("Failed to encode: %v", err)
+			}
+			if string(data) != tt.expected {
+				t.Errorf("Expected %s, got %s", tt.expected, data)
+			}
+
+			var decoded Content
+			if err := json.Unmarshal(data, &decoded); err != nil {
+				t.Fatalf("Failed to decode: %v", err)
+			}
+			if !reflect.DeepEqual(decoded, tt.content) {
+				t.Errorf("Expected %+v after a round trip, got %+v", tt.content, decoded)
+			}
+		})
+	}
+}
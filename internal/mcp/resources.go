@@ -0,0 +1,326 @@
+package mcp
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"technocrat/internal/templates"
+)
+
+// ResourceScheme is the URI scheme of the resources served from the workspace
+const ResourceScheme = "tchncrt"
+
+// serverInfoURI identifies the resource describing the server itself
+const serverInfoURI = "info://server"
+
+// featureDocuments are the per-feature markdown files exposed as resources,
+// keyed by the document name used in their URIs
+var featureDocuments = []string{"spec", "plan", "tasks", "research", "data-model", "quickstart"}
+
+// Workspace exposes the files of a technocrat project as MCP resources:
+//
+//	tchncrt://features/<feature>/<document>         specs/<feature>/<document>.md
+//	tchncrt://features/<feature>/contracts/<file>   specs/<feature>/contracts/<file>
+//	tchncrt://memory/<document>                     memory/<document>.md
+//	tchncrt://templates/<name>                      embedded templates
+type Workspace struct {
+	Root        string
+	FeatureName string // Feature the client's roots or the working directory are inside, if any
+}
+
+// Resources enumerates the workspace files and embedded templates that exist
+// right now, sorted by URI
+func (w Workspace) Resources() []Resource {
+	var resources []Resource
+
+	if w.Root != "" {
+		resources = append(resources, w.featureResources()...)
+		resources = append(resources, w.memoryResources()...)
+	}
+
+	resources = append(resources, templateResources()...)
+
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].URI < resources[j].URI
+	})
+	return resources
+}
+
+// featureResources lists the documents and contracts of every feature under specs/
+func (w Workspace) featureResources() []Resource {
+	entries, err := os.ReadDir(filepath.Join(w.Root, "specs"))
+	if err != nil {
+		return nil
+	}
+
+	var resources []Resource
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		feature := entry.Name()
+		featureDir := filepath.Join(w.Root, "specs", feature)
+
+		for _, document := range featureDocuments {
+			info, err := os.Stat(filepath.Join(featureDir, document+".md"))
+			if err != nil || info.IsDir() {
+				continue
+			}
+			resources = append(resources, Resource{
+				URI:         featureURI(feature, document),
+				Name:        feature + "/" + document + ".md",
+				Description: fmt.Sprintf("%s document for feature %s", document, feature),
+				MimeType:    "text/markdown",
+				Size:        info.Size(),
+			})
+		}
+
+		contractsDir := filepath.Join(featureDir, "contracts")
+		filepath.WalkDir(contractsDir, func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			rel, err := filepath.Rel(contractsDir, p)
+			if err != nil {
+				return nil
+			}
+			rel = filepath.ToSlash(rel)
+
+			resource := Resource{
+				URI:         featureURI(feature, "contracts/"+rel),
+				Name:        feature + "/contracts/" + rel,
+				Description: fmt.Sprintf("Contract for feature %s", feature),
+				MimeType:    resourceMimeType(rel),
+			}
+			if info, err := d.Info(); err == nil {
+				resource.Size = info.Size()
+			}
+			resources = append(resources, resource)
+			return nil
+		})
+	}
+	return resources
+}
+
+// memoryResources lists the markdown documents in memory/
+func (w Workspace) memoryResources() []Resource {
+	entries, err := os.ReadDir(filepath.Join(w.Root, "memory"))
+	if err != nil {
+		return nil
+	}
+
+	var resources []Resource
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".md" {
+			continue
+		}
+		resource := Resource{
+			URI:         memoryURI(strings.TrimSuffix(entry.Name(), ".md")),
+			Name:        entry.Name(),
+			Description: "Project memory document " + entry.Name(),
+			MimeType:    "text/markdown",
+		}
+		if entry.Name() == "constitution.md" {
+			resource.Description = "Project constitution"
+		}
+		if info, err := entry.Info(); err == nil {
+			resource.Size = info.Size()
+		}
+		resources = append(resources, resource)
+	}
+	return resources
+}
+
+// templateResources lists the templates embedded in the binary
+func templateResources() []Resource {
+	names, err := templates.ListTemplates()
+	if err != nil {
+		return nil
+	}
+
+	resources := make([]Resource, 0, len(names))
+	for _, name := range names {
+		resources = append(resources, Resource{
+			URI:         ResourceScheme + "://templates/" + strings.TrimSuffix(name, path.Ext(name)),
+			Name:        name,
+			Description: "Embedded template " + name,
+			MimeType:    resourceMimeType(name),
+		})
+	}
+	return resources
+}
+
+// ReadDocument reads one of a feature's markdown documents, such as its spec or plan
+func (w Workspace) ReadDocument(feature, document string) (*ResourceContents, error) {
+	uri := featureURI(feature, document)
+	if !validSegment(feature) || !isFeatureDocument(document) {
+		return nil, fmt.Errorf("%w: %s", ErrResourceNotFound, uri)
+	}
+	return w.read(uri, filepath.Join("specs", feature, document+".md"))
+}
+
+// ReadContract reads a file below a feature's contracts/ directory
+func (w Workspace) ReadContract(feature, name string) (*ResourceContents, error) {
+	uri := featureURI(feature, "contracts/"+name)
+	if !validSegment(feature) || !validPath(name) {
+		return nil, fmt.Errorf("%w: %s", ErrResourceNotFound, uri)
+	}
+	return w.read(uri, filepath.Join("specs", feature, "contracts", filepath.FromSlash(name)))
+}
+
+// ReadMemory reads a markdown document from memory/, such as the constitution
+func (w Workspace) ReadMemory(document string) (*ResourceContents, error) {
+	uri := memoryURI(document)
+	if !validSegment(document) {
+		return nil, fmt.Errorf("%w: %s", ErrResourceNotFound, uri)
+	}
+	return w.read(uri, filepath.Join("memory", document+".md"))
+}
+
+// ReadTemplate reads an embedded template by its name without extension
+func (w Workspace) ReadTemplate(id string) (*ResourceContents, error) {
+	uri := ResourceScheme + "://templates/" + id
+	names, err := templates.ListTemplates()
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if strings.TrimSuffix(name, path.Ext(name)) == id {
+			data, err := templates.GetTemplate(name)
+			if err != nil {
+				return nil, err
+			}
+			return newResourceContents(uri, name, data), nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrResourceNotFound, uri)
+}
+
+// read returns the contents of a file below the workspace root
+func (w Workspace) read(uri, name string) (*ResourceContents, error) {
+	if w.Root == "" {
+		return nil, fmt.Errorf("%w: %s", ErrResourceNotFound, uri)
+	}
+	data, err := os.ReadFile(filepath.Join(w.Root, name))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrResourceNotFound, uri)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", uri, err)
+	}
+	return newResourceContents(uri, name, data), nil
+}
+
+// newResourceContents wraps file data, base64-encoding anything that is not text
+func newResourceContents(uri, name string, data []byte) *ResourceContents {
+	contents := &ResourceContents{URI: uri, MimeType: resourceMimeType(name)}
+	if utf8.Valid(data) {
+		contents.Text = string(data)
+	} else {
+		contents.Blob = base64.StdEncoding.EncodeToString(data)
+	}
+	return contents
+}
+
+// validSegment reports whether name is a single path segment below its parent
+func validSegment(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
+
+// validPath reports whether name is a relative slash-separated path that stays
+// below its parent
+func validPath(name string) bool {
+	for _, segment := range strings.Split(name, "/") {
+		if !validSegment(segment) {
+			return false
+		}
+	}
+	return true
+}
+
+// isFeatureDocument reports whether name is one of the per-feature documents
+func isFeatureDocument(name string) bool {
+	for _, document := range featureDocuments {
+		if document == name {
+			return true
+		}
+	}
+	return false
+}
+
+// featureURI builds the URI of a file belonging to a feature. The feature and
+// each segment of name are percent-encoded, as the resource templates expect.
+func featureURI(feature, name string) string {
+	segments := strings.Split(name, "/")
+	for i, segment := range segments {
+		segments[i] = encodeTemplateValue(segment, false)
+	}
+	return ResourceScheme + "://features/" + encodeTemplateValue(feature, false) + "/" + strings.Join(segments, "/")
+}
+
+// memoryURI builds the URI of a document in memory/, percent-encoding its name
+func memoryURI(document string) string {
+	return ResourceScheme + "://memory/" + encodeTemplateValue(document, false)
+}
+
+// resourceMimeType picks the MIME type for a file from its extension
+func resourceMimeType(name string) string {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".md", ".markdown":
+		return "text/markdown"
+	case ".json":
+		return "application/json"
+	case ".yaml", ".yml":
+		return "application/yaml"
+	case ".graphql", ".gql":
+		return "application/graphql"
+	case ".proto":
+		return "text/x-protobuf"
+	}
+	if mimeType := mime.TypeByExtension(path.Ext(name)); mimeType != "" {
+		return mimeType
+	}
+	return "text/plain"
+}
+
+// workspaceFor returns the workspace of the client behind ctx
+func (h *Handler) workspaceFor(ctx context.Context) Workspace {
+	session, _ := SessionFromContext(ctx)
+	return h.sessionWorkspace(session)
+}
+
+// sessionWorkspace returns the project found among the session's roots, or
+// the handler's own workspace when the client shared none
+func (h *Handler) sessionWorkspace(session *Session) Workspace {
+	if session != nil {
+		if workspace, ok := session.Workspace(); ok {
+			return Workspace{Root: workspace.Root, FeatureName: workspace.FeatureName}
+		}
+	}
+	return h.workspace
+}
+
+// serverInfo returns the contents of the info://server resource
+func (h *Handler) serverInfo(ctx context.Context) (*ResourceContents, error) {
+	data, err := json.MarshalIndent(map[string]interface{}{
+		"name":            ServerName,
+		"version":         ServerVersion,
+		"protocolVersion": LatestProtocolVersion,
+		"workspaceRoot":   h.workspaceFor(ctx).Root,
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return &ResourceContents{URI: serverInfoURI, MimeType: "application/json", Text: string(data)}, nil
+}
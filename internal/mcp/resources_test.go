@@ -0,0 +1,251 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// writeWorkspaceFile creates a file below root, creating parent directories
+func writeWorkspaceFile(t *testing.T, root, name, content string) {
+	t.Helper()
+	path := filepath.Join(root, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+}
+
+// newTestWorkspace creates a project with one feature
+func newTestWorkspace(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	writeWorkspaceFile(t, root, "memory/constitution.md", "# Constitution")
+	writeWorkspaceFile(t, root, "specs/001-auth/spec.md", "# Auth spec")
+	writeWorkspaceFile(t, root, "specs/001-auth/plan.md", "# Auth plan")
+	writeWorkspaceFile(t, root, "specs/001-auth/data-model.md", "# Data model")
+	writeWorkspaceFile(t, root, "specs/001-auth/notes.md", "not a feature document")
+	writeWorkspaceFile(t, root, "specs/001-auth/contracts/api.yaml", "openapi: 3.0.0")
+	writeWorkspaceFile(t, root, "specs/001-auth/contracts/events/login.json", `{"type":"object"}`)
+	return root
+}
+
+// TestWorkspaceResources tests enumerating workspace files as resources
+func TestWorkspaceResources(t *testing.T) {
+	workspace := Workspace{Root: newTestWorkspace(t)}
+
+	mimeTypes := make(map[string]string)
+	for _, resource := range workspace.Resources() {
+		mimeTypes[resource.URI] = resource.MimeType
+	}
+
+	expected := map[string]string{
+		"tchncrt://features/001-auth/spec":                        "text/markdown",
+		"tchncrt://features/001-auth/plan":                        "text/markdown",
+		"tchncrt://features/001-auth/data-model":                  "text/markdown",
+		"tchncrt://features/001-auth/contracts/api.yaml":          "application/yaml",
+		"tchncrt://features/001-auth/contracts/events/login.json": "application/json",
+		"tchncrt://memory/constitution":                           "text/markdown",
+		"tchncrt://templates/spec-template":                       "text/markdown",
+		"tchncrt://templates/vscode-settings":                     "application/json",
+	}
+	for uri, mimeType := range expected {
+		if got, ok := mimeTypes[uri]; !ok {
+			t.Errorf("Expected resource %s", uri)
+		} else if got != mimeType {
+			t.Errorf("Expected %s to have MIME type %s, got %s", uri, mimeType, got)
+		}
+	}
+
+	for _, missing := range []string{"tchncrt://features/001-auth/tasks", "tchncrt://features/001-auth/notes"} {
+		if _, ok := mimeTypes[missing]; ok {
+			t.Errorf("Unexpected resource %s", missing)
+		}
+	}
+}
+
+// TestReadWorkspaceResource tests reading workspace resources by URI through the template resolver
+func TestReadWorkspaceResource(t *testing.T) {
+	h := NewHandler()
+	h.SetWorkspaceRoot(newTestWorkspace(t))
+
+	tests := []struct {
+		name         string
+		uri          string
+		expectedText string
+		expectError  bool
+	}{
+		{"Feature document", "tchncrt://features/001-auth/spec", "# Auth spec", false},
+		{"Contract", "tchncrt://features/001-auth/contracts/events/login.json", `{"type":"object"}`, false},
+		{"Constitution", "tchncrt://memory/constitution", "# Constitution", false},
+		{"Missing document", "tchncrt://features/001-auth/tasks", "", true},
+		{"Unknown document", "tchncrt://features/001-auth/notes", "", true},
+		{"Encoded characters", "tchncrt://features/001%2Dauth/plan", "# Auth plan", false},
+		{"Path traversal", "tchncrt://features/../contracts/spec", "", true},
+		{"Encoded traversal", "tchncrt://features/..%2F..%2Fmemory/spec", "", true},
+		{"Contract traversal", "tchncrt://features/001-auth/contracts/../spec.md", "", true},
+		{"Other scheme", "file:///etc/passwd", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := h.ReadResource(tt.uri)
+			if tt.expectError {
+				if !errors.Is(err, ErrResourceNotFound) {
+					t.Errorf("Expected ErrResourceNotFound, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Read failed: %v", err)
+			}
+			if contents := result.Contents[0]; contents.Text != tt.expectedText {
+				t.Errorf("Unexpected contents: %#v", contents)
+			}
+		})
+	}
+
+	result, err := h.ReadResource("tchncrt://templates/spec-template")
+	if err != nil || result.Contents[0].Text == "" || result.Contents[0].MimeType != "text/markdown" {
+		t.Errorf("Expected embedded template contents, got %#v, %v", result, err)
+	}
+}
+
+// TestReadResourceThroughDispatcher tests resources/list and resources/read over JSON-RPC
+func TestReadResourceThroughDispatcher(t *testing.T) {
+	h := NewHandler()
+	h.SetWorkspaceRoot(newTestWorkspace(t))
+	d := NewDispatcher(h)
+
+	resp := d.Handle(context.Background(), newTestRequest(t, 1, "resources/list", nil))
+	found := false
+	for _, resource := range resp.Result.(*ListResourcesResult).Resources {
+		found = found || resource.URI == "tchncrt://features/001-auth/spec"
+	}
+	if !found {
+		t.Error("Expected the feature spec in resources/list")
+	}
+
+	resp = d.Handle(context.Background(), newTestRequest(t, 2, "resources/read", ReadResourceParams{URI: "tchncrt://features/001-auth/plan"}))
+	if resp.Error != nil {
+		t.Fatalf("Unexpected error: %v", resp.Error)
+	}
+	if text := resp.Result.(*ReadResourceResult).Contents[0].Text; text != "# Auth plan" {
+		t.Errorf("Expected plan contents, got %q", text)
+	}
+
+	resp = d.Handle(context.Background(), newTestRequest(t, 3, "resources/read", ReadResourceParams{URI: "info://server"}))
+	var info map[string]interface{}
+	if err := json.Unmarshal([]byte(resp.Result.(*ReadResourceResult).Contents[0].Text), &info); err != nil {
+		t.Fatalf("Expected JSON server info: %v", err)
+	}
+	if info["name"] != ServerName {
+		t.Errorf("Unexpected server info: %v", info)
+	}
+}
+
+// TestFeatureURIEscaping tests that names needing escapes are listed as
+// percent-encoded URIs that read back the right files
+func TestFeatureURIEscaping(t *testing.T) {
+	root := newTestWorkspace(t)
+	writeWorkspaceFile(t, root, "specs/004-dark mode#2/spec.md", "# Dark mode")
+	writeWorkspaceFile(t, root, "specs/004-dark mode#2/contracts/ui+theme/v1?.yaml", "openapi: 3.1.0")
+	h := NewHandler()
+	h.SetWorkspaceRoot(root)
+	d := NewDispatcher(h)
+
+	expected := map[string]string{
+		"tchncrt://features/004-dark%20mode%232/spec":                            "# Dark mode",
+		"tchncrt://features/004-dark%20mode%232/contracts/ui%2Btheme/v1%3F.yaml": "openapi: 3.1.0",
+	}
+	listed := make(map[string]bool)
+	for _, resource := range (Workspace{Root: root}).Resources() {
+		listed[resource.URI] = true
+	}
+	for uri, contents := range expected {
+		if !listed[uri] {
+			t.Errorf("Expected %s in the resources", uri)
+		}
+		resp := d.Handle(context.Background(), newTestRequest(t, 1, "resources/read", ReadResourceParams{URI: uri}))
+		if resp.Error != nil {
+			t.Errorf("Reading %s failed: %v", uri, resp.Error)
+			continue
+		}
+		if text := resp.Result.(*ReadResourceResult).Contents[0].Text; text != contents {
+			t.Errorf("Expected %q from %s, got %q", contents, uri, text)
+		}
+	}
+
+	// Completion suggests the decoded names
+	paths, _ := h.completeContracts(context.Background(), "", map[string]string{"feature": "004-dark mode#2"})
+	if len(paths) != 1 || paths[0] != "ui+theme/v1?.yaml" {
+		t.Errorf("Expected the decoded contract path, got %v", paths)
+	}
+}
+
+// TestResolveResourceTemplate tests routing URIs to registered templates
+func TestResolveResourceTemplate(t *testing.T) {
+	h := NewHandler()
+	reader := func(ctx context.Context, uri string, params map[string]string) (*ResourceContents, error) {
+		return &ResourceContents{URI: uri, Text: params["id"]}, nil
+	}
+	if err := h.RegisterResourceTemplate(ResourceTemplate{URITemplate: "tchncrt://features/{feature}/tasks/{id}", Name: "Task", Reader: reader}); err != nil {
+		t.Fatalf("RegisterResourceTemplate failed: %v", err)
+	}
+
+	tests := []struct {
+		uri              string
+		expectedTemplate string
+		expectedParams   map[string]string
+	}{
+		{"tchncrt://features/001-auth/spec", "tchncrt://features/{feature}/{artifact}", map[string]string{"feature": "001-auth", "artifact": "spec"}},
+		{"tchncrt://features/001-auth/contracts/api/v1.yaml", "tchncrt://features/{feature}/contracts/{+path}", map[string]string{"feature": "001-auth", "path": "api/v1.yaml"}},
+		{"tchncrt://features/001-auth/tasks/T003", "tchncrt://features/{feature}/tasks/{id}", map[string]string{"feature": "001-auth", "id": "T003"}},
+		{"tchncrt://memory/constitution", "tchncrt://memory/{document}", map[string]string{"document": "constitution"}},
+		{"tchncrt://features/001-auth", "", nil},
+		{"other://features/001-auth/spec", "", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			template, params, ok := h.ResolveResourceTemplate(tt.uri)
+			if tt.expectedTemplate == "" {
+				if ok {
+					t.Errorf("Expected no match, got %s", template.URITemplate)
+				}
+				return
+			}
+			if !ok || template.URITemplate != tt.expectedTemplate {
+				t.Fatalf("Expected template %s, got %s (matched %v)", tt.expectedTemplate, template.URITemplate, ok)
+			}
+			for name, value := range tt.expectedParams {
+				if params[name] != value {
+					t.Errorf("Expected %s=%q, got %q", name, value, params[name])
+				}
+			}
+		})
+	}
+
+	result, err := h.ReadResource("tchncrt://features/001-auth/tasks/T003")
+	if err != nil || result.Contents[0].Text != "T003" {
+		t.Errorf("Expected the read routed to the template reader, got %#v, %v", result, err)
+	}
+
+	if err := h.RegisterResourceTemplate(ResourceTemplate{URITemplate: "tchncrt://{unterminated", Reader: reader}); err == nil {
+		t.Error("Expected an error for an invalid template")
+	}
+	if err := h.RegisterResourceTemplate(ResourceTemplate{URITemplate: "tchncrt://x/{id}"}); err == nil {
+		t.Error("Expected an error for a template without a reader")
+	}
+
+	for _, template := range h.ListResourceTemplates() {
+		if template.Reader != nil {
+			t.Errorf("Expected %s to be listed without its reader", template.URITemplate)
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// rootsRequestTimeout bounds how long the server waits for the client's roots
+const rootsRequestTimeout = 30 * time.Second
+
+// windowsDrivePath matches the leading slash and drive of a file URI path on Windows
+var windowsDrivePath = regexp.MustCompile(`^/[A-Za-z]:`)
+
+// refreshRoots asks the client for its roots and re-resolves the session's
+// workspace from them. Until the first response arrives, and when no root is
+// a local directory, the session keeps using the working directory. An HTTP
+// client without an open GET stream cannot receive the request yet, so it is
+// sent again once the client opens one.
+func (d *Dispatcher) refreshRoots(session *Session) {
+	ctx, cancel := context.WithTimeout(context.Background(), rootsRequestTimeout)
+	defer cancel()
+
+	var result ListRootsResult
+	if err := session.request(ctx, "roots/list", nil, &result); err != nil {
+		if errors.Is(err, errNoStream) {
+			logger.Debug("Listing client roots once the client opens a stream")
+			return
+		}
+		logger.Warn("Failed to list client roots", "error", err)
+		return
+	}
+	session.setRoots(result.Roots, d.rootDirs())
+	if d.watchers != nil {
+		d.watchers.moved(session)
+	}
+
+	if workspace, ok := session.Workspace(); ok {
+		logger.Info("Using workspace from client roots", "root", workspace.Root, "feature", workspace.FeatureName)
+	} else {
+		logger.Info("No client root is a local directory, using the working directory", "roots", len(result.Roots))
+	}
+}
+
+// rootsListChanged handles the notifications/roots/list_changed notification
+func (d *Dispatcher) rootsListChanged(ctx context.Context, params json.RawMessage) {
+	if session, ok := SessionFromContext(ctx); ok {
+		go d.refreshRoots(session)
+	}
+}
+
+// workspaceFromRoots picks the project among the client's roots: the first
+// root inside a technocrat project (one with a memory/ directory), or else the
+// first root that is a local directory. A root below specs/<feature>/ also
+// selects that feature. Unless allowed is nil, projects outside the allowed
+// directories are skipped.
+func workspaceFromRoots(roots []Root, allowed []string) (WorkspaceContext, bool) {
+	start := time.Now()
+	defer func() { metrics.workspaceDetect.observe(time.Since(start), "roots") }()
+
+	var fallback *WorkspaceContext
+	for _, root := range roots {
+		dir, ok := rootDirectory(root.URI)
+		if !ok {
+			continue
+		}
+
+		workspace := detectWorkspaceContext(dir)
+		if allowed != nil && !insideAny(workspace.Root, allowed) {
+			logger.Warn("Ignoring client root outside the allowed directories", "root", root.URI)
+			continue
+		}
+		if info, err := os.Stat(filepath.Join(workspace.Root, "memory")); err == nil && info.IsDir() {
+			return workspace, true
+		}
+		if fallback == nil {
+			fallback = &workspace
+		}
+	}
+
+	if fallback == nil {
+		return WorkspaceContext{}, false
+	}
+	return *fallback, true
+}
+
+// rootDirectory returns the local directory named by a file:// root URI
+func rootDirectory(uri string) (string, bool) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" || (u.Host != "" && u.Host != "localhost") {
+		return "", false
+	}
+
+	path := u.Path
+	if windowsDrivePath.MatchString(path) {
+		path = path[1:]
+	}
+	dir := filepath.Clean(filepath.FromSlash(path))
+
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		return "", false
+	}
+	return dir, true
+}
+
+// insideAny reports whether dir is one of parents or below one of them, after
+// resolving symbolic links
+func insideAny(dir string, parents []string) bool {
+	dir = resolvePath(dir)
+	for _, parent := range parents {
+		rel, err := filepath.Rel(resolvePath(parent), dir)
+		if err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolvePath returns path with symbolic links resolved, or cleaned when it
+// cannot be resolved
+func resolvePath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return filepath.Clean(path)
+}
@@ -0,0 +1,359 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fileURI returns the file:// URI of a local path
+func fileURI(path string) string {
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+// recordingSender records the messages sent through it
+type recordingSender struct {
+	sent chan []byte
+}
+
+// newRecordingSender creates a sender that buffers a few messages
+func newRecordingSender() *recordingSender {
+	return &recordingSender{sent: make(chan []byte, 8)}
+}
+
+// send records a message
+func (r *recordingSender) send(data []byte) error {
+	r.sent <- data
+	return nil
+}
+
+// Notify records a notification
+func (r *recordingSender) Notify(method string, params interface{}) error {
+	data, err := encodeNotification(method, params)
+	if err != nil {
+		return err
+	}
+	return r.send(data)
+}
+
+// TestWorkspaceFromRoots tests picking the project among the client's roots
+func TestWorkspaceFromRoots(t *testing.T) {
+	project := newTestWorkspace(t)
+	plain := t.TempDir()
+
+	tests := []struct {
+		name            string
+		roots           []Root
+		allowed         []string
+		expectFound     bool
+		expectedRoot    string
+		expectedFeature string
+	}{
+		{
+			name:  "No roots",
+			roots: nil,
+		},
+		{
+			name:  "Only remote roots",
+			roots: []Root{{URI: "https://example.com/repo"}, {URI: "file://server/share"}},
+		},
+		{
+			name:         "Project preferred over an earlier plain directory",
+			roots:        []Root{{URI: fileURI(plain)}, {URI: fileURI(project)}},
+			expectFound:  true,
+			expectedRoot: project,
+		},
+		{
+			name:         "Plain directory when no root is a project",
+			roots:        []Root{{URI: "file:///does/not/exist"}, {URI: fileURI(plain)}},
+			expectFound:  true,
+			expectedRoot: plain,
+		},
+		{
+			name:            "Root inside a feature",
+			roots:           []Root{{URI: fileURI(filepath.Join(project, "specs", "001-auth"))}},
+			expectFound:     true,
+			expectedRoot:    project,
+			expectedFeature: "001-auth",
+		},
+		{
+			name:         "Project outside the allowed directories",
+			roots:        []Root{{URI: fileURI(project)}, {URI: fileURI(plain)}},
+			allowed:      []string{plain},
+			expectFound:  true,
+			expectedRoot: plain,
+		},
+		{
+			name:            "Root inside an allowed project",
+			roots:           []Root{{URI: fileURI(filepath.Join(project, "specs", "001-auth"))}},
+			allowed:         []string{project},
+			expectFound:     true,
+			expectedRoot:    project,
+			expectedFeature: "001-auth",
+		},
+		{
+			name:    "No root allowed",
+			roots:   []Root{{URI: "file:///etc"}, {URI: fileURI(plain)}},
+			allowed: []string{project},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workspace, found := workspaceFromRoots(tt.roots, tt.allowed)
+			if found != tt.expectFound {
+				t.Fatalf("Expected found=%v, got %v (%+v)", tt.expectFound, found, workspace)
+			}
+			if workspace.Root != tt.expectedRoot || workspace.FeatureName != tt.expectedFeature {
+				t.Errorf("Expected root %q and feature %q, got %+v", tt.expectedRoot, tt.expectedFeature, workspace)
+			}
+		})
+	}
+}
+
+// TestWorkspaceFromContext tests that requests see the workspace of their client's roots
+func TestWorkspaceFromContext(t *testing.T) {
+	project := newTestWorkspace(t)
+	session := NewSession()
+	ctx := ContextWithSession(context.Background(), session)
+
+	if WorkspaceFromContext(ctx).Root != DetectWorkspaceContext().Root {
+		t.Error("Expected the working directory's workspace before roots are known")
+	}
+
+	session.setRoots([]Root{{URI: fileURI(project), Name: "project"}}, nil)
+	if root := WorkspaceFromContext(ctx).Root; root != project {
+		t.Errorf("Expected %s, got %s", project, root)
+	}
+
+	h := NewHandler()
+	result, err := h.ReadResourceContext(ctx, "tchncrt://memory/constitution")
+	if err != nil {
+		t.Fatalf("Failed to read from the roots workspace: %v", err)
+	}
+	if result.Contents[0].Text != "# Constitution" {
+		t.Errorf("Unexpected contents: %q", result.Contents[0].Text)
+	}
+}
+
+// TestSessionRequest tests sending a request to the client and correlating its response
+func TestSessionRequest(t *testing.T) {
+	session := NewSession()
+	sender := newRecordingSender()
+	session.setNotifier(sender)
+
+	respond := func(response string) {
+		var req Request
+		if err := json.Unmarshal(<-sender.sent, &req); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+			return
+		}
+		msg, rpcErr := parseMessage([]byte(strings.ReplaceAll(response, "ID", string(req.ID))))
+		if rpcErr != nil {
+			t.Errorf("Invalid response: %v", rpcErr)
+			return
+		}
+		if !session.deliver(msg) {
+			t.Errorf("No request waiting for id %s", req.ID)
+		}
+	}
+
+	go respond(`{"jsonrpc":"2.0","id":ID,"result":{"roots":[{"uri":"file:///tmp"}]}}`)
+	var result ListRootsResult
+	if err := session.request(context.Background(), "roots/list", nil, &result); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(result.Roots) != 1 || result.Roots[0].URI != "file:///tmp" {
+		t.Errorf("Unexpected result: %+v", result)
+	}
+
+	go respond(`{"jsonrpc":"2.0","id":ID,"error":{"code":-32601,"message":"Method not found"}}`)
+	var rpcErr *Error
+	if err := session.request(context.Background(), "roots/list", nil, nil); !errors.As(err, &rpcErr) || rpcErr.Code != CodeMethodNotFound {
+		t.Errorf("Expected the client's error, got %v", err)
+	}
+
+	// A request the client never answers is cancelled with ctx
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := session.request(ctx, "roots/list", nil, nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected the deadline error, got %v", err)
+	}
+	<-sender.sent // The request itself
+	if cancelled := decodeReply(t, <-sender.sent); cancelled["method"] != "notifications/cancelled" {
+		t.Errorf("Expected a cancellation, got %v", cancelled)
+	}
+
+	if err := NewSession().request(context.Background(), "roots/list", nil, nil); !errors.Is(err, errCannotRequest) {
+		t.Errorf("Expected a request without a transport to fail, got %v", err)
+	}
+}
+
+// TestStdioServerRoots tests that stdio clients with roots get their workspace served
+func TestStdioServerRoots(t *testing.T) {
+	project := newTestWorkspace(t)
+	server := NewStdioServer()
+	in, out, served := startStdioServer(context.Background(), t, server)
+
+	readLine := func(what string) map[string]interface{} {
+		t.Helper()
+		lines := make(chan string, 1)
+		go func() {
+			line, _ := out.ReadString('\n')
+			lines <- line
+		}()
+		return decodeReply(t, []byte(waitFor(t, lines, what)))
+	}
+
+	io.WriteString(in, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{"roots":{"listChanged":true}},"clientInfo":{"name":"test","version":"1"}}}`+"\n")
+	readLine("initialize reply")
+	io.WriteString(in, `{"jsonrpc":"2.0","method":"notifications/initialized"}`+"\n")
+
+	answerRoots := func(root string) {
+		t.Helper()
+		request := readLine("roots/list request")
+		if request["method"] != "roots/list" {
+			t.Fatalf("Expected roots/list, got %v", request)
+		}
+		id, _ := json.Marshal(request["id"])
+		io.WriteString(in, `{"jsonrpc":"2.0","id":`+string(id)+`,"result":{"roots":[{"uri":"`+fileURI(root)+`"}]}}`+"\n")
+	}
+
+	// The workspace is re-resolved in the background, so poll the server info
+	workspaceRoot := func(id int) string {
+		t.Helper()
+		data, _ := json.Marshal(map[string]interface{}{
+			"jsonrpc": "2.0", "id": id, "method": "resources/read",
+			"params": map[string]string{"uri": serverInfoURI},
+		})
+		io.WriteString(in, string(data)+"\n")
+		result := readLine("resources/read reply")["result"].(map[string]interface{})
+		var info map[string]interface{}
+		json.Unmarshal([]byte(result["contents"].([]interface{})[0].(map[string]interface{})["text"].(string)), &info)
+		root, _ := info["workspaceRoot"].(string)
+		return root
+	}
+	waitForRoot := func(expected string) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for id := 100; workspaceRoot(id) != expected; id++ {
+			if time.Now().After(deadline) {
+				t.Fatalf("Workspace root never became %s", expected)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	answerRoots(project)
+	waitForRoot(project)
+
+	other := newTestWorkspace(t)
+	io.WriteString(in, `{"jsonrpc":"2.0","method":"notifications/roots/list_changed"}`+"\n")
+	answerRoots(other)
+	waitForRoot(other)
+
+	in.Close()
+	if err := waitFor(t, served, "serve to return"); err != nil {
+		t.Errorf("serve returned error: %v", err)
+	}
+}
+
+// answerHTTPRoots initializes an HTTP session whose client offers roots and
+// answers the server's roots/list request with the given root URIs
+func answerHTTPRoots(t *testing.T, server *Server, url string, uris ...string) *httpSession {
+	t.Helper()
+
+	initialized := sessionRequest(t, http.MethodPost, url, "", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{"roots":{}},"clientInfo":{"name":"test","version":"1"}}}`)
+	initialized.Body.Close()
+	id := initialized.Header.Get(sessionIDHeader)
+	hs, _, _ := server.sessions.get(id)
+
+	notified := sessionRequest(t, http.MethodPost, url, id, `{"jsonrpc":"2.0","method":"notifications/initialized"}`)
+	notified.Body.Close()
+
+	stream := sessionRequest(t, http.MethodGet, url, id, "")
+	defer stream.Body.Close()
+	events := make(chan string, 1)
+	go func() {
+		reader := bufio.NewReader(stream.Body)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			if data, ok := strings.CutPrefix(strings.TrimSpace(line), "data: "); ok {
+				events <- data
+				return
+			}
+		}
+	}()
+	request := decodeReply(t, []byte(waitFor(t, events, "roots/list on the stream")))
+	if request["method"] != "roots/list" {
+		t.Fatalf("Expected roots/list on the stream, got %v", request)
+	}
+
+	roots := make([]Root, len(uris))
+	for i, uri := range uris {
+		roots[i] = Root{URI: uri}
+	}
+	result, _ := json.Marshal(ListRootsResult{Roots: roots})
+	requestID, _ := json.Marshal(request["id"])
+	answer := sessionRequest(t, http.MethodPost, url, id, `{"jsonrpc":"2.0","id":`+string(requestID)+`,"result":`+string(result)+`}`)
+	answer.Body.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for hs.session.needsRoots() {
+		if time.Now().After(deadline) {
+			t.Fatal("Roots were never recorded")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return hs
+}
+
+// TestStreamableHTTPRoots tests that HTTP clients with roots get their
+// workspace served, although no GET stream is open at notifications/initialized
+func TestStreamableHTTPRoots(t *testing.T) {
+	project := newTestWorkspace(t)
+	cfg := DefaultConfig()
+	cfg.AllowedRoots = []string{project}
+	server := NewServerWithConfig(cfg)
+	ts := httptest.NewServer(server.routes())
+	defer ts.Close()
+
+	hs := answerHTTPRoots(t, server, ts.URL, fileURI(project))
+	if workspace, ok := hs.session.Workspace(); !ok || workspace.Root != project {
+		t.Errorf("Expected workspace root %s, got %+v", project, workspace)
+	}
+}
+
+// TestStreamableHTTPRootsOutsideAllowed tests that HTTP clients cannot point
+// the server at directories outside its workspace and allowed_roots
+func TestStreamableHTTPRootsOutsideAllowed(t *testing.T) {
+	startup := newTestWorkspace(t)
+	server := NewServer(8080)
+	server.handler.SetWorkspaceRoot(startup)
+	ts := httptest.NewServer(server.routes())
+	defer ts.Close()
+
+	hs := answerHTTPRoots(t, server, ts.URL, "file:///etc")
+	if workspace, ok := hs.session.Workspace(); ok {
+		t.Fatalf("Expected the root to be ignored, got workspace %+v", workspace)
+	}
+
+	resp := sessionRequest(t, http.MethodPost, ts.URL, hs.session.ID(), `{"jsonrpc":"2.0","id":2,"method":"resources/read","params":{"uri":"info://server"}}`)
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(body), `\"workspaceRoot\": \"`+startup) {
+		t.Errorf("Expected reads from the startup workspace %s, got %s", startup, body)
+	}
+}
@@ -0,0 +1,304 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// SchemaViolation describes a single place where a value does not conform to a JSON Schema
+type SchemaViolation struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+// SchemaError lists every violation found while validating a value against a JSON Schema
+type SchemaError struct {
+	Violations []SchemaViolation
+}
+
+// Error implements the error interface
+func (e *SchemaError) Error() string {
+	parts := make([]string, 0, len(e.Violations))
+	for _, v := range e.Violations {
+		parts = append(parts, fmt.Sprintf("%s: %s", v.Path, v.Message))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// ValidateSchema checks value against a JSON Schema. Both are normalised through
+// JSON first, so Go literals such as []string or int can be used on either side.
+// Violations are reported with paths rooted at root, e.g. "arguments.items[2].name".
+func ValidateSchema(schema map[string]interface{}, value interface{}, root string) error {
+	normalizedSchema, err := normalizeJSON(schema)
+	if err != nil {
+		return fmt.Errorf("invalid schema: %w", err)
+	}
+	normalizedValue, err := normalizeJSON(value)
+	if err != nil {
+		return fmt.Errorf("value is not JSON encodable: %w", err)
+	}
+
+	schemaMap, _ := normalizedSchema.(map[string]interface{})
+	v := &schemaValidator{}
+	v.validate(schemaMap, normalizedValue, root)
+	if len(v.violations) > 0 {
+		return &SchemaError{Violations: v.violations}
+	}
+	return nil
+}
+
+// normalizeJSON round-trips a Go value through encoding/json so it only
+// contains maps, slices, strings, float64, bool and nil
+func normalizeJSON(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var normalized interface{}
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// schemaValidator accumulates violations while walking a schema and value together
+type schemaValidator struct {
+	violations []SchemaViolation
+}
+
+// fail records a violation at path
+func (v *schemaValidator) fail(path string, format string, args ...interface{}) {
+	v.violations = append(v.violations, SchemaViolation{
+		Path:    path,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// validate checks value against schema, recursing into objects and arrays
+func (v *schemaValidator) validate(schema map[string]interface{}, value interface{}, path string) {
+	if schema == nil {
+		return
+	}
+
+	if types := schemaTypes(schema); len(types) > 0 && !matchesAnyType(value, types) {
+		v.fail(path, "expected %s, got %s", strings.Join(types, " or "), jsonTypeName(value))
+		return
+	}
+
+	if allowed, ok := schema["enum"].([]interface{}); ok && !containsJSON(allowed, value) {
+		v.fail(path, "must be one of %s", formatJSONList(allowed))
+	}
+	if expected, ok := schema["const"]; ok && !reflect.DeepEqual(expected, value) {
+		v.fail(path, "must be %s", formatJSON(expected))
+	}
+
+	switch val := value.(type) {
+	case map[string]interface{}:
+		v.validateObject(schema, val, path)
+	case []interface{}:
+		v.validateArray(schema, val, path)
+	case string:
+		v.validateString(schema, val, path)
+	case float64:
+		v.validateNumber(schema, val, path)
+	}
+}
+
+// validateObject checks required and declared properties of an object
+func (v *schemaValidator) validateObject(schema map[string]interface{}, value map[string]interface{}, path string) {
+	if required, ok := schema["required"].([]interface{}); ok {
+		for _, name := range required {
+			key, _ := name.(string)
+			if _, present := value[key]; !present {
+				v.fail(joinPath(path, key), "is required")
+			}
+		}
+	}
+
+	properties, _ := schema["properties"].(map[string]interface{})
+	keys := make([]string, 0, len(value))
+	for key := range value {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		if propSchema, ok := properties[key].(map[string]interface{}); ok {
+			v.validate(propSchema, value[key], joinPath(path, key))
+			continue
+		}
+
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				v.fail(joinPath(path, key), "is not an allowed property")
+			}
+		case map[string]interface{}:
+			v.validate(additional, value[key], joinPath(path, key))
+		}
+	}
+}
+
+// validateArray checks every element of an array against the items schema
+func (v *schemaValidator) validateArray(schema map[string]interface{}, value []interface{}, path string) {
+	if min, ok := schema["minItems"].(float64); ok && float64(len(value)) < min {
+		v.fail(path, "must have at least %s items", formatJSON(min))
+	}
+	if max, ok := schema["maxItems"].(float64); ok && float64(len(value)) > max {
+		v.fail(path, "must have at most %s items", formatJSON(max))
+	}
+	if unique, _ := schema["uniqueItems"].(bool); unique {
+		for i := 1; i < len(value); i++ {
+			if containsJSON(value[:i], value[i]) {
+				v.fail(fmt.Sprintf("%s[%d]", path, i), "duplicates an earlier item")
+			}
+		}
+	}
+
+	items, ok := schema["items"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	for i, item := range value {
+		v.validate(items, item, fmt.Sprintf("%s[%d]", path, i))
+	}
+}
+
+// validateString checks length and pattern constraints on a string
+func (v *schemaValidator) validateString(schema map[string]interface{}, value string, path string) {
+	length := float64(utf8.RuneCountInString(value))
+	if min, ok := schema["minLength"].(float64); ok && length < min {
+		v.fail(path, "must be at least %s characters long", formatJSON(min))
+	}
+	if max, ok := schema["maxLength"].(float64); ok && length > max {
+		v.fail(path, "must be at most %s characters long", formatJSON(max))
+	}
+
+	if pattern, ok := schema["pattern"].(string); ok {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			v.fail(path, "schema pattern %q is invalid: %v", pattern, err)
+		} else if !re.MatchString(value) {
+			v.fail(path, "must match pattern %q", pattern)
+		}
+	}
+}
+
+// validateNumber checks range constraints on a number
+func (v *schemaValidator) validateNumber(schema map[string]interface{}, value float64, path string) {
+	if min, ok := schema["minimum"].(float64); ok && value < min {
+		v.fail(path, "must be >= %s", formatJSON(min))
+	}
+	if max, ok := schema["maximum"].(float64); ok && value > max {
+		v.fail(path, "must be <= %s", formatJSON(max))
+	}
+	if min, ok := schema["exclusiveMinimum"].(float64); ok && value <= min {
+		v.fail(path, "must be > %s", formatJSON(min))
+	}
+	if max, ok := schema["exclusiveMaximum"].(float64); ok && value >= max {
+		v.fail(path, "must be < %s", formatJSON(max))
+	}
+}
+
+// schemaTypes returns the type or types a schema allows
+func schemaTypes(schema map[string]interface{}) []string {
+	switch t := schema["type"].(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		types := make([]string, 0, len(t))
+		for _, name := range t {
+			if s, ok := name.(string); ok {
+				types = append(types, s)
+			}
+		}
+		return types
+	}
+	return nil
+}
+
+// matchesAnyType reports whether value is an instance of one of the JSON Schema types
+func matchesAnyType(value interface{}, types []string) bool {
+	for _, t := range types {
+		if matchesType(value, t) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchesType reports whether value is an instance of the JSON Schema type
+func matchesType(value interface{}, schemaType string) bool {
+	switch schemaType {
+	case "integer":
+		n, ok := value.(float64)
+		return ok && n == math.Trunc(n)
+	case "number":
+		_, ok := value.(float64)
+		return ok
+	default:
+		return jsonTypeName(value) == schemaType
+	}
+}
+
+// jsonTypeName returns the JSON Schema type name of a normalised value
+func jsonTypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+// containsJSON reports whether values holds an element equal to value
+func containsJSON(values []interface{}, value interface{}) bool {
+	for _, candidate := range values {
+		if reflect.DeepEqual(candidate, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// formatJSON renders a normalised value as compact JSON for error messages
+func formatJSON(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+// formatJSONList renders values as a comma separated list of JSON literals
+func formatJSONList(values []interface{}) string {
+	parts := make([]string, 0, len(values))
+	for _, value := range values {
+		parts = append(parts, formatJSON(value))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// joinPath appends an object key to a violation path
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,162 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestValidateSchema tests the supported JSON Schema keywords and violation paths
+func TestValidateSchema(t *testing.T) {
+	schema := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"name":  map[string]interface{}{"type": "string", "pattern": "^[0-9]{3}-[a-z-]+$"},
+			"count": map[string]interface{}{"type": "integer", "minimum": 1, "maximum": 10},
+			"mode":  map[string]interface{}{"type": "string", "enum": []string{"draft", "final"}},
+			"tags": map[string]interface{}{
+				"type":        "array",
+				"items":       map[string]interface{}{"type": "string", "minLength": 2},
+				"uniqueItems": true,
+			},
+			"owner": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"email": map[string]interface{}{"type": "string"},
+				},
+				"required":             []string{"email"},
+				"additionalProperties": false,
+			},
+			"note": map[string]interface{}{"type": []string{"string", "null"}},
+		},
+		"required": []string{"name"},
+	}
+
+	tests := []struct {
+		name          string
+		value         map[string]interface{}
+		expectedPaths []string
+	}{
+		{
+			name:  "Valid",
+			value: map[string]interface{}{"name": "001-auth", "count": 3, "mode": "draft", "tags": []string{"go", "mcp"}, "note": nil},
+		},
+		{
+			name:          "Missing required",
+			value:         map[string]interface{}{},
+			expectedPaths: []string{"arguments.name"},
+		},
+		{
+			name:          "Wrong type",
+			value:         map[string]interface{}{"name": 42},
+			expectedPaths: []string{"arguments.name"},
+		},
+		{
+			name:          "Pattern mismatch",
+			value:         map[string]interface{}{"name": "auth"},
+			expectedPaths: []string{"arguments.name"},
+		},
+		{
+			name:          "Integer bounds and fraction",
+			value:         map[string]interface{}{"name": "001-auth", "count": 11},
+			expectedPaths: []string{"arguments.count"},
+		},
+		{
+			name:          "Not an integer",
+			value:         map[string]interface{}{"name": "001-auth", "count": 1.5},
+			expectedPaths: []string{"arguments.count"},
+		},
+		{
+			name:          "Enum",
+			value:         map[string]interface{}{"name": "001-auth", "mode": "other"},
+			expectedPaths: []string{"arguments.mode"},
+		},
+		{
+			name:          "Array items",
+			value:         map[string]interface{}{"name": "001-auth", "tags": []interface{}{"go", "x", 3, "go"}},
+			expectedPaths: []string{"arguments.tags[3]", "arguments.tags[1]", "arguments.tags[2]"},
+		},
+		{
+			name:          "Nested object",
+			value:         map[string]interface{}{"name": "001-auth", "owner": map[string]interface{}{"role": "admin"}},
+			expectedPaths: []string{"arguments.owner.email", "arguments.owner.role"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateSchema(schema, tt.value, "arguments")
+			if len(tt.expectedPaths) == 0 {
+				if err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+				return
+			}
+
+			var schemaErr *SchemaError
+			if !errors.As(err, &schemaErr) {
+				t.Fatalf("Expected *SchemaError, got %v", err)
+			}
+			if len(schemaErr.Violations) != len(tt.expectedPaths) {
+				t.Fatalf("Expected %d violations, got %v", len(tt.expectedPaths), schemaErr)
+			}
+			for i, path := range tt.expectedPaths {
+				if schemaErr.Violations[i].Path != path {
+					t.Errorf("Violation %d: expected path %s, got %s", i, path, schemaErr.Violations[i].Path)
+				}
+			}
+		})
+	}
+}
+
+// TestCallToolValidatesArguments tests that tools/call rejects arguments that violate the input schema
+func TestCallToolValidatesArguments(t *testing.T) {
+	h := NewHandler()
+	called := false
+	h.RegisterTool(Tool{
+		Name: "create_feature",
+		InputSchema: map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"name": map[string]interface{}{"type": "string"},
+			},
+			"required": []string{"name"},
+		},
+		Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			called = true
+			return args["name"].(string), nil
+		},
+	})
+
+	_, err := h.CallTool("create_feature", nil)
+	if !errors.Is(err, ErrInvalidArguments) {
+		t.Fatalf("Expected ErrInvalidArguments, got %v", err)
+	}
+	if called {
+		t.Error("Handler must not run when arguments are invalid")
+	}
+
+	d := NewDispatcher(h)
+	resp := d.Handle(context.Background(), newTestRequest(t, 1, "tools/call", CallToolParams{
+		Name:      "create_feature",
+		Arguments: map[string]interface{}{"name": 7},
+	}))
+	if resp.Error == nil || resp.Error.Code != CodeInvalidParams {
+		t.Fatalf("Expected invalid params error, got %#v", resp)
+	}
+	if resp.Error.Message != "Invalid arguments for tool create_feature: arguments.name: expected string, got number" {
+		t.Errorf("Unexpected error message: %s", resp.Error.Message)
+	}
+	data, ok := resp.Error.Data.(map[string]interface{})
+	if !ok || len(data["violations"].([]SchemaViolation)) != 1 {
+		t.Errorf("Expected violations in error data, got %#v", resp.Error.Data)
+	}
+
+	resp = d.Handle(context.Background(), newTestRequest(t, 2, "tools/call", CallToolParams{
+		Name:      "create_feature",
+		Arguments: map[string]interface{}{"name": "004-reporting"},
+	}))
+	if resp.Error != nil || !called {
+		t.Errorf("Expected valid call to reach the handler, got %#v", resp)
+	}
+}
@@ -1,66 +1,205 @@
 package mcp
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
-	"fmt"
-	"io"
-	"log"
+	"errors"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
-	"strings"
 	"syscall"
 	"time"
 )
 
 // Server represents the MCP server
 type Server struct {
-	port       int
-	httpServer *http.Server
-	handler    *Handler
+	port         int
+	httpServer   *http.Server
+	handler      *Handler
+	dispatcher   *Dispatcher
+	auth         Authenticator
+	legacyRoutes bool
+	origins      *originPolicy
+
+	// maxBodyBytes bounds request bodies, and limiter, when set, throttles each
+	// session or client
+	maxBodyBytes int64
+	limiter      *rateLimiter
+
+	// audit configures the log of tool calls and prompt renders opened by Start
+	audit AuditConfig
+
+	// network and address are where the server listens, or listenErr why the
+	// configured address is unusable; tlsFiles turn on HTTPS
+	network   string
+	address   string
+	listenErr error
+	tlsFiles  tlsFiles
+
+	// sessions holds the sessions issued at initialize
+	sessions *sessionStore
+
+	// watchInterval and watchDebounce configure the resource watchers of the
+	// workspaces sessions subscribe to
+	watchInterval time.Duration
+	watchDebounce time.Duration
+
+	// baseCtx is the parent of every request context and is cancelled on shutdown
+	baseCtx    context.Context
+	cancelBase context.CancelFunc
+
+	// closeAudit closes the audit log opened by Start once shutdown has drained
+	// running requests, after which stopped is closed
+	closeAudit func()
+	stopped    chan struct{}
 }
 
-// NewServer creates a new MCP server instance
+// NewServer creates a new MCP server instance with default settings on the given port
 func NewServer(port int) *Server {
-	handler := NewHandler()
+	cfg := DefaultConfig()
+	cfg.Port = port
+	return NewServerWithConfig(cfg)
+}
 
-	return &Server{
-		port:    port,
-		handler: handler,
+// NewServerWithConfig creates a new MCP server instance from the given settings
+func NewServerWithConfig(cfg Config) *Server {
+	handler := NewHandler()
+	dispatcher := NewDispatcher(handler)
+	dispatcher.SetRequestTimeout(cfg.RequestTimeout())
+	dispatcher.SetMaxConcurrentTools(cfg.MaxConcurrentTools)
+	// Any remote client may connect, so its roots must not reach the rest of the disk
+	dispatcher.SetAllowedRoots(cfg.AllowedRoots)
+
+	baseCtx, cancelBase := context.WithCancel(context.Background())
+
+	s := &Server{
+		port:          cfg.Port,
+		handler:       handler,
+		dispatcher:    dispatcher,
+		origins:       newOriginPolicy(cfg),
+		maxBodyBytes:  int64(cfg.MaxBodyBytes),
+		limiter:       newRateLimiter(cfg.RateLimit),
+		audit:         cfg.Audit,
+		sessions:      newSessionStore(cfg.SessionIdleTimeout(), cfg.MaxSessions),
+		watchInterval: cfg.WatchInterval(),
+		watchDebounce: cfg.WatchDebounce(),
+		baseCtx:       baseCtx,
+		cancelBase:    cancelBase,
+		closeAudit:    func() {},
+		stopped:       make(chan struct{}),
 	}
+	s.network, s.address, s.listenErr = cfg.ListenAddress()
+	s.tlsFiles = tlsFiles{cert: cfg.TLSCert, key: cfg.TLSKey, clientCA: cfg.TLSClientCA}
+	if s.watchInterval > 0 {
+		dispatcher.watchingResources = true
+		dispatcher.watchers = newSharedWatchers(baseCtx, handler, s.watchInterval, s.watchDebounce, dispatcher.publishSessionChanges)
+	}
+
+	return s
 }
 
-// Start starts the MCP server
-func (s *Server) Start() error {
+// EnableLegacyRoutes additionally serves the pre-Streamable-HTTP /mcp/v1/* REST routes
+func (s *Server) EnableLegacyRoutes() {
+	s.legacyRoutes = true
+}
+
+// routes builds the HTTP handler for all server endpoints
+func (s *Server) routes() http.Handler {
 	mux := http.NewServeMux()
 
-	// MCP protocol endpoints
-	mux.HandleFunc("/mcp/v1/initialize", s.handleInitialize)
-	mux.HandleFunc("/mcp/v1/tools/list", s.handleToolsList)
-	mux.HandleFunc("/mcp/v1/tools/call", s.handleToolsCall)
-	mux.HandleFunc("/mcp/v1/resources/list", s.handleResourcesList)
-	mux.HandleFunc("/mcp/v1/resources/read", s.handleResourcesRead)
-	mux.HandleFunc("/mcp/v1/prompts/list", s.handlePromptsList)
-	mux.HandleFunc("/mcp/v1/prompts/get", s.handlePromptsGet)
+	// Streamable HTTP transport endpoint
+	mux.HandleFunc(mcpEndpoint, s.requireAuth(s.limitRate(s.handleMCP)))
+
+	// Legacy REST-style endpoints, kept for older integrations
+	if s.legacyRoutes {
+		mux.HandleFunc("/mcp/v1/initialize", s.requireAuth(s.limitRate(s.handleInitialize)))
+		mux.HandleFunc("/mcp/v1/tools/list", s.requireAuth(s.limitRate(s.handleToolsList)))
+		mux.HandleFunc("/mcp/v1/tools/call", s.requireAuth(s.limitRate(s.handleToolsCall)))
+		mux.HandleFunc("/mcp/v1/resources/list", s.requireAuth(s.limitRate(s.handleResourcesList)))
+		mux.HandleFunc("/mcp/v1/resources/read", s.requireAuth(s.limitRate(s.handleResourcesRead)))
+		mux.HandleFunc("/mcp/v1/prompts/list", s.requireAuth(s.limitRate(s.handlePromptsList)))
+		mux.HandleFunc("/mcp/v1/prompts/get", s.requireAuth(s.limitRate(s.handlePromptsGet)))
+	}
+
+	// OAuth protected resource metadata, under the well-known path itself and
+	// with the resource's path appended
+	if _, ok := s.auth.(protectedResource); ok {
+		mux.HandleFunc(protectedResourcePath, s.handleProtectedResource)
+		mux.HandleFunc(protectedResourcePath+"/", s.handleProtectedResource)
+	}
 
 	// Health check endpoint
 	mux.HandleFunc("/health", s.handleHealth)
 
+	// Prometheus metrics, behind the same authentication as the MCP endpoint
+	mux.HandleFunc(metricsPath, s.requireAuth(s.handleMetrics))
+
+	return s.checkOrigin(mux)
+}
+
+// Start starts the MCP server
+func (s *Server) Start() error {
+	listener, err := s.listen()
+	if err != nil {
+		return err
+	}
+
+	closeAudit, err := s.dispatcher.startAudit(s.audit, TransportHTTP)
+	if err != nil {
+		listener.Close()
+		return err
+	}
+	s.closeAudit = closeAudit
+
 	s.httpServer = &http.Server{
-		Addr:         fmt.Sprintf(":%d", s.port),
-		Handler:      mux,
+		Handler:      s.routes(),
 		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
+		WriteTimeout: writeTimeout,
 		IdleTimeout:  60 * time.Second,
+		BaseContext:  s.baseContext,
+		ConnContext:  s.connContext,
 	}
 
 	// Graceful shutdown
 	go s.handleShutdown()
 
-	log.Printf("MCP Server listening on port %d", s.port)
-	return s.httpServer.ListenAndServe()
+	go s.expireSessions(s.baseCtx)
+
+	s.warnIfExposed()
+	logger.Info("MCP Server listening", "network", s.network, "addr", s.address,
+		"tls", s.tlsFiles.cert != "", "endpoint", mcpEndpoint)
+	err = s.httpServer.Serve(listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		// Requests still running when shutdown began are audited before the log closes
+		<-s.stopped
+		return err
+	}
+	closeAudit()
+	return err
+}
+
+// warnIfExposed warns when the server is reachable from other machines,
+// which only a deliberate --bind or --listen does
+func (s *Server) warnIfExposed() {
+	host := listenHost(s.network, s.address)
+	if isLoopbackHost(host) {
+		return
+	}
+
+	logger.Warn("Listening on a non-loopback address: other machines on the network can reach this server",
+		"addr", s.address)
+	if s.auth == nil {
+		logger.Warn("Authentication is off: anyone who can reach the server can call its tools. Configure auth or bind to 127.0.0.1")
+	}
+	if s.origins.anyHost {
+		logger.Warn("Host headers are not checked on a wildcard bind; list the server's names in allowed_hosts to guard against DNS rebinding")
+	}
+}
+
+// baseContext returns the parent context for requests accepted on any listener
+func (s *Server) baseContext(net.Listener) context.Context {
+	return s.baseCtx
 }
 
 // handleShutdown handles graceful shutdown on interrupt signals
@@ -69,19 +208,31 @@ func (s *Server) handleShutdown() {
 	signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
 	<-sigChan
-	log.Println("Shutdown signal received, gracefully stopping server...")
+	logger.Info("Shutdown signal received, gracefully stopping server")
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	if err := s.httpServer.Shutdown(ctx); err != nil {
-		log.Printf("Server shutdown error: %v", err)
+	if err := s.shutdown(ctx); err != nil {
+		logger.Error("Server shutdown error", "error", err)
 	}
 
-	log.Println("Server stopped")
+	logger.Info("Server stopped")
 	os.Exit(0)
 }
 
+// shutdown stops the server, waiting for running requests until ctx is done,
+// and then closes the audit log so their entries are still written
+func (s *Server) shutdown(ctx context.Context) error {
+	// Stop running requests so Shutdown does not wait on long tool calls
+	s.cancelBase()
+
+	err := s.httpServer.Shutdown(ctx)
+	s.closeAudit()
+	close(s.stopped)
+	return err
+}
+
 // handleInitialize handles the MCP initialize request
 func (s *Server) handleInitialize(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
@@ -89,20 +240,8 @@ func (s *Server) handleInitialize(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	response := map[string]interface{}{
-		"protocolVersion": "2024-11-05",
-		"serverInfo": map[string]string{
-			"name":    "technocrat",
-			"version": "1.0.0",
-		},
-		"capabilities": map[string]interface{}{
-			"tools":     map[string]bool{},
-			"resources": map[string]bool{},
-			"prompts":   map[string]bool{},
-		},
-	}
-
-	s.respondJSON(w, http.StatusOK, response)
+	// The legacy routes predate protocol negotiation and always speak the original revision
+	s.respondJSON(w, http.StatusOK, s.dispatcher.InitializeResult("2024-11-05"))
 }
 
 // handleToolsList handles listing available tools
@@ -125,12 +264,10 @@ func (s *Server) handleToolsCall(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+	body, ok := s.readBody(w, r)
+	if !ok {
 		return
 	}
-	defer r.Body.Close()
 
 	var request struct {
 		Name      string                 `json:"name"`
@@ -142,7 +279,16 @@ func (s *Server) handleToolsCall(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	release, ok := s.dispatcher.acquireToolSlot()
+	if !ok {
+		tooManyRequests(w, "Too many tool calls running", time.Second)
+		return
+	}
+	defer release()
+
+	start := time.Now()
 	result, err := s.handler.CallTool(request.Name, request.Arguments)
+	s.dispatcher.record(r.Context(), "tools/call", request.Name, request.Arguments, start, toolError(result, err))
 	if err != nil {
 		s.respondJSON(w, http.StatusBadRequest, map[string]interface{}{
 			"error": err.Error(),
@@ -150,6 +296,14 @@ func (s *Server) handleToolsCall(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The legacy routes keep reporting tool failures as errors
+	if result.IsError {
+		s.respondJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error": resultText(result),
+		})
+		return
+	}
+
 	s.respondJSON(w, http.StatusOK, map[string]interface{}{
 		"result": result,
 	})
@@ -175,12 +329,10 @@ func (s *Server) handleResourcesRead(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+	body, ok := s.readBody(w, r)
+	if !ok {
 		return
 	}
-	defer r.Body.Close()
 
 	var request struct {
 		URI string `json:"uri"`
@@ -191,7 +343,7 @@ func (s *Server) handleResourcesRead(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	content, err := s.handler.ReadResource(request.URI)
+	result, err := s.handler.ReadResource(request.URI)
 	if err != nil {
 		s.respondJSON(w, http.StatusNotFound, map[string]interface{}{
 			"error": err.Error(),
@@ -199,9 +351,7 @@ func (s *Server) handleResourcesRead(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.respondJSON(w, http.StatusOK, map[string]interface{}{
-		"contents": content,
-	})
+	s.respondJSON(w, http.StatusOK, result)
 }
 
 // handlePromptsList handles listing available prompts
@@ -224,12 +374,10 @@ func (s *Server) handlePromptsGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	body, err := io.ReadAll(r.Body)
-	if err != nil {
-		http.Error(w, "Failed to read request body", http.StatusBadRequest)
+	body, ok := s.readBody(w, r)
+	if !ok {
 		return
 	}
-	defer r.Body.Close()
 
 	var request struct {
 		Name      string                 `json:"name"`
@@ -241,7 +389,9 @@ func (s *Server) handlePromptsGet(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	start := time.Now()
 	prompt, err := s.handler.GetPrompt(request.Name, request.Arguments)
+	s.dispatcher.record(r.Context(), "prompts/get", request.Name, request.Arguments, start, err)
 	if err != nil {
 		s.respondJSON(w, http.StatusNotFound, map[string]interface{}{
 			"error": err.Error(),
@@ -265,235 +415,6 @@ func (s *Server) respondJSON(w http.ResponseWriter, status int, data interface{}
 	w.WriteHeader(status)
 
 	if err := json.NewEncoder(w).Encode(data); err != nil {
-		log.Printf("Error encoding JSON response: %v", err)
-	}
-}
-
-// StdioServer represents the MCP server using stdio transport
-type StdioServer struct {
-	handler *Handler
-}
-
-// NewStdioServer creates a new MCP server instance for stdio transport
-func NewStdioServer() *StdioServer {
-	handler := NewHandler()
-	return &StdioServer{
-		handler: handler,
-	}
-}
-
-// Start starts the MCP server in stdio mode
-func (s *StdioServer) Start() error {
-	scanner := bufio.NewScanner(os.Stdin)
-
-	// Handle interrupt signals for graceful shutdown
-	sigCh := make(chan os.Signal, 1)
-	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
-
-	go func() {
-		<-sigCh
-		log.Println("Shutting down MCP server...")
-		os.Exit(0)
-	}()
-
-	for scanner.Scan() {
-		line := strings.TrimSpace(scanner.Text())
-		if line == "" {
-			continue
-		}
-
-		var request map[string]interface{}
-		if err := json.Unmarshal([]byte(line), &request); err != nil {
-			log.Printf("Error parsing request: %v", err)
-			continue
-		}
-
-		response := s.handleStdioRequest(request)
-
-		responseJSON, err := json.Marshal(response)
-		if err != nil {
-			log.Printf("Error marshaling response: %v", err)
-			continue
-		}
-
-		fmt.Println(string(responseJSON))
-	}
-
-	if err := scanner.Err(); err != nil {
-		return fmt.Errorf("error reading from stdin: %w", err)
-	}
-
-	return nil
-}
-
-// handleStdioRequest handles a single MCP request via stdio
-func (s *StdioServer) handleStdioRequest(request map[string]interface{}) map[string]interface{} {
-	// Extract request ID for JSON-RPC 2.0 compliance
-	id := request["id"]
-
-	method, ok := request["method"].(string)
-	if !ok {
-		return map[string]interface{}{
-			"jsonrpc": "2.0",
-			"id":      id,
-			"error": map[string]interface{}{
-				"code":    -32600,
-				"message": "Invalid request: missing method",
-			},
-		}
-	}
-
-	switch method {
-	case "initialize":
-		return map[string]interface{}{
-			"jsonrpc": "2.0",
-			"id":      id,
-			"result": map[string]interface{}{
-				"protocolVersion": "2024-11-05",
-				"serverInfo": map[string]interface{}{
-					"name":    "technocrat",
-					"version": "0.5.1",
-				},
-				"capabilities": map[string]interface{}{
-					"tools":     map[string]interface{}{},
-					"resources": map[string]interface{}{},
-					"prompts":   map[string]interface{}{},
-				},
-			},
-		}
-	case "tools/list":
-		tools := s.handler.ListTools()
-		return map[string]interface{}{
-			"jsonrpc": "2.0",
-			"id":      id,
-			"result": map[string]interface{}{
-				"tools": tools,
-			},
-		}
-	case "tools/call":
-		params, ok := request["params"].(map[string]interface{})
-		if !ok {
-			return map[string]interface{}{
-				"jsonrpc": "2.0",
-				"id":      id,
-				"error": map[string]interface{}{
-					"code":    -32602,
-					"message": "Invalid params",
-				},
-			}
-		}
-
-		name, ok := params["name"].(string)
-		if !ok {
-			return map[string]interface{}{
-				"jsonrpc": "2.0",
-				"id":      id,
-				"error": map[string]interface{}{
-					"code":    -32602,
-					"message": "Missing tool name",
-				},
-			}
-		}
-
-		// Extract arguments if provided
-		var args map[string]interface{}
-		if argsInterface, exists := params["arguments"]; exists {
-			if argsMap, ok := argsInterface.(map[string]interface{}); ok {
-				args = argsMap
-			}
-		}
-
-		result, err := s.handler.CallTool(name, args)
-		if err != nil {
-			return map[string]interface{}{
-				"jsonrpc": "2.0",
-				"id":      id,
-				"error": map[string]interface{}{
-					"code":    -32603,
-					"message": fmt.Sprintf("Internal error: %v", err),
-				},
-			}
-		}
-		return map[string]interface{}{
-			"jsonrpc": "2.0",
-			"id":      id,
-			"result":  result,
-		}
-	case "resources/list":
-		resources := s.handler.ListResources()
-		return map[string]interface{}{
-			"jsonrpc": "2.0",
-			"id":      id,
-			"result": map[string]interface{}{
-				"resources": resources,
-			},
-		}
-	case "prompts/list":
-		prompts := s.handler.ListPrompts()
-		return map[string]interface{}{
-			"jsonrpc": "2.0",
-			"id":      id,
-			"result": map[string]interface{}{
-				"prompts": prompts,
-			},
-		}
-	case "prompts/get":
-		params, ok := request["params"].(map[string]interface{})
-		if !ok {
-			return map[string]interface{}{
-				"jsonrpc": "2.0",
-				"id":      id,
-				"error": map[string]interface{}{
-					"code":    -32602,
-					"message": "Invalid params",
-				},
-			}
-		}
-
-		name, ok := params["name"].(string)
-		if !ok {
-			return map[string]interface{}{
-				"jsonrpc": "2.0",
-				"id":      id,
-				"error": map[string]interface{}{
-					"code":    -32602,
-					"message": "Missing prompt name",
-				},
-			}
-		}
-
-		// Extract arguments if provided
-		var args map[string]interface{}
-		if argsInterface, exists := params["arguments"]; exists {
-			if argsMap, ok := argsInterface.(map[string]interface{}); ok {
-				args = argsMap
-			}
-		}
-
-		prompt, err := s.handler.GetPrompt(name, args)
-		if err != nil {
-			return map[string]interface{}{
-				"jsonrpc": "2.0",
-				"id":      id,
-				"error": map[string]interface{}{
-					"code":    -32603,
-					"message": fmt.Sprintf("Internal error: %v", err),
-				},
-			}
-		}
-		return map[string]interface{}{
-			"jsonrpc": "2.0",
-			"id":      id,
-			"result":  prompt,
-		}
-	default:
-		return map[string]interface{}{
-			"jsonrpc": "2.0",
-			"id":      id,
-			"error": map[string]interface{}{
-				"code":    -32601,
-				"message": fmt.Sprintf("Method not found: %s", method),
-			},
-		}
+		logger.Error("Failed to encode JSON response", "error", err)
 	}
 }
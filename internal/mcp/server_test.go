@@ -2,6 +2,7 @@ package mcp
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -626,6 +627,36 @@ func TestNewStdioServer(t *testing.T) {
 	if server.handler == nil {
 		t.Error("handler is nil")
 	}
+
+	if server.dispatcher == nil {
+		t.Error("dispatcher is nil")
+	}
+}
+
+// dispatchMap round-trips a map-shaped request through the dispatcher as JSON
+func dispatchMap(t testing.TB, d *Dispatcher, request map[string]interface{}) map[string]interface{} {
+	t.Helper()
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		t.Fatalf("Failed to marshal request: %v", err)
+	}
+
+	var req Request
+	if err := json.Unmarshal(data, &req); err != nil {
+		t.Fatalf("Failed to unmarshal request: %v", err)
+	}
+
+	respData, err := json.Marshal(d.Handle(context.Background(), &req))
+	if err != nil {
+		t.Fatalf("Failed to marshal response: %v", err)
+	}
+
+	var response map[string]interface{}
+	if err := json.Unmarshal(respData, &response); err != nil {
+		t.Fatalf("Failed to unmarshal response: %v", err)
+	}
+	return response
 }
 
 // TestHandleStdioRequest tests the stdio request handler
@@ -775,7 +806,7 @@ func TestHandleStdioRequest(t *testing.T) {
 				if errorData, ok := response["error"].(map[string]interface{}); !ok {
 					t.Error("Expected error in response")
 				} else {
-					if code, ok := errorData["code"].(int); !ok || code != -32600 {
+					if code, ok := errorData["code"].(float64); !ok || code != -32600 {
 						t.Errorf("Expected error code -32600, got %v", code)
 					}
 				}
@@ -794,7 +825,7 @@ func TestHandleStdioRequest(t *testing.T) {
 				if errorData, ok := response["error"].(map[string]interface{}); !ok {
 					t.Error("Expected error in response")
 				} else {
-					if code, ok := errorData["code"].(int); !ok || code != -32601 {
+					if code, ok := errorData["code"].(float64); !ok || code != -32601 {
 						t.Errorf("Expected error code -32601, got %v", code)
 					}
 				}
@@ -813,7 +844,7 @@ func TestHandleStdioRequest(t *testing.T) {
 				if errorData, ok := response["error"].(map[string]interface{}); !ok {
 					t.Error("Expected error in response")
 				} else {
-					if code, ok := errorData["code"].(int); !ok || code != -32602 {
+					if code, ok := errorData["code"].(float64); !ok || code != -32602 {
 						t.Errorf("Expected error code -32602, got %v", code)
 					}
 				}
@@ -833,7 +864,7 @@ func TestHandleStdioRequest(t *testing.T) {
 				if errorData, ok := response["error"].(map[string]interface{}); !ok {
 					t.Error("Expected error in response")
 				} else {
-					if code, ok := errorData["code"].(int); !ok || code != -32602 {
+					if code, ok := errorData["code"].(float64); !ok || code != -32602 {
 						t.Errorf("Expected error code -32602, got %v", code)
 					}
 				}
@@ -855,8 +886,8 @@ func TestHandleStdioRequest(t *testing.T) {
 				if errorData, ok := response["error"].(map[string]interface{}); !ok {
 					t.Error("Expected error in response")
 				} else {
-					if code, ok := errorData["code"].(int); !ok || code != -32603 {
-						t.Errorf("Expected error code -32603, got %v", code)
+					if code, ok := errorData["code"].(float64); !ok || code != -32602 {
+						t.Errorf("Expected error code -32602, got %v", code)
 					}
 				}
 			},
@@ -874,7 +905,7 @@ func TestHandleStdioRequest(t *testing.T) {
 				if errorData, ok := response["error"].(map[string]interface{}); !ok {
 					t.Error("Expected error in response")
 				} else {
-					if code, ok := errorData["code"].(int); !ok || code != -32602 {
+					if code, ok := errorData["code"].(float64); !ok || code != -32602 {
 						t.Errorf("Expected error code -32602, got %v", code)
 					}
 				}
@@ -894,7 +925,7 @@ func TestHandleStdioRequest(t *testing.T) {
 				if errorData, ok := response["error"].(map[string]interface{}); !ok {
 					t.Error("Expected error in response")
 				} else {
-					if code, ok := errorData["code"].(int); !ok || code != -32602 {
+					if code, ok := errorData["code"].(float64); !ok || code != -32602 {
 						t.Errorf("Expected error code -32602, got %v", code)
 					}
 				}
@@ -904,11 +935,7 @@ func TestHandleStdioRequest(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			response := server.handleStdioRequest(tt.request)
-
-			if response == nil {
-				t.Fatal("handleStdioRequest returned nil")
-			}
+			response := dispatchMap(t, server.dispatcher, tt.request)
 
 			// Check JSON-RPC 2.0 version
 			if jsonrpc, ok := response["jsonrpc"].(string); !ok || jsonrpc != "2.0" {
@@ -916,7 +943,7 @@ func TestHandleStdioRequest(t *testing.T) {
 			}
 
 			// Check ID is preserved
-			if response["id"] != tt.request["id"] {
+			if response["id"] != float64(tt.request["id"].(int)) {
 				t.Errorf("Expected id %v, got %v", tt.request["id"], response["id"])
 			}
 
@@ -1041,14 +1068,14 @@ func TestStdioServerJSONRPCCompliance(t *testing.T) {
 		"method":  "initialize",
 	}
 
-	response := server.handleStdioRequest(request)
+	response := dispatchMap(t, server.dispatcher, request)
 
 	// Check required fields
 	if jsonrpc, ok := response["jsonrpc"].(string); !ok || jsonrpc != "2.0" {
 		t.Error("Response missing or invalid 'jsonrpc' field")
 	}
 
-	if id := response["id"]; id != 42 {
+	if id := response["id"]; id != float64(42) {
 		t.Errorf("Response ID mismatch: expected 42, got %v", id)
 	}
 
@@ -1119,6 +1146,102 @@ func BenchmarkStdioHandleRequest(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_ = server.handleStdioRequest(request)
+		_ = dispatchMap(b, server.dispatcher, request)
+	}
+}
+
+// TestStdioServerServe tests the stdio read/write loop end to end
+func TestStdioServerServe(t *testing.T) {
+	server := NewStdioServer()
+
+	input := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+		``,
+		`{"jsonrpc":"2.0","id":2,"method":"ping"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"resources/read","params":{"uri":"info://server"}}`,
+	}, "\n")
+
+	var out bytes.Buffer
+	if err := server.serve(context.Background(), strings.NewReader(input), &out); err != nil {
+		t.Fatalf("serve failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 responses (notification must not be answered), got %d:\n%s", len(lines), out.String())
+	}
+
+	// Requests run concurrently, so replies may arrive in any order
+	seen := make(map[float64]bool)
+	for i, line := range lines {
+		var response map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &response); err != nil {
+			t.Fatalf("Failed to unmarshal response %d: %v", i, err)
+		}
+		id, _ := response["id"].(float64)
+		seen[id] = true
+		if _, ok := response["error"]; ok {
+			t.Errorf("Unexpected error in response %v: %v", id, response["error"])
+		}
+	}
+	for id := 1; id <= 3; id++ {
+		if !seen[float64(id)] {
+			t.Errorf("Missing response for id %d", id)
+		}
+	}
+}
+
+// TestStdioServerServeRecoversFromBadLines tests that malformed lines get errors without ending the session
+func TestStdioServerServeRecoversFromBadLines(t *testing.T) {
+	server := NewStdioServer()
+
+	input := strings.Join([]string{
+		`{"jsonrpc":"2.0","id":1,"method":`,
+		`[{"jsonrpc":"2.0","id":2,"method":"ping"},{"jsonrpc":"2.0","id":3,"method":"ping"}]`,
+		`{"jsonrpc":"2.0","id":4,"method":"ping"}`,
+	}, "\n")
+
+	var out bytes.Buffer
+	if err := server.serve(context.Background(), strings.NewReader(input), &out); err != nil {
+		t.Fatalf("serve failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("Expected 3 replies, got %d:\n%s", len(lines), out.String())
+	}
+
+	// Requests run concurrently, so replies may arrive in any order
+	var parseErrors, batches, singles int
+	for _, line := range lines {
+		var batch []map[string]interface{}
+		if json.Unmarshal([]byte(line), &batch) == nil {
+			if len(batch) != 2 {
+				t.Errorf("Expected batch reply with 2 responses, got %s", line)
+			}
+			batches++
+			continue
+		}
+
+		var response map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &response); err != nil {
+			t.Fatalf("Failed to unmarshal reply %s: %v", line, err)
+		}
+		if errorData, ok := response["error"].(map[string]interface{}); ok {
+			if code := errorData["code"]; code != float64(-32700) {
+				t.Errorf("Expected parse error -32700, got %v", code)
+			}
+			parseErrors++
+			continue
+		}
+		if response["id"] != float64(4) {
+			t.Errorf("Expected reply to id 4, got %s", line)
+		}
+		singles++
+	}
+
+	if parseErrors != 1 || batches != 1 || singles != 1 {
+		t.Errorf("Expected one parse error, one batch and one reply, got %d, %d, %d", parseErrors, batches, singles)
 	}
 }
@@ -0,0 +1,328 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+)
+
+// Session holds the protocol state negotiated with a single client
+type Session struct {
+	mu                 sync.RWMutex
+	id                 string
+	protocolVersion    string
+	clientInfo         Implementation
+	clientCapabilities map[string]interface{}
+	initialized        bool
+
+	// notifier reaches the client outside of any request, and subscriptions
+	// holds the URIs of the resources it asked to hear about
+	notifier      Notifier
+	subscriptions map[string]struct{}
+
+	// logLevel is the minimum level of log records forwarded to the client,
+	// nil until it sends logging/setLevel
+	logLevel *slog.Level
+
+	// pending holds the channels of server-initiated requests awaiting the
+	// client's response, keyed by request id
+	pending       map[string]chan *message
+	lastRequestID int64
+
+	// roots are the client's roots from its last roots/list response, and
+	// workspace the project found among them, if any. rootsListed is set once
+	// the client has answered roots/list.
+	roots       []Root
+	workspace   *WorkspaceContext
+	rootsListed bool
+}
+
+// NewSession creates a session that has not been initialized yet
+func NewSession() *Session {
+	return &Session{
+		subscriptions: make(map[string]struct{}),
+		pending:       make(map[string]chan *message),
+	}
+}
+
+// ID returns the ID the transport issued for the session, or "" when it issues none
+func (s *Session) ID() string {
+	return s.id
+}
+
+// ProtocolVersion returns the negotiated protocol version, or the oldest
+// supported version before initialize has completed
+func (s *Session) ProtocolVersion() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.protocolVersion == "" {
+		return supportedProtocolVersions[len(supportedProtocolVersions)-1]
+	}
+	return s.protocolVersion
+}
+
+// negotiated reports whether the session completed an initialize request
+func (s *Session) negotiated() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.protocolVersion != ""
+}
+
+// Features returns the version-dependent features enabled for the session
+func (s *Session) Features() ProtocolFeatures {
+	return protocolFeatures[s.ProtocolVersion()]
+}
+
+// ClientInfo returns the client implementation reported at initialize
+func (s *Session) ClientInfo() Implementation {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.clientInfo
+}
+
+// ClientSupports reports whether the client declared the named capability
+func (s *Session) ClientSupports(capability string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.clientCapabilities[capability]
+	return ok
+}
+
+// Initialized reports whether the client sent notifications/initialized
+func (s *Session) Initialized() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.initialized
+}
+
+// begin records the outcome of an initialize request
+func (s *Session) begin(version string, params InitializeParams) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.protocolVersion = version
+	s.clientInfo = params.ClientInfo
+	s.clientCapabilities = params.Capabilities
+}
+
+// markInitialized records that the client finished the initialization handshake
+func (s *Session) markInitialized() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.initialized = true
+}
+
+// setNotifier sets how notifications that belong to no request reach the client
+func (s *Session) setNotifier(notifier Notifier) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notifier = notifier
+}
+
+// notify sends a notification that belongs to no request. It is a no-op for
+// sessions whose transport cannot reach the client outside of a request.
+func (s *Session) notify(method string, params interface{}) error {
+	s.mu.RLock()
+	notifier := s.notifier
+	s.mu.RUnlock()
+
+	if notifier == nil {
+		return nil
+	}
+	return notifier.Notify(method, params)
+}
+
+// messageSender is implemented by transports that can send requests to the client
+type messageSender interface {
+	send(data []byte) error
+}
+
+// errCannotRequest is returned for requests over a transport that cannot send them
+var errCannotRequest = errors.New("transport cannot send requests to the client")
+
+// request sends a request to the client and decodes the result of its response
+// into result. It goes out on the transport of the current request when ctx
+// carries one, and on the session's own otherwise. When ctx is done before the
+// client responds, the client is told the request was cancelled.
+func (s *Session) request(ctx context.Context, method string, params, result interface{}) error {
+	sender, ok := s.sender(ctx)
+	if !ok {
+		return fmt.Errorf("%s: %w", method, errCannotRequest)
+	}
+
+	s.mu.Lock()
+	s.lastRequestID++
+	id := json.RawMessage(strconv.FormatInt(s.lastRequestID, 10))
+	responses := make(chan *message, 1)
+	s.pending[string(id)] = responses
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.pending, string(id))
+		s.mu.Unlock()
+	}()
+
+	data, err := encodeRequest(id, method, params)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", method, err)
+	}
+	if err := sender.send(data); err != nil {
+		return fmt.Errorf("failed to send %s request: %w", method, err)
+	}
+
+	select {
+	case msg := <-responses:
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if result == nil {
+			return nil
+		}
+		if err := json.Unmarshal(msg.Result, result); err != nil {
+			return fmt.Errorf("invalid %s result: %w", method, err)
+		}
+		return nil
+
+	case <-ctx.Done():
+		if data, err := encodeNotification("notifications/cancelled", CancelledParams{RequestID: id, Reason: ctx.Err().Error()}); err == nil {
+			sender.send(data)
+		}
+		return ctx.Err()
+	}
+}
+
+// sender picks the transport for a server-initiated request
+func (s *Session) sender(ctx context.Context) (messageSender, bool) {
+	if notifier, ok := notifierFromContext(ctx); ok {
+		if sender, ok := notifier.(messageSender); ok {
+			return sender, true
+		}
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	sender, ok := s.notifier.(messageSender)
+	return sender, ok
+}
+
+// deliver hands a client response to the request waiting for it and reports
+// whether there was one
+func (s *Session) deliver(msg *message) bool {
+	s.mu.Lock()
+	responses, ok := s.pending[string(msg.ID)]
+	delete(s.pending, string(msg.ID))
+	s.mu.Unlock()
+
+	if ok {
+		responses <- msg
+	}
+	return ok
+}
+
+// subscribe records the client's interest in updates to a resource
+func (s *Session) subscribe(uri string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscriptions[uri] = struct{}{}
+}
+
+// unsubscribe removes a resource subscription
+func (s *Session) unsubscribe(uri string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.subscriptions, uri)
+}
+
+// Subscribed reports whether the client subscribed to updates of a resource
+func (s *Session) Subscribed(uri string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.subscriptions[uri]
+	return ok
+}
+
+// setLogLevel starts forwarding log records at or above level to the client
+func (s *Session) setLogLevel(level slog.Level) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logLevel = &level
+}
+
+// wantsLog reports whether the client asked for log records at level
+func (s *Session) wantsLog(level slog.Level) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.logLevel != nil && level >= *s.logLevel
+}
+
+// Roots returns the roots the client shared through roots/list
+func (s *Session) Roots() []Root {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Root(nil), s.roots...)
+}
+
+// Workspace returns the project found among the client's roots, if any
+func (s *Session) Workspace() (WorkspaceContext, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.workspace == nil {
+		return WorkspaceContext{}, false
+	}
+	return *s.workspace, true
+}
+
+// setRoots records the client's roots and the workspace found among them,
+// inside allowed unless it is nil
+func (s *Session) setRoots(roots []Root, allowed []string) {
+	workspace, found := workspaceFromRoots(roots, allowed)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.roots = roots
+	s.rootsListed = true
+	s.workspace = nil
+	if found {
+		s.workspace = &workspace
+	}
+}
+
+// needsRoots reports whether the initialized client offers roots that have
+// not been listed yet
+func (s *Session) needsRoots() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, supported := s.clientCapabilities["roots"]
+	return supported && s.initialized && !s.rootsListed
+}
+
+// sessionContextKey is the context key under which the active session is stored
+type sessionContextKey struct{}
+
+// ContextWithSession returns a context carrying the given session
+func ContextWithSession(ctx context.Context, session *Session) context.Context {
+	return context.WithValue(ctx, sessionContextKey{}, session)
+}
+
+// SessionFromContext returns the session carried by ctx, if any
+func SessionFromContext(ctx context.Context) (*Session, bool) {
+	session, ok := ctx.Value(sessionContextKey{}).(*Session)
+	return session, ok
+}
+
+// featuresFromContext returns the protocol features of the session carried by
+// ctx, or those of the latest protocol version when there is no session
+func featuresFromContext(ctx context.Context) ProtocolFeatures {
+	if session, ok := SessionFromContext(ctx); ok {
+		return session.Features()
+	}
+	return protocolFeatures[LatestProtocolVersion]
+}
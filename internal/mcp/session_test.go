@@ -0,0 +1,136 @@
+package mcp
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestNegotiateProtocolVersion tests protocol version selection
+func TestNegotiateProtocolVersion(t *testing.T) {
+	tests := []struct {
+		requested string
+		expected  string
+	}{
+		{"2025-06-18", "2025-06-18"},
+		{"2025-03-26", "2025-03-26"},
+		{"2024-11-05", "2024-11-05"},
+		{"2023-01-01", LatestProtocolVersion},
+		{"", LatestProtocolVersion},
+	}
+
+	for _, tt := range tests {
+		if got := NegotiateProtocolVersion(tt.requested); got != tt.expected {
+			t.Errorf("NegotiateProtocolVersion(%q) = %q, want %q", tt.requested, got, tt.expected)
+		}
+	}
+}
+
+// TestSessionRecordsNegotiation tests that initialize stores the negotiated state on the session
+func TestSessionRecordsNegotiation(t *testing.T) {
+	d := NewDispatcher(NewHandler())
+	session := NewSession()
+	ctx := ContextWithSession(context.Background(), session)
+
+	if session.ProtocolVersion() != "2024-11-05" {
+		t.Errorf("Expected oldest version before initialize, got %s", session.ProtocolVersion())
+	}
+
+	resp := d.Handle(ctx, newTestRequest(t, 1, "initialize", InitializeParams{
+		ProtocolVersion: "2025-03-26",
+		Capabilities:    map[string]interface{}{"roots": map[string]interface{}{}},
+		ClientInfo:      Implementation{Name: "test-client", Version: "0.1"},
+	}))
+	if resp.Error != nil {
+		t.Fatalf("Initialize failed: %v", resp.Error)
+	}
+
+	result := resp.Result.(*InitializeResult)
+	if result.ProtocolVersion != "2025-03-26" {
+		t.Errorf("Expected negotiated version 2025-03-26, got %s", result.ProtocolVersion)
+	}
+	if session.ProtocolVersion() != "2025-03-26" {
+		t.Errorf("Session did not record version, got %s", session.ProtocolVersion())
+	}
+	if session.ClientInfo().Name != "test-client" {
+		t.Errorf("Session did not record client info, got %#v", session.ClientInfo())
+	}
+	if !session.ClientSupports("roots") || session.ClientSupports("sampling") {
+		t.Error("Session did not record client capabilities")
+	}
+
+	features := session.Features()
+	if !features.Completions || features.StructuredToolOutput || features.Elicitation {
+		t.Errorf("Unexpected features for 2025-03-26: %#v", features)
+	}
+
+	if session.Initialized() {
+		t.Error("Session should not be initialized before notifications/initialized")
+	}
+	d.Handle(ctx, &Request{JSONRPC: "2.0", Method: "notifications/initialized"})
+	if !session.Initialized() {
+		t.Error("Session should be initialized after notifications/initialized")
+	}
+}
+
+// TestSessionUnsupportedVersion tests the fallback for unknown client versions
+func TestSessionUnsupportedVersion(t *testing.T) {
+	d := NewDispatcher(NewHandler())
+	session := NewSession()
+	ctx := ContextWithSession(context.Background(), session)
+
+	resp := d.Handle(ctx, newTestRequest(t, 1, "initialize", InitializeParams{ProtocolVersion: "1999-01-01"}))
+	if resp.Error != nil {
+		t.Fatalf("Initialize failed: %v", resp.Error)
+	}
+	if v := resp.Result.(*InitializeResult).ProtocolVersion; v != LatestProtocolVersion {
+		t.Errorf("Expected fallback to %s, got %s", LatestProtocolVersion, v)
+	}
+	if !session.Features().Elicitation {
+		t.Error("Expected latest features after fallback")
+	}
+}
+
+// TestStreamableHTTPProtocolVersionHeader tests that requests after initialize
+// must carry the session's protocol version, a missing header meaning 2025-03-26
+func TestStreamableHTTPProtocolVersionHeader(t *testing.T) {
+	server := NewServer(8080)
+	latest := initSession(t, server)
+
+	resp := postMCPSession(t, server, "", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`, "application/json")
+	resp.Body.Close()
+	older := resp.Header.Get(sessionIDHeader)
+
+	tests := []struct {
+		name           string
+		session        string
+		header         string
+		expectedStatus int
+	}{
+		{"Negotiated version", latest, "2025-06-18", http.StatusOK},
+		{"Other supported version", latest, "2025-03-26", http.StatusBadRequest},
+		{"Missing header", latest, "", http.StatusBadRequest},
+		{"Unsupported version", latest, "1999-01-01", http.StatusBadRequest},
+		{"Negotiated older version", older, "2025-03-26", http.StatusOK},
+		{"Missing header in an older session", older, "", http.StatusOK},
+		{"Newer version in an older session", older, "2025-06-18", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newMCPRequest(`{"jsonrpc":"2.0","id":1,"method":"ping"}`)
+			req.Header.Set(sessionIDHeader, tt.session)
+			if tt.header != "" {
+				req.Header.Set(protocolVersionHeader, tt.header)
+			}
+			w := httptest.NewRecorder()
+			server.routes().ServeHTTP(w, req)
+
+			if w.Code != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d: %s", tt.expectedStatus, w.Code, strings.TrimSpace(w.Body.String()))
+			}
+		})
+	}
+}
@@ -0,0 +1,264 @@
+package mcp
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// sessionIDHeader carries the session ID issued at initialize on every later HTTP request
+const sessionIDHeader = "Mcp-Session-Id"
+
+// maxSessionSweepInterval bounds how long an expired session may linger before it is removed
+const maxSessionSweepInterval = time.Minute
+
+// httpSession is a client session of the Streamable HTTP transport: the
+// protocol state and the client's GET streams
+type httpSession struct {
+	session *Session
+	streams *sseHub
+	owner   string // Subject of the caller that created the session, when authenticated
+
+	mu       sync.Mutex
+	lastUsed time.Time
+}
+
+// touch records that the client used the session
+func (hs *httpSession) touch(now time.Time) {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	hs.lastUsed = now
+}
+
+// idleSince reports whether the session has been unused since before cutoff.
+// Sessions with an open GET stream are in use.
+func (hs *httpSession) idleSince(cutoff time.Time) bool {
+	hs.mu.Lock()
+	defer hs.mu.Unlock()
+	return hs.lastUsed.Before(cutoff) && hs.streams.open() == 0
+}
+
+// sessionStore holds the HTTP sessions issued at initialize. Sessions idle for
+// longer than idleTimeout expire; 0 keeps them until they are deleted. At most
+// maxSessions are held at once; 0 leaves them unlimited.
+type sessionStore struct {
+	mu          sync.Mutex
+	sessions    map[string]*httpSession
+	idleTimeout time.Duration
+	maxSessions int
+	now         func() time.Time
+}
+
+// newSessionStore creates an empty store
+func newSessionStore(idleTimeout time.Duration, maxSessions int) *sessionStore {
+	return &sessionStore{
+		sessions:    make(map[string]*httpSession),
+		idleTimeout: idleTimeout,
+		maxSessions: maxSessions,
+		now:         time.Now,
+	}
+}
+
+// add stores a session under its ID. It reports false, storing nothing, when
+// the store already holds maxSessions sessions.
+func (st *sessionStore) add(hs *httpSession) bool {
+	hs.touch(st.now())
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.maxSessions > 0 && len(st.sessions) >= st.maxSessions {
+		return false
+	}
+	st.sessions[hs.session.ID()] = hs
+	return true
+}
+
+// get returns the live session with the given ID and marks it used. An expired
+// session is removed and returned with false, so the caller can end it.
+func (st *sessionStore) get(id string) (hs *httpSession, expired bool, ok bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	hs, ok = st.sessions[id]
+	if !ok {
+		return nil, false, false
+	}
+	now := st.now()
+	if st.idleTimeout > 0 && hs.idleSince(now.Add(-st.idleTimeout)) {
+		delete(st.sessions, id)
+		return hs, true, false
+	}
+	hs.touch(now)
+	return hs, false, true
+}
+
+// has reports whether a session with the given ID is stored, without marking it used
+func (st *sessionStore) has(id string) bool {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	_, ok := st.sessions[id]
+	return ok
+}
+
+// remove deletes a session and returns it
+func (st *sessionStore) remove(id string) (*httpSession, bool) {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	hs, ok := st.sessions[id]
+	delete(st.sessions, id)
+	return hs, ok
+}
+
+// expire removes and returns the sessions idle for longer than the idle timeout
+func (st *sessionStore) expire() []*httpSession {
+	if st.idleTimeout <= 0 {
+		return nil
+	}
+
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	cutoff := st.now().Add(-st.idleTimeout)
+	var expired []*httpSession
+	for id, hs := range st.sessions {
+		if hs.idleSince(cutoff) {
+			delete(st.sessions, id)
+			expired = append(expired, hs)
+		}
+	}
+	return expired
+}
+
+// len returns the number of live sessions
+func (st *sessionStore) len() int {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return len(st.sessions)
+}
+
+// sweepInterval returns how often expired sessions are looked for
+func (st *sessionStore) sweepInterval() time.Duration {
+	if interval := st.idleTimeout / 2; interval < maxSessionSweepInterval {
+		return interval
+	}
+	return maxSessionSweepInterval
+}
+
+// newSessionID returns a random, unguessable session ID
+func newSessionID() string {
+	var id [16]byte
+	rand.Read(id[:])
+	return hex.EncodeToString(id[:])
+}
+
+// newHTTPSession creates a session with its own GET streams
+func (s *Server) newHTTPSession(id string) *httpSession {
+	session := NewSession()
+	session.id = id
+
+	hs := &httpSession{session: session, streams: newSSEHub()}
+
+	// Notifications outside of requests go to the session's GET streams
+	session.setNotifier(hs.streams)
+	s.dispatcher.attachSession(session)
+	return hs
+}
+
+// createSession issues a new session for a client sending initialize. It
+// reports false when the server already holds its maximum of sessions, even
+// after ending the expired ones.
+func (s *Server) createSession(r *http.Request) (*httpSession, bool) {
+	hs := s.newHTTPSession(newSessionID())
+	if principal, ok := PrincipalFromContext(r.Context()); ok {
+		hs.owner = principal.Subject
+	}
+	if s.sessions.add(hs) {
+		return hs, true
+	}
+
+	for _, expired := range s.sessions.expire() {
+		s.endSession(expired, "expired")
+	}
+	if s.sessions.add(hs) {
+		return hs, true
+	}
+	s.dispatcher.detachSession(hs.session)
+	logger.Warn("Refusing new session: session limit reached", "max", s.sessions.maxSessions)
+	return nil, false
+}
+
+// endSession releases an HTTP session: its GET streams close, its workspace
+// stops being watched for it and requests naming it get 404 from now on
+func (s *Server) endSession(hs *httpSession, reason string) {
+	s.sessions.remove(hs.session.ID())
+	hs.streams.close()
+	s.dispatcher.detachSession(hs.session)
+	logger.Info("MCP session ended", "session", hs.session.ID(), "reason", reason)
+}
+
+// sessionFor returns the session named by the request's Mcp-Session-Id header.
+// Requests without one are answered with 400, as every client must initialize
+// first. Unknown and expired sessions, and those created by another caller, are
+// answered with 404, after which clients start over with a new initialize.
+func (s *Server) sessionFor(w http.ResponseWriter, r *http.Request) (*httpSession, bool) {
+	id := r.Header.Get(sessionIDHeader)
+	if id == "" {
+		http.Error(w, "Bad request: missing "+sessionIDHeader, http.StatusBadRequest)
+		return nil, false
+	}
+
+	hs, expired, ok := s.sessions.get(id)
+	if expired {
+		s.endSession(hs, "expired")
+	}
+	if !ok || !ownsSession(r, hs) {
+		http.Error(w, "Session not found", http.StatusNotFound)
+		return nil, false
+	}
+	return hs, true
+}
+
+// ownsSession reports whether the caller of the request created the session
+func ownsSession(r *http.Request, hs *httpSession) bool {
+	principal, ok := PrincipalFromContext(r.Context())
+	if !ok {
+		return hs.owner == ""
+	}
+	return hs.owner == principal.Subject
+}
+
+// expireSessions ends idle sessions until ctx is done
+func (s *Server) expireSessions(ctx context.Context) {
+	if s.sessions.idleTimeout <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(s.sessions.sweepInterval())
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, hs := range s.sessions.expire() {
+				s.endSession(hs, "expired")
+			}
+		}
+	}
+}
+
+// handleMCPDelete ends the session named by the request's Mcp-Session-Id header
+func (s *Server) handleMCPDelete(w http.ResponseWriter, r *http.Request) {
+	hs, ok := s.sessionFor(w, r)
+	if !ok {
+		return
+	}
+	s.endSession(hs, "deleted by client")
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,289 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testInitialize = `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`
+
+// sessionRequest sends a request to the MCP endpoint naming the given session
+func sessionRequest(t *testing.T, method, url, sessionID, body string) *http.Response {
+	t.Helper()
+	req, _ := http.NewRequest(method, url+mcpEndpoint, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	if sessionID != "" {
+		req.Header.Set(sessionIDHeader, sessionID)
+		req.Header.Set(protocolVersionHeader, LatestProtocolVersion)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("%s failed: %v", method, err)
+	}
+	return resp
+}
+
+// TestHTTPSessionLifecycle tests issuing, using and deleting sessions
+func TestHTTPSessionLifecycle(t *testing.T) {
+	server := NewServer(8080)
+	server.handler.RegisterTool(Tool{
+		Name:        "whoami",
+		InputSchema: map[string]interface{}{"type": "object"},
+		Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			session, _ := SessionFromContext(ctx)
+			return session.ID(), nil
+		},
+	})
+	ts := httptest.NewServer(server.routes())
+	defer ts.Close()
+
+	initialize := func() string {
+		t.Helper()
+		resp := sessionRequest(t, http.MethodPost, ts.URL, "", testInitialize)
+		resp.Body.Close()
+		id := resp.Header.Get(sessionIDHeader)
+		if resp.StatusCode != http.StatusOK || id == "" {
+			t.Fatalf("Expected a session ID from initialize, got %d %q", resp.StatusCode, id)
+		}
+		return id
+	}
+	first, second := initialize(), initialize()
+	if first == second {
+		t.Fatal("Expected a new session ID for every initialize")
+	}
+
+	// Tools see the session of the request
+	resp := sessionRequest(t, http.MethodPost, ts.URL, second, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"whoami"}}`)
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(data), second) {
+		t.Errorf("Expected the tool to see session %s, got %s", second, data)
+	}
+
+	tests := []struct {
+		name           string
+		method         string
+		sessionID      string
+		expectedStatus int
+	}{
+		{name: "Known session", method: http.MethodPost, sessionID: first, expectedStatus: http.StatusOK},
+		{name: "Unknown session", method: http.MethodPost, sessionID: "unknown", expectedStatus: http.StatusNotFound},
+		{name: "Stream of an unknown session", method: http.MethodGet, sessionID: "unknown", expectedStatus: http.StatusNotFound},
+		{name: "Delete without a session", method: http.MethodDelete, expectedStatus: http.StatusBadRequest},
+		{name: "Delete", method: http.MethodDelete, sessionID: first, expectedStatus: http.StatusNoContent},
+		{name: "Deleted session", method: http.MethodPost, sessionID: first, expectedStatus: http.StatusNotFound},
+		{name: "Delete again", method: http.MethodDelete, sessionID: first, expectedStatus: http.StatusNotFound},
+		{name: "Other session unaffected", method: http.MethodPost, sessionID: second, expectedStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := sessionRequest(t, tt.method, ts.URL, tt.sessionID, `{"jsonrpc":"2.0","id":3,"method":"ping"}`)
+			resp.Body.Close()
+			if resp.StatusCode != tt.expectedStatus {
+				t.Errorf("Expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+		})
+	}
+
+	if n := server.sessions.len(); n != 1 {
+		t.Errorf("Expected 1 live session, got %d", n)
+	}
+}
+
+// TestHTTPSessionFailedInitialize tests that a failed initialize issues no session
+func TestHTTPSessionFailedInitialize(t *testing.T) {
+	server := NewServer(8080)
+	ts := httptest.NewServer(server.routes())
+	defer ts.Close()
+
+	resp := sessionRequest(t, http.MethodPost, ts.URL, "", `{"jsonrpc":"2.0","id":1,"method":"initialize","params":"invalid"}`)
+	resp.Body.Close()
+	if id := resp.Header.Get(sessionIDHeader); id != "" {
+		t.Errorf("Expected no session ID, got %q", id)
+	}
+	if n := server.sessions.len(); n != 0 {
+		t.Errorf("Expected no live sessions, got %d", n)
+	}
+}
+
+// TestHTTPSessionLimit tests that initialize is refused with 503 once the
+// server holds its maximum of sessions, and accepted again when one ends
+func TestHTTPSessionLimit(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MaxSessions = 2
+	server := NewServerWithConfig(cfg)
+
+	first := initSession(t, server)
+	initSession(t, server)
+
+	resp := postMCPSession(t, server, "", testInitialize, "application/json")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected status %d over the limit, got %d", http.StatusServiceUnavailable, resp.StatusCode)
+	}
+	if id := resp.Header.Get(sessionIDHeader); id != "" {
+		t.Errorf("Expected no session ID over the limit, got %q", id)
+	}
+	if n := server.sessions.len(); n != 2 {
+		t.Errorf("Expected 2 live sessions, got %d", n)
+	}
+
+	hs, _, _ := server.sessions.get(first)
+	server.endSession(hs, "deleted by client")
+	initSession(t, server)
+}
+
+// TestHTTPSessionIsolation tests that a client cannot answer a server request
+// made in another client's session, with or without a session ID of its own
+func TestHTTPSessionIsolation(t *testing.T) {
+	root := newTestWorkspace(t)
+	server := NewServer(8080)
+	server.handler.SetWorkspaceRoot(root)
+	ts := httptest.NewServer(server.routes())
+	defer ts.Close()
+
+	initialize := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{"elicitation":{}},"clientInfo":{"name":"test","version":"1"}}}`
+	open := func() string {
+		resp := sessionRequest(t, http.MethodPost, ts.URL, "", initialize)
+		resp.Body.Close()
+		return resp.Header.Get(sessionIDHeader)
+	}
+	answer := func(sessionID string, id []byte, choice string) int {
+		resp := sessionRequest(t, http.MethodPost, ts.URL, sessionID,
+			`{"jsonrpc":"2.0","id":`+string(id)+`,"result":{"action":"accept","content":{"answer":"`+choice+`"}}}`)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Client A is asked a question on the SSE response of its tool call
+	a := open()
+	call := sessionRequest(t, http.MethodPost, ts.URL, a, `{"jsonrpc":"2.0","id":2,"method":"tools/call","params":{"name":"clarify_spec","arguments":{"feature":"001-auth","questions":[{"question":"Which identity provider?","options":["OAuth","SAML"]}]}}}`)
+	defer call.Body.Close()
+	events := bufio.NewReader(call.Body)
+	nextEvent := func() map[string]interface{} {
+		t.Helper()
+		for {
+			line, err := events.ReadString('\n')
+			if err != nil {
+				t.Fatalf("Stream ended: %v", err)
+			}
+			if data, ok := strings.CutPrefix(strings.TrimSpace(line), "data: "); ok {
+				return decodeReply(t, []byte(data))
+			}
+		}
+	}
+	request := nextEvent()
+	id, _ := json.Marshal(request["id"])
+
+	// Client B answers without a session, then from its own session
+	if status := answer("", id, "SAML"); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an answer without a session, got %d", status)
+	}
+	answer(open(), id, "SAML")
+
+	// Only A's own answer reaches the tool
+	if status := answer(a, id, "OAuth"); status != http.StatusAccepted {
+		t.Errorf("Expected 202 for A's answer, got %d", status)
+	}
+	if reply := nextEvent(); reply["result"] == nil {
+		t.Fatalf("Expected a tool result, got %v", reply)
+	}
+	spec, _ := os.ReadFile(filepath.Join(root, "specs", "001-auth", "spec.md"))
+	if !strings.Contains(string(spec), "A: OAuth") || strings.Contains(string(spec), "SAML") {
+		t.Errorf("Expected only A's answer in the spec, got %q", spec)
+	}
+}
+
+// TestHTTPSessionDeleteClosesStreams tests that deleting a session ends its GET streams
+func TestHTTPSessionDeleteClosesStreams(t *testing.T) {
+	server := NewServer(8080)
+	ts := httptest.NewServer(server.routes())
+	defer ts.Close()
+
+	resp := sessionRequest(t, http.MethodPost, ts.URL, "", testInitialize)
+	resp.Body.Close()
+	id := resp.Header.Get(sessionIDHeader)
+
+	stream := sessionRequest(t, http.MethodGet, ts.URL, id, "")
+	defer stream.Body.Close()
+	if stream.StatusCode != http.StatusOK {
+		t.Fatalf("Expected the stream to open, got %d", stream.StatusCode)
+	}
+
+	deleted := sessionRequest(t, http.MethodDelete, ts.URL, id, "")
+	deleted.Body.Close()
+
+	ended := make(chan error, 1)
+	go func() {
+		_, err := io.Copy(io.Discard, bufio.NewReader(stream.Body))
+		ended <- err
+	}()
+	if err := waitFor(t, ended, "the stream to end"); err != nil {
+		t.Errorf("Expected the stream to end cleanly, got %v", err)
+	}
+}
+
+// TestSessionStoreExpiry tests that idle sessions expire unless a stream is open
+func TestSessionStoreExpiry(t *testing.T) {
+	now := time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC)
+	store := newSessionStore(time.Minute, 0)
+	store.now = func() time.Time { return now }
+
+	newSession := func(id string) *httpSession {
+		session := NewSession()
+		session.id = id
+		hs := &httpSession{session: session, streams: newSSEHub()}
+		store.add(hs)
+		return hs
+	}
+	newSession("idle")
+	newSession("used")
+	streaming := newSession("streaming")
+	messages := streaming.streams.subscribe()
+	defer streaming.streams.unsubscribe(messages)
+
+	now = now.Add(50 * time.Second)
+	if _, _, ok := store.get("used"); !ok {
+		t.Fatal("Expected the session to be live before the timeout")
+	}
+
+	now = now.Add(20 * time.Second)
+	if _, expired, ok := store.get("idle"); ok || !expired {
+		t.Errorf("Expected the idle session to have expired, got ok=%v expired=%v", ok, expired)
+	}
+	if _, _, ok := store.get("idle"); ok {
+		t.Error("Expected the expired session to be gone")
+	}
+
+	if expired := store.expire(); len(expired) != 0 {
+		t.Errorf("Expected no other expired sessions, got %d", len(expired))
+	}
+
+	now = now.Add(time.Minute)
+	expired := store.expire()
+	if len(expired) != 1 || expired[0].session.ID() != "used" {
+		t.Errorf("Expected only the used session to expire, got %d", len(expired))
+	}
+	if store.len() != 1 {
+		t.Errorf("Expected the streaming session to stay, got %d sessions", store.len())
+	}
+
+	// A timeout of 0 keeps sessions forever
+	store = newSessionStore(0, 0)
+	newSession("kept")
+	now = now.Add(24 * time.Hour)
+	if _, _, ok := store.get("kept"); !ok || len(store.expire()) != 0 {
+		t.Error("Expected sessions to be kept without an idle timeout")
+	}
+}
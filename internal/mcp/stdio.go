@@ -0,0 +1,351 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// stdioQueueSize bounds the number of requests read ahead of the workers
+const stdioQueueSize = 64
+
+// stdioDrainTimeout is how long in-flight requests may keep running after a
+// shutdown signal before they are cancelled
+const stdioDrainTimeout = 30 * time.Second
+
+// StdioServer represents the MCP server using stdio transport
+type StdioServer struct {
+	handler        *Handler
+	dispatcher     *Dispatcher
+	session        *Session
+	watcher        *resourceWatcher
+	workers        int
+	maxMessageSize int
+	drainTimeout   time.Duration
+	audit          AuditConfig
+}
+
+// NewStdioServer creates a new MCP server instance for stdio transport with default settings
+func NewStdioServer() *StdioServer {
+	return NewStdioServerWithConfig(DefaultConfig())
+}
+
+// NewStdioServerWithConfig creates a new MCP server instance for stdio transport from the given settings
+func NewStdioServerWithConfig(cfg Config) *StdioServer {
+	handler := NewHandler()
+	dispatcher := NewDispatcher(handler)
+	dispatcher.SetRequestTimeout(cfg.RequestTimeout())
+	dispatcher.SetMaxConcurrentTools(cfg.MaxConcurrentTools)
+
+	workers := cfg.StdioWorkers
+	if workers < 1 {
+		workers = 1
+	}
+	if cfg.MaxMessageBytes < 1 {
+		cfg.MaxMessageBytes = DefaultConfig().MaxMessageBytes
+	}
+
+	s := &StdioServer{
+		handler:        handler,
+		dispatcher:     dispatcher,
+		session:        NewSession(),
+		workers:        workers,
+		maxMessageSize: cfg.MaxMessageBytes,
+		drainTimeout:   stdioDrainTimeout,
+		audit:          cfg.Audit,
+	}
+
+	if cfg.WatchInterval() > 0 {
+		s.watcher = newResourceWatcher(handler, s.session, cfg.WatchInterval(), cfg.WatchDebounce())
+		dispatcher.watchingResources = true
+	}
+
+	return s
+}
+
+// Start starts the MCP server in stdio mode. It returns when stdin is closed or
+// after an interrupt signal, once running requests have finished.
+func (s *StdioServer) Start() error {
+	// stdout carries the JSON-RPC stream from here on
+	protectStdout()
+
+	closeAudit, err := s.dispatcher.startAudit(s.audit, TransportStdio)
+	if err != nil {
+		return err
+	}
+	defer closeAudit()
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		<-ctx.Done()
+		// A second signal terminates immediately
+		stop()
+	}()
+
+	return s.serve(ctx, os.Stdin, os.Stdout)
+}
+
+// lineWriter writes newline-delimited messages, serialising concurrent writers
+// so replies and notifications never interleave
+type lineWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// writeLine writes data followed by a newline
+func (lw *lineWriter) writeLine(data []byte) error {
+	lw.mu.Lock()
+	defer lw.mu.Unlock()
+
+	line := make([]byte, 0, len(data)+1)
+	line = append(append(line, data...), '\n')
+	_, err := lw.w.Write(line)
+	return err
+}
+
+// send writes a server-initiated request as a single line
+func (lw *lineWriter) send(data []byte) error {
+	return lw.writeLine(data)
+}
+
+// Notify writes a server notification as a single line
+func (lw *lineWriter) Notify(method string, params interface{}) error {
+	data, err := encodeNotification(method, params)
+	if err != nil {
+		return err
+	}
+	return lw.writeLine(data)
+}
+
+// serve reads newline-delimited JSON-RPC messages from in and writes responses to out.
+// Requests are handled concurrently by a bounded pool of workers, while
+// notifications are handled as they arrive so a cancellation reaches a request
+// that is still running. When ctx is done, serve stops accepting messages and
+// drains the requests already accepted, cancelling them after the drain timeout.
+func (s *StdioServer) serve(ctx context.Context, in io.Reader, out io.Writer) error {
+	writer := &lineWriter{w: out}
+
+	// Requests outlive ctx while draining, so they get their own cancellation
+	requestCtx, cancelRequests := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelRequests()
+
+	// A stdio connection serves exactly one client session
+	requestCtx = ContextWithSession(requestCtx, s.session)
+	requestCtx = ContextWithNotifier(requestCtx, writer)
+
+	// Notifications outside of requests, such as resource updates, share the writer
+	s.session.setNotifier(writer)
+	s.dispatcher.attachSession(s.session)
+	defer s.dispatcher.detachSession(s.session)
+	if s.watcher != nil {
+		go s.watcher.run(requestCtx, s.dispatcher.publishResourceChanges)
+	}
+
+	requests := make(chan []byte, stdioQueueSize)
+	var workers sync.WaitGroup
+	for i := 0; i < s.workers; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for message := range requests {
+				reply := s.dispatcher.HandleMessage(requestCtx, message)
+				if reply == nil {
+					// Notifications, client responses and cancelled requests never get a reply
+					continue
+				}
+				if err := writer.writeLine(reply); err != nil {
+					logger.Error("Failed to write reply", "error", err)
+				}
+			}
+		}()
+	}
+
+	messages, readErr := readMessages(in, s.maxMessageSize)
+
+read:
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Info("Shutting down MCP server")
+			break read
+		case message, ok := <-messages:
+			if !ok {
+				break read
+			}
+			if message.oversized {
+				s.rejectOversized(writer, message.data)
+				continue
+			}
+			line := message.data
+			if isNotificationMessage(line) || isResponseMessage(line) {
+				// Handled inline so responses reach requests that are waiting on a worker
+				s.dispatcher.HandleMessage(requestCtx, line)
+				continue
+			}
+			select {
+			case requests <- line:
+			case <-ctx.Done():
+				logger.Info("Shutting down MCP server")
+				break read
+			}
+		}
+	}
+
+	close(requests)
+	s.drain(&workers, cancelRequests, ctx.Err() != nil)
+
+	if err := readErr(); err != nil {
+		return fmt.Errorf("error reading from stdin: %w", err)
+	}
+
+	return nil
+}
+
+// rejectOversized replies to a message over the size limit with an error for
+// its request id, so the client's request fails instead of hanging
+func (s *StdioServer) rejectOversized(writer *lineWriter, prefix []byte) {
+	logger.Warn("Rejecting oversized message", "max_bytes", s.maxMessageSize)
+
+	// When the id is not among the bytes kept, the error goes out with a null id
+	reply := encodeReply(newErrorResponse(peekRequestID(prefix), NewError(CodeInvalidRequest,
+		"Invalid request: message exceeds the maximum size of %d bytes", s.maxMessageSize)))
+	if err := writer.writeLine(reply); err != nil {
+		logger.Error("Failed to write reply", "error", err)
+	}
+}
+
+// drain waits for the workers to finish the requests already accepted. During
+// shutdown, requests still running after the drain timeout are cancelled.
+func (s *StdioServer) drain(workers *sync.WaitGroup, cancelRequests context.CancelFunc, shuttingDown bool) {
+	done := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(done)
+	}()
+
+	if !shuttingDown {
+		<-done
+		return
+	}
+
+	select {
+	case <-done:
+	case <-time.After(s.drainTimeout):
+		logger.Warn("Cancelling requests still running after drain timeout", "timeout", s.drainTimeout)
+		cancelRequests()
+		<-done
+	}
+}
+
+// stdioMessage is a line read from stdin. Lines over the size limit carry only
+// their first bytes, enough to recover the request id.
+type stdioMessage struct {
+	data      []byte
+	oversized bool
+}
+
+// oversizedPrefixSize is how much of an oversized message is kept to recover its id
+const oversizedPrefixSize = 4096
+
+// readMessages reads newline-delimited messages from in on a separate goroutine,
+// never buffering more than maxSize bytes of a single message. The returned
+// function reports the read error once the channel has been closed, and nil
+// while the reader is still running.
+func readMessages(in io.Reader, maxSize int) (<-chan stdioMessage, func() error) {
+	messages := make(chan stdioMessage)
+	done := make(chan struct{})
+	var err error
+
+	go func() {
+		defer close(messages)
+
+		reader := bufio.NewReader(in)
+		for {
+			message, readErr := readMessage(reader, maxSize)
+			if data := bytes.TrimSpace(message.data); len(data) > 0 {
+				message.data = data
+				messages <- message
+			}
+			if readErr != nil {
+				if readErr != io.EOF {
+					err = readErr
+				}
+				break
+			}
+		}
+		close(done)
+	}()
+
+	return messages, func() error {
+		select {
+		case <-done:
+			return err
+		default:
+			return nil
+		}
+	}
+}
+
+// readMessage reads one line from r. Once the line grows past maxSize, the rest
+// of it is discarded and the message is marked oversized.
+func readMessage(r *bufio.Reader, maxSize int) (stdioMessage, error) {
+	var message stdioMessage
+	for {
+		chunk, err := r.ReadSlice('\n')
+		if !message.oversized {
+			if len(message.data)+len(chunk) > maxSize {
+				message.oversized = true
+				keep := oversizedPrefixSize - len(message.data)
+				if keep > len(chunk) {
+					keep = len(chunk)
+				}
+				if keep > 0 {
+					message.data = append(message.data, chunk[:keep]...)
+				}
+			} else {
+				message.data = append(message.data, chunk...)
+			}
+		}
+		if err != bufio.ErrBufferFull {
+			return message, err
+		}
+	}
+}
+
+// peekRequestID extracts the top-level id from the start of a message that was
+// too large to read in full. It returns nil when the id does not appear in the
+// bytes available.
+func peekRequestID(prefix []byte) json.RawMessage {
+	decoder := json.NewDecoder(bytes.NewReader(prefix))
+	if token, err := decoder.Token(); err != nil || token != json.Delim('{') {
+		return nil
+	}
+
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return nil
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil
+		}
+		if key == "id" {
+			if !validID(value) {
+				return nil
+			}
+			return value
+		}
+	}
+	return nil
+}
@@ -0,0 +1,241 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// startStdioServer serves the server over pipes and returns the client ends
+func startStdioServer(ctx context.Context, t *testing.T, server *StdioServer) (io.WriteCloser, *bufio.Reader, <-chan error) {
+	t.Helper()
+
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	served := make(chan error, 1)
+	go func() {
+		served <- server.serve(ctx, inReader, outWriter)
+		outWriter.Close()
+	}()
+	t.Cleanup(func() { inWriter.Close() })
+
+	return inWriter, bufio.NewReader(outReader), served
+}
+
+// TestStdioServerConcurrentRequests tests that a slow request does not block later ones
+func TestStdioServerConcurrentRequests(t *testing.T) {
+	server := NewStdioServer()
+	started, _ := registerBlockingTool(server.handler)
+	in, out, served := startStdioServer(context.Background(), t, server)
+
+	io.WriteString(in, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"block"}}`+"\n")
+	waitFor(t, started, "handler to start")
+
+	io.WriteString(in, `{"jsonrpc":"2.0","id":2,"method":"ping"}`+"\n")
+	line, err := out.ReadString('\n')
+	if err != nil {
+		t.Fatalf("Failed to read reply: %v", err)
+	}
+	if response := decodeReply(t, []byte(line)); response["id"] != float64(2) {
+		t.Errorf("Expected the ping reply while the tool runs, got %s", line)
+	}
+
+	io.WriteString(in, `{"jsonrpc":"2.0","method":"notifications/cancelled","params":{"requestId":1}}`+"\n")
+	in.Close()
+	if err := waitFor(t, served, "serve to return"); err != nil {
+		t.Errorf("serve returned error: %v", err)
+	}
+}
+
+// TestStdioServerSingleWorker tests that one worker handles requests in order
+func TestStdioServerSingleWorker(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.StdioWorkers = 1
+	server := NewStdioServerWithConfig(cfg)
+
+	input := strings.Repeat(`{"jsonrpc":"2.0","id":1,"method":"ping"}`+"\n", 3)
+	var output bytes.Buffer
+	if err := server.serve(context.Background(), strings.NewReader(input), &output); err != nil {
+		t.Fatalf("serve returned error: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSpace(output.String()), "\n"); len(lines) != 3 {
+		t.Errorf("Expected 3 replies, got %d", len(lines))
+	}
+}
+
+// TestStdioServerDrainsOnShutdown tests that running requests finish and reply after shutdown begins
+func TestStdioServerDrainsOnShutdown(t *testing.T) {
+	server := NewStdioServer()
+	release := make(chan struct{})
+	started := make(chan struct{}, 1)
+	server.handler.RegisterTool(Tool{
+		Name:        "slow",
+		InputSchema: map[string]interface{}{"type": "object"},
+		Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			started <- struct{}{}
+			<-release
+			return "done", nil
+		},
+	})
+
+	ctx, shutdown := context.WithCancel(context.Background())
+	in, out, served := startStdioServer(ctx, t, server)
+
+	io.WriteString(in, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"slow"}}`+"\n")
+	waitFor(t, started, "handler to start")
+
+	shutdown()
+	select {
+	case <-served:
+		t.Fatal("serve returned before the running request finished")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	line, err := out.ReadString('\n')
+	if err != nil {
+		t.Fatalf("Failed to read reply: %v", err)
+	}
+	response := decodeReply(t, []byte(line))
+	if response["id"] != float64(1) || response["error"] != nil {
+		t.Errorf("Expected a successful reply after shutdown, got %s", line)
+	}
+	if err := waitFor(t, served, "serve to return"); err != nil {
+		t.Errorf("serve returned error: %v", err)
+	}
+}
+
+// TestStdioServerDrainTimeout tests that requests still running after the drain timeout are cancelled
+func TestStdioServerDrainTimeout(t *testing.T) {
+	server := NewStdioServer()
+	server.drainTimeout = 20 * time.Millisecond
+	started, stopped := registerBlockingTool(server.handler)
+
+	ctx, shutdown := context.WithCancel(context.Background())
+	in, out, served := startStdioServer(ctx, t, server)
+	go io.Copy(io.Discard, out)
+
+	io.WriteString(in, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"block"}}`+"\n")
+	waitFor(t, started, "handler to start")
+
+	shutdown()
+	if err := waitFor(t, stopped, "handler to stop"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected handler to see Canceled, got %v", err)
+	}
+	if err := waitFor(t, served, "serve to return"); err != nil {
+		t.Errorf("serve returned error: %v", err)
+	}
+}
+
+// TestLineWriter tests that concurrent writes never interleave
+func TestLineWriter(t *testing.T) {
+	var output bytes.Buffer
+	writer := &lineWriter{w: &output}
+	message := []byte(strings.Repeat("x", 4096))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			writer.writeLine(message)
+		}()
+	}
+	wg.Wait()
+
+	lines := strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+	if len(lines) != 16 {
+		t.Fatalf("Expected 16 lines, got %d", len(lines))
+	}
+	for i, line := range lines {
+		if line != string(message) {
+			t.Errorf("Line %d was interleaved with another write", i)
+		}
+	}
+}
+
+// TestStdioServerLargeMessage tests messages larger than a default bufio.Scanner token
+func TestStdioServerLargeMessage(t *testing.T) {
+	server := NewStdioServer()
+	message := strings.Repeat("a", 256<<10)
+
+	input := `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"echo","arguments":{"message":"` + message + `"}}}` + "\n"
+	var output bytes.Buffer
+	if err := server.serve(context.Background(), strings.NewReader(input), &output); err != nil {
+		t.Fatalf("serve returned error: %v", err)
+	}
+
+	response := decodeReply(t, output.Bytes())
+	if response["error"] != nil {
+		t.Fatalf("Unexpected error: %v", response["error"])
+	}
+	if !strings.Contains(output.String(), message) {
+		t.Error("Expected the echoed message in the reply")
+	}
+}
+
+// TestStdioServerOversizedMessage tests that oversized messages are rejected without ending the session
+func TestStdioServerOversizedMessage(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.MaxMessageBytes = 1024
+	cfg.StdioWorkers = 1
+	server := NewStdioServerWithConfig(cfg)
+
+	oversized := `{"jsonrpc":"2.0","id":"big","method":"tools/call","params":{"name":"echo","arguments":{"message":"` +
+		strings.Repeat("a", 8192) + `"}}}`
+	input := oversized + "\n" + `{"jsonrpc":"2.0","id":2,"method":"ping"}` + "\n"
+
+	var output bytes.Buffer
+	if err := server.serve(context.Background(), strings.NewReader(input), &output); err != nil {
+		t.Fatalf("serve returned error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 replies, got %d: %s", len(lines), output.String())
+	}
+
+	rejected := decodeReply(t, []byte(lines[0]))
+	if rejected["id"] != "big" {
+		t.Errorf("Expected the error to carry the request id, got %v", rejected["id"])
+	}
+	errorData, ok := rejected["error"].(map[string]interface{})
+	if !ok || errorData["code"] != float64(CodeInvalidRequest) {
+		t.Errorf("Expected invalid request error, got %v", rejected["error"])
+	}
+
+	if ping := decodeReply(t, []byte(lines[1])); ping["id"] != float64(2) || ping["error"] != nil {
+		t.Errorf("Expected the session to keep running, got %s", lines[1])
+	}
+}
+
+// TestPeekRequestID tests recovering the id from the start of a truncated message
+func TestPeekRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		prefix   string
+		expected string
+	}{
+		{"Number id", `{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"argu`, `7`},
+		{"String id", `{"id":"abc","params":{"a":`, `"abc"`},
+		{"Id after params", `{"jsonrpc":"2.0","method":"tools/call","params":{"argu`, ``},
+		{"Nested id ignored", `{"params":{"id":3},"method":"x","par`, ``},
+		{"Invalid id", `{"id":{"x":1},"method":`, ``},
+		{"Batch", `[{"jsonrpc":"2.0","id":1`, ``},
+		{"Not JSON", `hello`, ``},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if id := string(peekRequestID([]byte(tt.prefix))); id != tt.expected {
+				t.Errorf("Expected id %q, got %q", tt.expected, id)
+			}
+		})
+	}
+}
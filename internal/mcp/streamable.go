@@ -0,0 +1,393 @@
+package mcp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// mcpEndpoint is the single Streamable HTTP endpoint that serves JSON-RPC traffic
+const mcpEndpoint = "/mcp"
+
+// protocolVersionHeader carries the negotiated protocol version on HTTP requests after initialize
+const protocolVersionHeader = "Mcp-Protocol-Version"
+
+// assumedProtocolVersion is the protocol version of HTTP requests without a
+// protocol version header, as the specification directs
+const assumedProtocolVersion = "2025-03-26"
+
+// writeTimeout bounds writing a response. Replies to /mcp POSTs get it from
+// the moment they are ready, as requests may run longer.
+const writeTimeout = 15 * time.Second
+
+// sseKeepAliveInterval controls how often idle SSE streams receive a comment line
+const sseKeepAliveInterval = 30 * time.Second
+
+// sseHub fans server-initiated messages out to every open GET stream
+type sseHub struct {
+	mu      sync.Mutex
+	streams map[chan []byte]struct{}
+
+	// done is closed when the session ends, ending its streams
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newSSEHub creates an empty stream hub
+func newSSEHub() *sseHub {
+	return &sseHub{
+		streams: make(map[chan []byte]struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// open returns the number of open streams
+func (h *sseHub) open() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.streams)
+}
+
+// close ends every stream of the hub
+func (h *sseHub) close() {
+	h.closeOnce.Do(func() { close(h.done) })
+}
+
+// subscribe registers a new stream and returns its message channel
+func (h *sseHub) subscribe() chan []byte {
+	ch := make(chan []byte, 16)
+
+	h.mu.Lock()
+	h.streams[ch] = struct{}{}
+	h.mu.Unlock()
+
+	return ch
+}
+
+// unsubscribe removes a stream from the hub
+func (h *sseHub) unsubscribe(ch chan []byte) {
+	h.mu.Lock()
+	delete(h.streams, ch)
+	h.mu.Unlock()
+}
+
+// publish delivers a message to every open stream, dropping it for slow readers
+func (h *sseHub) publish(message []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for ch := range h.streams {
+		select {
+		case ch <- message:
+		default:
+			logger.Warn("Dropping server message for slow SSE stream")
+		}
+	}
+}
+
+// send publishes a server-initiated request to every open stream. It fails
+// when none is open, as the request could not be answered.
+func (h *sseHub) send(message []byte) error {
+	if h.open() == 0 {
+		return errNoStream
+	}
+	h.publish(message)
+	return nil
+}
+
+// Notify publishes a server notification to every open stream
+func (h *sseHub) Notify(method string, params interface{}) error {
+	data, err := encodeNotification(method, params)
+	if err != nil {
+		return err
+	}
+	h.publish(data)
+	return nil
+}
+
+// postStream upgrades the response to a POST to an SSE stream as soon as a
+// notification for it is sent, so the notification reaches the client ahead of
+// the reply. Responses without notifications keep their regular form.
+type postStream struct {
+	mu        sync.Mutex
+	w         http.ResponseWriter
+	streaming bool
+	closed    bool
+}
+
+// Notify writes a notification to the POST response, switching it to SSE first
+func (p *postStream) Notify(method string, params interface{}) error {
+	data, err := encodeNotification(method, params)
+	if err != nil {
+		return err
+	}
+	return p.send(data)
+}
+
+// send writes a message to the POST response, switching it to SSE first
+func (p *postStream) send(data []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.closed {
+		return errStreamClosed
+	}
+	if !p.streaming {
+		// Streamed responses last as long as the request does, not the server's write timeout
+		if err := http.NewResponseController(p.w).SetWriteDeadline(time.Time{}); err != nil && err != http.ErrNotSupported {
+			logger.Warn("Failed to clear SSE write deadline", "error", err)
+		}
+		setSSEHeaders(p.w)
+		p.w.WriteHeader(http.StatusOK)
+		p.streaming = true
+	}
+
+	if err := writeSSEEvent(p.w, data); err != nil {
+		return err
+	}
+	if flusher, ok := p.w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// finish closes the stream to further notifications and, when the response was
+// upgraded, writes the reply as the final event. It reports whether it did so.
+func (p *postStream) finish(reply []byte) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+	if !p.streaming {
+		return false
+	}
+	if reply != nil {
+		if err := writeSSEEvent(p.w, reply); err != nil {
+			logger.Error("Failed to write SSE response", "error", err)
+		}
+	}
+	return true
+}
+
+// errStreamClosed is returned for notifications sent after a POST was answered
+var errStreamClosed = errors.New("response stream already closed")
+
+// errNoStream is returned for requests sent while the client has no GET stream open
+var errNoStream = errors.New("no SSE stream open")
+
+// handleMCP implements the Streamable HTTP transport on a single endpoint
+func (s *Server) handleMCP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		s.handleMCPPost(w, r)
+	case http.MethodGet:
+		s.handleMCPStream(w, r)
+	case http.MethodDelete:
+		s.handleMCPDelete(w, r)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleMCPPost handles a JSON-RPC message sent by the client
+func (s *Server) handleMCPPost(w http.ResponseWriter, r *http.Request) {
+	body, ok := s.readBody(w, r)
+	if !ok {
+		return
+	}
+
+	if !json.Valid(body) {
+		s.respondJSON(w, http.StatusBadRequest, newErrorResponse(nil, NewError(CodeParseError, "Parse error")))
+		return
+	}
+
+	if version := r.Header.Get(protocolVersionHeader); version != "" && !IsSupportedProtocolVersion(version) {
+		http.Error(w, fmt.Sprintf("Unsupported %s: %s", protocolVersionHeader, version), http.StatusBadRequest)
+		return
+	}
+
+	// An initialize request starts a new session, whose ID the client sends on
+	// every later request; other requests continue the session they name
+	var hs *httpSession
+	initializing := isInitializeRequest(body)
+	if initializing {
+		if hs, ok = s.createSession(r); !ok {
+			http.Error(w, "Service unavailable: too many sessions, retry later", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set(sessionIDHeader, hs.session.ID())
+	} else if hs, ok = s.sessionFor(w, r); !ok {
+		return
+	}
+
+	// Once initialized, the session speaks only the version it negotiated
+	if !initializing && hs.session.negotiated() {
+		version := r.Header.Get(protocolVersionHeader)
+		if version == "" {
+			version = assumedProtocolVersion
+		}
+		if negotiated := hs.session.ProtocolVersion(); version != negotiated {
+			http.Error(w, fmt.Sprintf("Bad request: %s %s does not match the session's protocol version %s", protocolVersionHeader, version, negotiated), http.StatusBadRequest)
+			return
+		}
+	}
+
+	ctx := ContextWithSession(r.Context(), hs.session)
+
+	// The reply is written once the request is handled, which may take up to
+	// the request timeout, so the write deadline starts only then
+	controller := http.NewResponseController(w)
+	if err := controller.SetWriteDeadline(time.Time{}); err != nil && err != http.ErrNotSupported {
+		logger.Warn("Failed to clear write deadline", "error", err)
+	}
+
+	// Notifications produced while handling the POST go on its own response when
+	// the client accepts SSE there, and to the GET streams otherwise
+	var stream *postStream
+	if acceptsMediaType(r, "text/event-stream") {
+		stream = &postStream{w: w}
+		ctx = ContextWithNotifier(ctx, stream)
+	} else {
+		ctx = ContextWithNotifier(ctx, hs.streams)
+	}
+
+	reply := s.dispatcher.HandleMessage(ctx, body)
+
+	// A failed initialize leaves nothing to continue
+	if initializing && !hs.session.negotiated() {
+		s.endSession(hs, "initialize failed")
+		w.Header().Del(sessionIDHeader)
+	}
+	if stream != nil && stream.finish(reply) {
+		return
+	}
+	if err := controller.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil && err != http.ErrNotSupported {
+		logger.Warn("Failed to set write deadline", "error", err)
+	}
+
+	// Payloads holding only notifications and responses are acknowledged without a body
+	if reply == nil {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	// A tool call turned away because every slot is taken is worth retrying shortly
+	if isToolCallRequest(body) && isServerBusyReply(reply) {
+		w.Header().Set("Retry-After", "1")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write(reply)
+		return
+	}
+
+	if acceptsMediaType(r, "text/event-stream") && !acceptsMediaType(r, "application/json") {
+		s.respondSSE(w, reply)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(reply); err != nil {
+		logger.Error("Failed to write JSON-RPC reply", "error", err)
+	}
+}
+
+// handleMCPStream opens an SSE stream for server-initiated messages
+func (s *Server) handleMCPStream(w http.ResponseWriter, r *http.Request) {
+	if !acceptsMediaType(r, "text/event-stream") {
+		http.Error(w, "Not acceptable: GET requires Accept: text/event-stream", http.StatusNotAcceptable)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	hs, ok := s.sessionFor(w, r)
+	if !ok {
+		return
+	}
+
+	// Long-lived streams must outlive the server's write timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil && err != http.ErrNotSupported {
+		logger.Warn("Failed to clear SSE write deadline", "error", err)
+	}
+
+	setSSEHeaders(w)
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	messages := hs.streams.subscribe()
+	defer hs.streams.unsubscribe(messages)
+
+	// Roots the client could not be asked for without a stream are listed now
+	if hs.session.needsRoots() {
+		go s.dispatcher.refreshRoots(hs.session)
+	}
+
+	keepAlive := time.NewTicker(sseKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-hs.streams.done:
+			return
+		case message := <-messages:
+			if err := writeSSEEvent(w, message); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-keepAlive.C:
+			if _, err := io.WriteString(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// respondSSE sends a single JSON-RPC reply as an SSE stream and closes it
+func (s *Server) respondSSE(w http.ResponseWriter, reply []byte) {
+	setSSEHeaders(w)
+	w.WriteHeader(http.StatusOK)
+
+	if err := writeSSEEvent(w, reply); err != nil {
+		logger.Error("Failed to write SSE response", "error", err)
+	}
+}
+
+// acceptsMediaType reports whether the request's Accept header lists the media type
+func acceptsMediaType(r *http.Request, mediaType string) bool {
+	for _, value := range r.Header.Values("Accept") {
+		for _, part := range strings.Split(value, ",") {
+			accepted := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+			if accepted == mediaType || accepted == "*/*" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// setSSEHeaders sets the response headers for an event stream
+func setSSEHeaders(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+}
+
+// writeSSEEvent writes a single SSE message event
+func writeSSEEvent(w io.Writer, data []byte) error {
+	_, err := fmt.Fprintf(w, "event: message\ndata: %s\n\n", data)
+	return err
+}
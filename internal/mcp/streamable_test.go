@@ -0,0 +1,408 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newMCPRequest builds a POST request carrying a JSON-RPC message for the MCP endpoint
+func newMCPRequest(body string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "http://localhost:8080"+mcpEndpoint, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	return req
+}
+
+// postMCP sends a JSON-RPC message to the Streamable HTTP endpoint, in a new
+// session unless the message is an initialize request
+func postMCP(t *testing.T, server *Server, body string, accept string) *http.Response {
+	t.Helper()
+
+	sessionID := ""
+	if !isInitializeRequest([]byte(body)) {
+		sessionID = initSession(t, server)
+	}
+	return postMCPSession(t, server, sessionID, body, accept)
+}
+
+// initSession initializes a new session and returns its ID
+func initSession(t *testing.T, server *Server) string {
+	t.Helper()
+
+	resp := postMCPSession(t, server, "", testInitialize, "application/json")
+	resp.Body.Close()
+	id := resp.Header.Get(sessionIDHeader)
+	if id == "" {
+		t.Fatalf("Expected a session ID from initialize, got status %d", resp.StatusCode)
+	}
+	return id
+}
+
+// postMCPSession sends a JSON-RPC message in the session with the given ID
+func postMCPSession(t *testing.T, server *Server, sessionID, body, accept string) *http.Response {
+	t.Helper()
+
+	req := newMCPRequest(body)
+	if sessionID != "" {
+		req.Header.Set(sessionIDHeader, sessionID)
+		req.Header.Set(protocolVersionHeader, LatestProtocolVersion)
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+
+	server.routes().ServeHTTP(w, req)
+	return w.Result()
+}
+
+// TestStreamableHTTPPost tests JSON-RPC requests over the single MCP endpoint
+func TestStreamableHTTPPost(t *testing.T) {
+	server := NewServer(8080)
+
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedCode   int
+		checkResult    func(t *testing.T, result map[string]interface{})
+	}{
+		{
+			name:           "Initialize",
+			body:           `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+			expectedStatus: http.StatusOK,
+			checkResult: func(t *testing.T, result map[string]interface{}) {
+				if _, ok := result["protocolVersion"]; !ok {
+					t.Error("Missing protocolVersion in result")
+				}
+				if _, ok := result["capabilities"]; !ok {
+					t.Error("Missing capabilities in result")
+				}
+			},
+		},
+		{
+			name:           "Tools list",
+			body:           `{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+			expectedStatus: http.StatusOK,
+			checkResult: func(t *testing.T, result map[string]interface{}) {
+				if _, ok := result["tools"]; !ok {
+					t.Error("Missing tools in result")
+				}
+			},
+		},
+		{
+			name:           "Tools call",
+			body:           `{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"echo","arguments":{"message":"hi"}}}`,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "Resources read",
+			body:           `{"jsonrpc":"2.0","id":4,"method":"resources/read","params":{"uri":"info://server"}}`,
+			expectedStatus: http.StatusOK,
+			checkResult: func(t *testing.T, result map[string]interface{}) {
+				if _, ok := result["contents"]; !ok {
+					t.Error("Missing contents in result")
+				}
+			},
+		},
+		{
+			name:           "Unknown method",
+			body:           `{"jsonrpc":"2.0","id":5,"method":"unknown/method"}`,
+			expectedStatus: http.StatusOK,
+			expectedCode:   -32601,
+		},
+		{
+			name:           "Malformed JSON",
+			body:           `{"jsonrpc":`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   -32700,
+		},
+		{
+			name:           "Notification is accepted without body",
+			body:           `{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+			expectedStatus: http.StatusAccepted,
+		},
+		{
+			name:           "Client response is accepted without body",
+			body:           `{"jsonrpc":"2.0","id":"srv-1","result":{}}`,
+			expectedStatus: http.StatusAccepted,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := postMCP(t, server, tt.body, "application/json, text/event-stream")
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.expectedStatus {
+				t.Fatalf("Expected status %d, got %d", tt.expectedStatus, resp.StatusCode)
+			}
+
+			body, _ := io.ReadAll(resp.Body)
+			if resp.StatusCode == http.StatusAccepted {
+				if len(body) != 0 {
+					t.Errorf("Expected empty body for 202, got %q", body)
+				}
+				return
+			}
+
+			var response map[string]interface{}
+			if err := json.Unmarshal(body, &response); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+
+			if response["jsonrpc"] != "2.0" {
+				t.Error("Missing or invalid jsonrpc version")
+			}
+
+			if tt.expectedCode != 0 {
+				errorData, ok := response["error"].(map[string]interface{})
+				if !ok {
+					t.Fatal("Expected error in response")
+				}
+				if code := int(errorData["code"].(float64)); code != tt.expectedCode {
+					t.Errorf("Expected error code %d, got %d", tt.expectedCode, code)
+				}
+				return
+			}
+
+			result, ok := response["result"].(map[string]interface{})
+			if !ok {
+				t.Fatalf("Missing result in response: %s", body)
+			}
+			if tt.checkResult != nil {
+				tt.checkResult(t, result)
+			}
+		})
+	}
+}
+
+// TestStreamableHTTPPostSSEResponse tests that SSE-only clients get an event stream
+func TestStreamableHTTPPostSSEResponse(t *testing.T) {
+	server := NewServer(8080)
+
+	resp := postMCP(t, server, `{"jsonrpc":"2.0","id":1,"method":"tools/list"}`, "text/event-stream")
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected Content-Type text/event-stream, got %s", ct)
+	}
+
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.HasPrefix(string(body), "event: message\ndata: ") {
+		t.Fatalf("Unexpected SSE body: %q", body)
+	}
+
+	data := strings.TrimSpace(strings.TrimPrefix(string(body), "event: message\ndata: "))
+	var response map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &response); err != nil {
+		t.Fatalf("Failed to unmarshal SSE data: %v", err)
+	}
+	if _, ok := response["result"]; !ok {
+		t.Error("Missing result in SSE response")
+	}
+}
+
+// TestStreamableHTTPPostOutlivesWriteTimeout tests that a JSON reply to a
+// request running longer than the server's write timeout still arrives
+func TestStreamableHTTPPostOutlivesWriteTimeout(t *testing.T) {
+	server := NewServer(8080)
+	server.handler.RegisterTool(Tool{
+		Name:        "slow",
+		InputSchema: map[string]interface{}{"type": "object"},
+		Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+			time.Sleep(200 * time.Millisecond)
+			return "done", nil
+		},
+	})
+	ts := httptest.NewUnstartedServer(server.routes())
+	ts.Config.WriteTimeout = 50 * time.Millisecond
+	ts.Start()
+	defer ts.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+mcpEndpoint,
+		strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"slow"}}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set(sessionIDHeader, initSession(t, server))
+	req.Header.Set(protocolVersionHeader, LatestProtocolVersion)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("POST failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var response Response
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("Expected a JSON reply, got status %d: %v", resp.StatusCode, err)
+	}
+	if response.Error != nil {
+		t.Errorf("Expected a result, got %+v", response.Error)
+	}
+}
+
+// TestStreamableHTTPMethodNotAllowed tests unsupported HTTP methods on the MCP endpoint
+func TestStreamableHTTPMethodNotAllowed(t *testing.T) {
+	server := NewServer(8080)
+
+	req := httptest.NewRequest(http.MethodPut, "http://localhost:8080"+mcpEndpoint, nil)
+	w := httptest.NewRecorder()
+	server.routes().ServeHTTP(w, req)
+
+	if w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected status 405, got %d", w.Code)
+	}
+	if allow := w.Header().Get("Allow"); !strings.Contains(allow, "POST") {
+		t.Errorf("Expected Allow header to list POST, got %q", allow)
+	}
+}
+
+// TestStreamableHTTPGetRequiresEventStream tests the Accept check on GET
+func TestStreamableHTTPGetRequiresEventStream(t *testing.T) {
+	server := NewServer(8080)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8080"+mcpEndpoint, nil)
+	req.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	server.routes().ServeHTTP(w, req)
+
+	if w.Code != http.StatusNotAcceptable {
+		t.Errorf("Expected status 406, got %d", w.Code)
+	}
+}
+
+// TestStreamableHTTPGetStream tests delivery of server-initiated messages over GET
+func TestStreamableHTTPGetStream(t *testing.T) {
+	server := NewServer(8080)
+	ts := httptest.NewServer(server.routes())
+	defer ts.Close()
+
+	id := initSession(t, server)
+	hs, _, _ := server.sessions.get(id)
+
+	req, err := http.NewRequest(http.MethodGet, ts.URL+mcpEndpoint, nil)
+	if err != nil {
+		t.Fatalf("Failed to create request: %v", err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set(sessionIDHeader, id)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET stream failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("Expected Content-Type text/event-stream, got %s", ct)
+	}
+
+	// Wait for the stream to register before publishing
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if hs.streams.open() > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("SSE stream was never registered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	hs.streams.publish([]byte(`{"jsonrpc":"2.0","method":"notifications/tools/list_changed"}`))
+
+	reader := bufio.NewReader(resp.Body)
+	var lines []string
+	for len(lines) < 2 {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read SSE stream: %v", err)
+		}
+		lines = append(lines, strings.TrimSpace(line))
+	}
+
+	if lines[0] != "event: message" {
+		t.Errorf("Expected message event, got %q", lines[0])
+	}
+	if !strings.Contains(lines[1], "notifications/tools/list_changed") {
+		t.Errorf("Expected published notification, got %q", lines[1])
+	}
+}
+
+// TestLegacyRoutes tests that /mcp/v1/* routes are only served when enabled
+func TestLegacyRoutes(t *testing.T) {
+	server := NewServer(8080)
+
+	req := httptest.NewRequest(http.MethodGet, "http://localhost:8080/mcp/v1/tools/list", nil)
+	w := httptest.NewRecorder()
+	server.routes().ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected legacy route to be disabled by default, got status %d", w.Code)
+	}
+
+	server.EnableLegacyRoutes()
+
+	w = httptest.NewRecorder()
+	server.routes().ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected legacy route to succeed when enabled, got status %d", w.Code)
+	}
+}
+
+// TestAcceptsMediaType tests Accept header parsing
+func TestAcceptsMediaType(t *testing.T) {
+	tests := []struct {
+		accept    string
+		mediaType string
+		expected  bool
+	}{
+		{"application/json, text/event-stream", "text/event-stream", true},
+		{"application/json;q=0.9", "application/json", true},
+		{"text/event-stream", "application/json", false},
+		{"*/*", "text/event-stream", true},
+		{"", "application/json", false},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, mcpEndpoint, nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		if got := acceptsMediaType(req, tt.mediaType); got != tt.expected {
+			t.Errorf("acceptsMediaType(%q, %q) = %v, want %v", tt.accept, tt.mediaType, got, tt.expected)
+		}
+	}
+}
+
+// TestStreamableHTTPBatch tests batch requests over the MCP endpoint
+func TestStreamableHTTPBatch(t *testing.T) {
+	server := NewServer(8080)
+
+	resp := postMCP(t, server, `[{"jsonrpc":"2.0","id":1,"method":"ping"},{"jsonrpc":"2.0","method":"notifications/initialized"}]`, "application/json, text/event-stream")
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+
+	var responses []map[string]interface{}
+	body, _ := io.ReadAll(resp.Body)
+	if err := json.Unmarshal(body, &responses); err != nil {
+		t.Fatalf("Failed to unmarshal batch reply: %v", err)
+	}
+	if len(responses) != 1 {
+		t.Errorf("Expected 1 response, got %d", len(responses))
+	}
+
+	notifications := postMCP(t, server, `[{"jsonrpc":"2.0","method":"notifications/initialized"}]`, "application/json")
+	defer notifications.Body.Close()
+	if notifications.StatusCode != http.StatusAccepted {
+		t.Errorf("Expected status 202 for notification-only batch, got %d", notifications.StatusCode)
+	}
+}
@@ -28,11 +28,13 @@ func ProcessTemplate(workflowContent string, data TemplateData) (string, error)
 		Funcs(templateFuncs()).
 		Parse(workflowContent)
 	if err != nil {
+		metrics.renderFailures.inc(data.CommandName, "parse")
 		return "", enhanceTemplateError("parse", err)
 	}
 
 	var buf bytes.Buffer
 	if err := tmpl.Execute(&buf, data); err != nil {
+		metrics.renderFailures.inc(data.CommandName, "execute")
 		return "", enhanceTemplateError("execute", err)
 	}
 
@@ -105,11 +107,13 @@ func ProcessTemplateWithContext(workflowContent string, data TemplateData) (stri
 		Funcs(funcs).
 		Parse(workflowContent)
 	if err != nil {
+		metrics.renderFailures.inc(data.CommandName, "parse")
 		return "", enhanceTemplateError("parse", err)
 	}
 
 	var buf bytes.Buffer
 	if err := tmpl.Execute(&buf, data); err != nil {
+		metrics.renderFailures.inc(data.CommandName, "execute")
 		return "", enhanceTemplateError("execute", err)
 	}
 
@@ -0,0 +1,155 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// ToolResultBuilder assembles a CallToolResult block by block
+type ToolResultBuilder struct {
+	result CallToolResult
+}
+
+// NewToolResult starts an empty tool result
+func NewToolResult() *ToolResultBuilder {
+	return &ToolResultBuilder{
+		result: CallToolResult{Content: []Content{}},
+	}
+}
+
+// Text appends a text block
+func (b *ToolResultBuilder) Text(text string) *ToolResultBuilder {
+	b.result.Content = append(b.result.Content, TextContent(text))
+	return b
+}
+
+// Textf appends a formatted text block
+func (b *ToolResultBuilder) Textf(format string, args ...interface{}) *ToolResultBuilder {
+	return b.Text(fmt.Sprintf(format, args...))
+}
+
+// Resource appends a block embedding the given resource contents
+func (b *ToolResultBuilder) Resource(contents ResourceContents) *ToolResultBuilder {
+	b.result.Content = append(b.result.Content, EmbeddedResourceContent(contents))
+	return b
+}
+
+// ResourceLink appends a block linking to a resource the client can read on demand
+func (b *ToolResultBuilder) ResourceLink(resource Resource) *ToolResultBuilder {
+	b.result.Content = append(b.result.Content, ResourceLinkContent(resource))
+	return b
+}
+
+// Structured sets the structured content of the result and appends its JSON
+// encoding as a text block for clients that only read content. Values that do
+// not encode to a JSON object are added as text only, since structuredContent
+// must be an object.
+func (b *ToolResultBuilder) Structured(value interface{}) *ToolResultBuilder {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return b.Textf("failed to encode tool result: %v", err).Error()
+	}
+
+	if len(data) > 0 && data[0] == '{' {
+		b.result.StructuredContent = json.RawMessage(data)
+	}
+	return b.Text(string(data))
+}
+
+// Error marks the result as a tool execution failure
+func (b *ToolResultBuilder) Error() *ToolResultBuilder {
+	b.result.IsError = true
+	return b
+}
+
+// Build returns the assembled result
+func (b *ToolResultBuilder) Build() *CallToolResult {
+	result := b.result
+	return &result
+}
+
+// TextResult creates a result holding a single text block
+func TextResult(text string) *CallToolResult {
+	return NewToolResult().Text(text).Build()
+}
+
+// StructuredResult creates a result holding value as structured content
+func StructuredResult(value interface{}) *CallToolResult {
+	return NewToolResult().Structured(value).Build()
+}
+
+// ErrorResult creates a failed result describing err
+func ErrorResult(err error) *CallToolResult {
+	return NewToolResult().Text(err.Error()).Error().Build()
+}
+
+// resultText joins the text blocks of a result
+func resultText(result *CallToolResult) string {
+	var texts []string
+	for _, block := range result.Content {
+		if block.Type == ContentTypeText {
+			texts = append(texts, block.Text)
+		}
+	}
+	return strings.Join(texts, "\n")
+}
+
+// toToolResult converts whatever a tool handler returned into a CallToolResult.
+// Strings become text blocks and any other value becomes structured content.
+func toToolResult(value interface{}) *CallToolResult {
+	switch v := value.(type) {
+	case *CallToolResult:
+		if v == nil {
+			return NewToolResult().Build()
+		}
+		return v
+	case CallToolResult:
+		return &v
+	case nil:
+		return NewToolResult().Build()
+	case string:
+		return TextResult(v)
+	default:
+		return StructuredResult(v)
+	}
+}
+
+// validateToolOutput checks a successful result against the tool's output schema
+func validateToolOutput(tool Tool, result *CallToolResult) error {
+	if tool.OutputSchema == nil || result.IsError {
+		return nil
+	}
+	if result.StructuredContent == nil {
+		return fmt.Errorf("%w: %s declares an output schema but returned no structured content",
+			ErrInvalidToolOutput, tool.Name)
+	}
+	if err := ValidateSchema(tool.OutputSchema, result.StructuredContent, "structuredContent"); err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidToolOutput, tool.Name, err)
+	}
+	return nil
+}
+
+// adaptToolResult strips the parts of a result that the negotiated protocol
+// version does not know about: structured content is dropped, leaving its text
+// encoding, and resource links are rewritten as text
+func adaptToolResult(result *CallToolResult, features ProtocolFeatures) *CallToolResult {
+	if features.StructuredToolOutput && features.ResourceLinks {
+		return result
+	}
+
+	adapted := *result
+	if !features.StructuredToolOutput {
+		adapted.StructuredContent = nil
+	}
+	if !features.ResourceLinks {
+		adapted.Content = make([]Content, len(result.Content))
+		for i, block := range result.Content {
+			if block.Type == ContentTypeResourceLink {
+				block = TextContent(fmt.Sprintf("Resource: %s (%s)", block.Name, block.URI))
+			}
+			adapted.Content[i] = block
+		}
+	}
+	return &adapted
+}
@@ -0,0 +1,168 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// TestToolResultBuilder tests assembling results from content blocks
+func TestToolResultBuilder(t *testing.T) {
+	result := NewToolResult().
+		Text("Created spec").
+		Resource(ResourceContents{URI: "file:///spec.md", MimeType: "text/markdown", Text: "# Spec"}).
+		ResourceLink(Resource{URI: "file:///plan.md", Name: "plan.md", MimeType: "text/markdown"}).
+		Structured(map[string]interface{}{"feature": "001-auth"}).
+		Build()
+
+	expectedTypes := []string{ContentTypeText, ContentTypeResource, ContentTypeResourceLink, ContentTypeText}
+	if len(result.Content) != len(expectedTypes) {
+		t.Fatalf("Expected %d blocks, got %d", len(expectedTypes), len(result.Content))
+	}
+	for i, expected := range expectedTypes {
+		if result.Content[i].Type != expected {
+			t.Errorf("Block %d: expected type %s, got %s", i, expected, result.Content[i].Type)
+		}
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		t.Fatalf("Failed to marshal result: %v", err)
+	}
+
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Failed to unmarshal result: %v", err)
+	}
+	structured, ok := decoded["structuredContent"].(map[string]interface{})
+	if !ok || structured["feature"] != "001-auth" {
+		t.Errorf("Unexpected structuredContent: %v", decoded["structuredContent"])
+	}
+	if _, ok := decoded["isError"]; ok {
+		t.Error("isError should be omitted for successful results")
+	}
+
+	link := decoded["content"].([]interface{})[2].(map[string]interface{})
+	if link["uri"] != "file:///plan.md" || link["name"] != "plan.md" {
+		t.Errorf("Unexpected resource link: %v", link)
+	}
+}
+
+// TestToToolResult tests conversion of raw handler return values
+func TestToToolResult(t *testing.T) {
+	tests := []struct {
+		name          string
+		value         interface{}
+		expectBlocks  int
+		expectStruct  bool
+		expectedFirst string
+	}{
+		{"Nil", nil, 0, false, ""},
+		{"String", "hello", 1, false, "hello"},
+		{"Map", map[string]interface{}{"a": 1}, 1, true, `{"a":1}`},
+		{"Slice", []string{"a", "b"}, 1, false, `["a","b"]`},
+		{"Result", TextResult("built"), 1, false, "built"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := toToolResult(tt.value)
+			if len(result.Content) != tt.expectBlocks {
+				t.Fatalf("Expected %d blocks, got %d", tt.expectBlocks, len(result.Content))
+			}
+			if (result.StructuredContent != nil) != tt.expectStruct {
+				t.Errorf("Expected structured content %v, got %v", tt.expectStruct, result.StructuredContent)
+			}
+			if tt.expectBlocks > 0 && result.Content[0].Text != tt.expectedFirst {
+				t.Errorf("Expected first block %q, got %q", tt.expectedFirst, result.Content[0].Text)
+			}
+		})
+	}
+}
+
+// TestCallToolOutputSchema tests validation of structured content against a tool's output schema
+func TestCallToolOutputSchema(t *testing.T) {
+	h := NewHandler()
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": map[string]interface{}{"count": map[string]interface{}{"type": "integer"}},
+		"required":   []string{"count"},
+	}
+
+	register := func(name string, value interface{}, err error) {
+		h.RegisterTool(Tool{
+			Name:         name,
+			InputSchema:  map[string]interface{}{"type": "object"},
+			OutputSchema: schema,
+			Handler: func(ctx context.Context, args map[string]interface{}) (interface{}, error) {
+				return value, err
+			},
+		})
+	}
+	register("valid", map[string]interface{}{"count": 3}, nil)
+	register("wrong_type", map[string]interface{}{"count": "three"}, nil)
+	register("unstructured", "just text", nil)
+	register("failing", nil, errors.New("boom"))
+
+	if _, err := h.CallTool("valid", nil); err != nil {
+		t.Errorf("Expected valid output to pass, got %v", err)
+	}
+	if _, err := h.CallTool("wrong_type", nil); !errors.Is(err, ErrInvalidToolOutput) {
+		t.Errorf("Expected ErrInvalidToolOutput for wrong type, got %v", err)
+	}
+	if _, err := h.CallTool("unstructured", nil); !errors.Is(err, ErrInvalidToolOutput) {
+		t.Errorf("Expected ErrInvalidToolOutput for missing structured content, got %v", err)
+	}
+
+	// Failed calls are not held to the output schema
+	result, err := h.CallTool("failing", nil)
+	if err != nil {
+		t.Fatalf("Expected in-band failure, got %v", err)
+	}
+	if !result.IsError || resultText(result) != "boom" {
+		t.Errorf("Unexpected failure result: %#v", result)
+	}
+
+	// Invalid output surfaces as an internal error over JSON-RPC
+	d := NewDispatcher(h)
+	resp := d.Handle(context.Background(), newTestRequest(t, 1, "tools/call", CallToolParams{Name: "wrong_type"}))
+	if resp.Error == nil || resp.Error.Code != CodeInternalError {
+		t.Errorf("Expected internal error for invalid output, got %#v", resp)
+	}
+}
+
+// TestAdaptToolResult tests downgrading results for older protocol versions
+func TestAdaptToolResult(t *testing.T) {
+	result := NewToolResult().
+		ResourceLink(Resource{URI: "file:///plan.md", Name: "plan.md"}).
+		Structured(map[string]interface{}{"ok": true}).
+		Build()
+
+	latest := adaptToolResult(result, protocolFeatures[LatestProtocolVersion])
+	if latest != result {
+		t.Error("Expected the latest protocol to receive the result unchanged")
+	}
+
+	old := adaptToolResult(result, protocolFeatures["2024-11-05"])
+	if old.StructuredContent != nil {
+		t.Error("Expected structured content to be dropped for 2024-11-05")
+	}
+	if old.Content[0].Type != ContentTypeText || old.Content[0].Text != "Resource: plan.md (file:///plan.md)" {
+		t.Errorf("Expected resource link rewritten as text, got %#v", old.Content[0])
+	}
+	if result.Content[0].Type != ContentTypeResourceLink || result.StructuredContent == nil {
+		t.Error("Adapting must not modify the original result")
+	}
+
+	// tools/list hides output schemas from clients that cannot use them
+	d := NewDispatcher(NewHandler())
+	session := NewSession()
+	ctx := ContextWithSession(context.Background(), session)
+	resp := d.Handle(ctx, newTestRequest(t, 1, "tools/list", nil))
+	for _, tool := range resp.Result.(*ListToolsResult).Tools {
+		if tool.OutputSchema != nil {
+			t.Errorf("Expected no output schema for %s before negotiation", tool.Name)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package mcp
+
+// withUmask runs fn; platforms without a umask leave file modes to the caller
+func withUmask(mask int, fn func()) {
+	fn()
+}
@@ -0,0 +1,12 @@
+//go:build unix
+
+package mcp
+
+import "syscall"
+
+// withUmask runs fn with the process umask set to mask
+func withUmask(mask int, fn func()) {
+	previous := syscall.Umask(mask)
+	defer syscall.Umask(previous)
+	fn()
+}
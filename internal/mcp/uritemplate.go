@@ -0,0 +1,203 @@
+package mcp
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// URITemplate is a parsed RFC 6570 URI template. Simple string expansion
+// ({var}), reserved expansion ({+var}) and fragment expansion ({#var}) are
+// supported, each with one or more comma-separated variables.
+type URITemplate struct {
+	raw       string
+	parts     []templatePart
+	variables []string
+	pattern   *regexp.Regexp
+	literals  int
+}
+
+// templatePart is either a literal or an expression of a URI template
+type templatePart struct {
+	literal  string
+	operator byte // 0 for simple expansion, '+' or '#'
+	names    []string
+}
+
+// Patterns matching the characters each expansion may produce
+const (
+	unreservedPattern = `(?:[A-Za-z0-9\-._~]|%[0-9A-Fa-f]{2})`
+	reservedPattern   = `(?:[A-Za-z0-9\-._~:/?#\[\]@!$&'()*+,;=]|%[0-9A-Fa-f]{2})`
+)
+
+// varnamePattern matches a variable name as defined by RFC 6570
+var varnamePattern = regexp.MustCompile(`^(?:[A-Za-z0-9_]|%[0-9A-Fa-f]{2})(?:\.?(?:[A-Za-z0-9_]|%[0-9A-Fa-f]{2}))*$`)
+
+// ParseURITemplate parses an RFC 6570 URI template
+func ParseURITemplate(raw string) (*URITemplate, error) {
+	t := &URITemplate{raw: raw}
+	var pattern strings.Builder
+	pattern.WriteString("^")
+
+	rest := raw
+	for rest != "" {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			start = len(rest)
+		}
+		if literal := rest[:start]; literal != "" {
+			if strings.ContainsRune(literal, '}') {
+				return nil, fmt.Errorf("invalid URI template %q: unmatched '}'", raw)
+			}
+			t.parts = append(t.parts, templatePart{literal: literal})
+			t.literals += len(literal)
+			pattern.WriteString(regexp.QuoteMeta(literal))
+		}
+		if start == len(rest) {
+			break
+		}
+
+		end := strings.IndexByte(rest[start:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("invalid URI template %q: unterminated expression", raw)
+		}
+		part, err := parseExpression(rest[start+1 : start+end])
+		if err != nil {
+			return nil, fmt.Errorf("invalid URI template %q: %w", raw, err)
+		}
+		t.parts = append(t.parts, part)
+		t.variables = append(t.variables, part.names...)
+		pattern.WriteString(part.pattern())
+
+		rest = rest[start+end+1:]
+	}
+
+	pattern.WriteString("$")
+	t.pattern = regexp.MustCompile(pattern.String())
+	return t, nil
+}
+
+// parseExpression parses the text between braces
+func parseExpression(expression string) (templatePart, error) {
+	var part templatePart
+	if expression != "" && (expression[0] == '+' || expression[0] == '#') {
+		part.operator = expression[0]
+		expression = expression[1:]
+	}
+
+	for _, name := range strings.Split(expression, ",") {
+		if !varnamePattern.MatchString(name) {
+			return part, fmt.Errorf("unsupported expression {%s}", expression)
+		}
+		part.names = append(part.names, name)
+	}
+	return part, nil
+}
+
+// pattern returns the regular expression matching the expansion of an expression
+func (p templatePart) pattern() string {
+	chars := unreservedPattern
+	if p.operator != 0 {
+		chars = reservedPattern
+	}
+
+	groups := make([]string, len(p.names))
+	for i := range p.names {
+		groups[i] = "(" + chars + "+)"
+	}
+
+	prefix := ""
+	if p.operator == '#' {
+		prefix = "#"
+	}
+	return prefix + strings.Join(groups, ",")
+}
+
+// String returns the template as it was written
+func (t *URITemplate) String() string {
+	return t.raw
+}
+
+// Variables returns the names of the template's variables in order
+func (t *URITemplate) Variables() []string {
+	return t.variables
+}
+
+// Match reports whether uri is an expansion of the template and returns the
+// decoded value of each variable. Every variable must match at least one character.
+func (t *URITemplate) Match(uri string) (map[string]string, bool) {
+	groups := t.pattern.FindStringSubmatch(uri)
+	if groups == nil {
+		return nil, false
+	}
+
+	values := make(map[string]string, len(t.variables))
+	for i, name := range t.variables {
+		value, err := url.PathUnescape(groups[i+1])
+		if err != nil {
+			return nil, false
+		}
+		values[name] = value
+	}
+	return values, true
+}
+
+// Expand substitutes values into the template. Variables without a value are
+// left out, as RFC 6570 specifies for undefined variables.
+func (t *URITemplate) Expand(values map[string]string) string {
+	var b strings.Builder
+	for _, part := range t.parts {
+		if part.names == nil {
+			b.WriteString(part.literal)
+			continue
+		}
+
+		var expanded []string
+		for _, name := range part.names {
+			if value, ok := values[name]; ok {
+				expanded = append(expanded, encodeTemplateValue(value, part.operator != 0))
+			}
+		}
+		if len(expanded) == 0 {
+			continue
+		}
+		if part.operator == '#' {
+			b.WriteByte('#')
+		}
+		b.WriteString(strings.Join(expanded, ","))
+	}
+	return b.String()
+}
+
+// encodeTemplateValue percent-encodes a value for expansion. Reserved
+// expansion keeps reserved characters and existing percent-encoded triples.
+func encodeTemplateValue(value string, reserved bool) string {
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		switch {
+		case isUnreserved(c):
+			b.WriteByte(c)
+		case reserved && strings.IndexByte(":/?#[]@!$&'()*+,;=", c) >= 0:
+			b.WriteByte(c)
+		case reserved && c == '%' && i+2 < len(value) && isHex(value[i+1]) && isHex(value[i+2]):
+			b.WriteString(value[i : i+3])
+			i += 2
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+// isUnreserved reports whether c is an RFC 3986 unreserved character
+func isUnreserved(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' ||
+		c == '-' || c == '.' || c == '_' || c == '~'
+}
+
+// isHex reports whether c is a hexadecimal digit
+func isHex(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'A' && c <= 'F' || c >= 'a' && c <= 'f'
+}
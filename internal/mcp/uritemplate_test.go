@@ -0,0 +1,96 @@
+package mcp
+
+import (
+	"testing"
+)
+
+// TestURITemplateMatch tests extracting variables from URIs
+func TestURITemplateMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		uri      string
+		expected map[string]string
+	}{
+		{"Simple", "tchncrt://features/{feature}/{artifact}", "tchncrt://features/001-auth/spec", map[string]string{"feature": "001-auth", "artifact": "spec"}},
+		{"Simple rejects slash", "tchncrt://features/{feature}", "tchncrt://features/001-auth/spec", nil},
+		{"Simple decodes", "tchncrt://notes/{title}", "tchncrt://notes/hello%20world", map[string]string{"title": "hello world"}},
+		{"Empty value", "tchncrt://features/{feature}/spec", "tchncrt://features//spec", nil},
+		{"Reserved", "tchncrt://files/{+path}", "tchncrt://files/a/b/c.json", map[string]string{"path": "a/b/c.json"}},
+		{"Fragment", "tchncrt://doc{#section}", "tchncrt://doc#intro", map[string]string{"section": "intro"}},
+		{"Multiple variables", "tchncrt://range/{from,to}", "tchncrt://range/1,5", map[string]string{"from": "1", "to": "5"}},
+		{"Literal mismatch", "tchncrt://features/{feature}", "tchncrt://memory/constitution", nil},
+		{"Literal only", "tchncrt://memory/constitution", "tchncrt://memory/constitution", map[string]string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			template, err := ParseURITemplate(tt.template)
+			if err != nil {
+				t.Fatalf("ParseURITemplate failed: %v", err)
+			}
+
+			values, ok := template.Match(tt.uri)
+			if tt.expected == nil {
+				if ok {
+					t.Errorf("Expected no match, got %v", values)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("Expected %s to match %s", tt.uri, tt.template)
+			}
+			if len(values) != len(tt.expected) {
+				t.Errorf("Expected %v, got %v", tt.expected, values)
+			}
+			for name, value := range tt.expected {
+				if values[name] != value {
+					t.Errorf("Expected %s=%q, got %q", name, value, values[name])
+				}
+			}
+		})
+	}
+}
+
+// TestURITemplateExpand tests substituting values into templates
+func TestURITemplateExpand(t *testing.T) {
+	tests := []struct {
+		template string
+		values   map[string]string
+		expected string
+	}{
+		{"tchncrt://features/{feature}/{artifact}", map[string]string{"feature": "001-auth", "artifact": "spec"}, "tchncrt://features/001-auth/spec"},
+		{"tchncrt://notes/{title}", map[string]string{"title": "a/b c"}, "tchncrt://notes/a%2Fb%20c"},
+		{"tchncrt://files/{+path}", map[string]string{"path": "a/b c%20d"}, "tchncrt://files/a/b%20c%20d"},
+		{"tchncrt://doc{#section}", map[string]string{}, "tchncrt://doc"},
+		{"tchncrt://doc{#section}", map[string]string{"section": "intro"}, "tchncrt://doc#intro"},
+		{"tchncrt://range/{from,to}", map[string]string{"to": "5"}, "tchncrt://range/5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.template, func(t *testing.T) {
+			template, err := ParseURITemplate(tt.template)
+			if err != nil {
+				t.Fatalf("ParseURITemplate failed: %v", err)
+			}
+			if expanded := template.Expand(tt.values); expanded != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, expanded)
+			}
+		})
+	}
+}
+
+// TestParseURITemplateErrors tests rejecting malformed and unsupported templates
+func TestParseURITemplateErrors(t *testing.T) {
+	for _, raw := range []string{
+		"tchncrt://{feature",
+		"tchncrt://feature}",
+		"tchncrt://{}",
+		"tchncrt://{?query}",
+		"tchncrt://{a b}",
+	} {
+		if _, err := ParseURITemplate(raw); err == nil {
+			t.Errorf("Expected an error for %q", raw)
+		}
+	}
+}
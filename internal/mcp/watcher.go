@@ -0,0 +1,322 @@
+package mcp
+
+import (
+	"context"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fileState is what the watcher compares between polls to detect a change
+type fileState struct {
+	size    int64
+	modTime time.Time
+}
+
+// resourceChanges are the changes found by the watcher since it last reported
+type resourceChanges struct {
+	updated     []string // URIs of resources modified, created or removed
+	listChanged bool     // Whether resources were created or removed
+}
+
+// resourceWatcher polls specs/ and memory/ for changes to the files served as
+// resources. Polling works the same on every platform and filesystem, and the
+// workspace is small enough that a stat of every file is cheap.
+type resourceWatcher struct {
+	files    func() map[string]fileState
+	interval time.Duration
+	debounce time.Duration
+}
+
+// newResourceWatcher creates a watcher over the workspace of the session's
+// client, or over the handler's workspace when session is nil. It follows the
+// session's workspace, so when the client's roots point it at another project
+// the whole resource list is reported as changed.
+func newResourceWatcher(handler *Handler, session *Session, interval, debounce time.Duration) *resourceWatcher {
+	return &resourceWatcher{
+		files:    func() map[string]fileState { return handler.sessionWorkspace(session).files() },
+		interval: interval,
+		debounce: debounce,
+	}
+}
+
+// sharedWatchers runs one resource watcher per workspace for the HTTP sessions
+// subscribed to resources, so sessions of the same project share its polling.
+// A workspace's watcher starts with its first subscribing session and stops
+// when its last one ends or moves to another workspace.
+type sharedWatchers struct {
+	ctx      context.Context
+	handler  *Handler
+	interval time.Duration
+	debounce time.Duration
+	publish  func(*Session, resourceChanges)
+
+	mu       sync.Mutex
+	watchers map[string]*workspaceWatcher // By workspace root
+	roots    map[*Session]string          // Root each watching session is registered under
+}
+
+// workspaceWatcher is the running watcher of one workspace and its sessions
+type workspaceWatcher struct {
+	cancel   context.CancelFunc
+	sessions map[*Session]struct{}
+}
+
+// newSharedWatchers creates the watchers of an HTTP server; they stop with ctx
+func newSharedWatchers(ctx context.Context, handler *Handler, interval, debounce time.Duration, publish func(*Session, resourceChanges)) *sharedWatchers {
+	return &sharedWatchers{
+		ctx:      ctx,
+		handler:  handler,
+		interval: interval,
+		debounce: debounce,
+		publish:  publish,
+		watchers: make(map[string]*workspaceWatcher),
+		roots:    make(map[*Session]string),
+	}
+}
+
+// watch registers the session with the watcher of its workspace, starting the
+// watcher if it is the workspace's first session
+func (w *sharedWatchers) watch(session *Session) {
+	root := w.handler.sessionWorkspace(session).Root
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if previous, ok := w.roots[session]; ok {
+		if previous == root {
+			return
+		}
+		w.remove(session, previous)
+	}
+	w.add(session, root)
+}
+
+// moved re-registers a watching session whose workspace may have changed with
+// the client's roots, reporting every resource as changed when it did
+func (w *sharedWatchers) moved(session *Session) {
+	root := w.handler.sessionWorkspace(session).Root
+
+	w.mu.Lock()
+	previous, ok := w.roots[session]
+	if !ok || previous == root {
+		w.mu.Unlock()
+		return
+	}
+	w.remove(session, previous)
+	w.add(session, root)
+	w.mu.Unlock()
+
+	updated, listChanged := diffFileStates(Workspace{Root: previous}.files(), Workspace{Root: root}.files())
+	w.publish(session, resourceChanges{updated: updated, listChanged: listChanged || len(updated) > 0})
+}
+
+// unwatch removes a session that ended, stopping its workspace's watcher when
+// no other session uses it
+func (w *sharedWatchers) unwatch(session *Session) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if root, ok := w.roots[session]; ok {
+		w.remove(session, root)
+	}
+}
+
+// active returns the number of running watchers
+func (w *sharedWatchers) active() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.watchers)
+}
+
+// add registers a session under root. w.mu must be held.
+func (w *sharedWatchers) add(session *Session, root string) {
+	w.roots[session] = root
+	if watcher, ok := w.watchers[root]; ok {
+		watcher.sessions[session] = struct{}{}
+		return
+	}
+
+	ctx, cancel := context.WithCancel(w.ctx)
+	watcher := &workspaceWatcher{cancel: cancel, sessions: map[*Session]struct{}{session: {}}}
+	w.watchers[root] = watcher
+
+	workspace := Workspace{Root: root}
+	poller := &resourceWatcher{files: workspace.files, interval: w.interval, debounce: w.debounce}
+	go poller.run(ctx, func(changes resourceChanges) {
+		for _, session := range w.sessionsOf(watcher) {
+			w.publish(session, changes)
+		}
+	})
+}
+
+// remove unregisters a session from root, stopping the watcher with its last
+// session. w.mu must be held.
+func (w *sharedWatchers) remove(session *Session, root string) {
+	delete(w.roots, session)
+	watcher, ok := w.watchers[root]
+	if !ok {
+		return
+	}
+	delete(watcher.sessions, session)
+	if len(watcher.sessions) == 0 {
+		watcher.cancel()
+		delete(w.watchers, root)
+	}
+}
+
+// sessionsOf returns the sessions registered with a watcher
+func (w *sharedWatchers) sessionsOf(watcher *workspaceWatcher) []*Session {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	sessions := make([]*Session, 0, len(watcher.sessions))
+	for session := range watcher.sessions {
+		sessions = append(sessions, session)
+	}
+	return sessions
+}
+
+// run polls until ctx is done. Changes are collected until the workspace has
+// been quiet for the debounce period and then passed to report together, so a
+// burst of edits produces a single round of notifications.
+func (w *resourceWatcher) run(ctx context.Context, report func(resourceChanges)) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	previous := w.files()
+	pending := make(map[string]struct{})
+	listChanged := false
+
+	// quiet fires once no change has been seen for the debounce period
+	quiet := time.NewTimer(w.debounce)
+	quiet.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			quiet.Stop()
+			return
+
+		case <-ticker.C:
+			current := w.files()
+			updated, changedList := diffFileStates(previous, current)
+			previous = current
+			if len(updated) == 0 {
+				continue
+			}
+
+			for _, uri := range updated {
+				pending[uri] = struct{}{}
+			}
+			listChanged = listChanged || changedList
+			quiet.Reset(w.debounce)
+
+		case <-quiet.C:
+			changes := resourceChanges{listChanged: listChanged}
+			for uri := range pending {
+				changes.updated = append(changes.updated, uri)
+			}
+			sort.Strings(changes.updated)
+			pending = make(map[string]struct{})
+			listChanged = false
+
+			report(changes)
+		}
+	}
+}
+
+// diffFileStates returns the URIs whose files differ between two polls and
+// whether any were created or removed
+func diffFileStates(previous, current map[string]fileState) ([]string, bool) {
+	var updated []string
+	listChanged := false
+
+	for uri, state := range current {
+		old, existed := previous[uri]
+		if !existed {
+			listChanged = true
+		}
+		if !existed || old != state {
+			updated = append(updated, uri)
+		}
+	}
+	for uri := range previous {
+		if _, exists := current[uri]; !exists {
+			listChanged = true
+			updated = append(updated, uri)
+		}
+	}
+	return updated, listChanged
+}
+
+// files returns the state of every workspace file served as a resource, keyed by URI
+func (w Workspace) files() map[string]fileState {
+	files := make(map[string]fileState)
+	if w.Root == "" {
+		return files
+	}
+
+	for _, dir := range []string{"specs", "memory"} {
+		filepath.WalkDir(filepath.Join(w.Root, dir), func(p string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return nil
+			}
+			rel, err := filepath.Rel(w.Root, p)
+			if err != nil {
+				return nil
+			}
+			uri := workspaceFileURI(filepath.ToSlash(rel))
+			if uri == "" {
+				return nil
+			}
+			if info, err := d.Info(); err == nil {
+				files[uri] = fileState{size: info.Size(), modTime: info.ModTime()}
+			}
+			return nil
+		})
+	}
+	return files
+}
+
+// workspaceFileURI returns the resource URI of a file given by its slash-separated
+// path relative to the workspace root, or "" when the file is not a resource
+func workspaceFileURI(rel string) string {
+	parts := strings.Split(rel, "/")
+	switch {
+	case len(parts) == 2 && parts[0] == "memory" && path.Ext(parts[1]) == ".md":
+		return memoryURI(strings.TrimSuffix(parts[1], ".md"))
+	case len(parts) == 3 && parts[0] == "specs" && path.Ext(parts[2]) == ".md" &&
+		isFeatureDocument(strings.TrimSuffix(parts[2], ".md")):
+		return featureURI(parts[1], strings.TrimSuffix(parts[2], ".md"))
+	case len(parts) >= 4 && parts[0] == "specs" && parts[2] == "contracts":
+		return featureURI(parts[1], "contracts/"+strings.Join(parts[3:], "/"))
+	}
+	return ""
+}
+
+// publishResourceChanges notifies every attached session of resource changes
+func (d *Dispatcher) publishResourceChanges(changes resourceChanges) {
+	for _, session := range d.attachedSessions() {
+		d.publishSessionChanges(session, changes)
+	}
+}
+
+// publishSessionChanges notifies a session of resource changes: always when the
+// list changed, and for each updated resource it subscribed to
+func (d *Dispatcher) publishSessionChanges(session *Session, changes resourceChanges) {
+	if changes.listChanged {
+		if err := session.notify("notifications/resources/list_changed", nil); err != nil {
+			logger.Warn("Failed to send resource list change", "error", err)
+		}
+	}
+	for _, uri := range changes.updated {
+		if !session.Subscribed(uri) {
+			continue
+		}
+		if err := session.notify("notifications/resources/updated", ResourceUpdatedParams{URI: uri}); err != nil {
+			logger.Warn("Failed to send resource update", "uri", uri, "error", err)
+		}
+	}
+}
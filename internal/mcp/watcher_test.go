@@ -0,0 +1,354 @@
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// methodNotifier records the method of every notification sent through it
+type methodNotifier struct {
+	mu      sync.Mutex
+	methods []string
+}
+
+// Notify records the notification as "method" or "method uri"
+func (n *methodNotifier) Notify(method string, params interface{}) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if p, ok := params.(ResourceUpdatedParams); ok {
+		method += " " + p.URI
+	}
+	n.methods = append(n.methods, method)
+	return nil
+}
+
+// sent returns the recorded notifications
+func (n *methodNotifier) sent() []string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]string(nil), n.methods...)
+}
+
+// TestWorkspaceFileURI tests mapping workspace paths to resource URIs
+func TestWorkspaceFileURI(t *testing.T) {
+	tests := map[string]string{
+		"specs/001-auth/tasks.md":             "tchncrt://features/001-auth/tasks",
+		"specs/001-auth/contracts/api.yaml":   "tchncrt://features/001-auth/contracts/api.yaml",
+		"specs/001-auth/contracts/v1/ev.json": "tchncrt://features/001-auth/contracts/v1/ev.json",
+		"memory/constitution.md":              "tchncrt://memory/constitution",
+		"specs/001-auth/notes.md":             "",
+		"specs/README.md":                     "",
+		"memory/archive/old.md":               "",
+		"memory/notes.txt":                    "",
+	}
+
+	for rel, expected := range tests {
+		if uri := workspaceFileURI(rel); uri != expected {
+			t.Errorf("%s: expected %q, got %q", rel, expected, uri)
+		}
+	}
+}
+
+// TestDiffFileStates tests detecting modified, created and removed files
+func TestDiffFileStates(t *testing.T) {
+	now := time.Now()
+	previous := map[string]fileState{
+		"a": {size: 1, modTime: now},
+		"b": {size: 1, modTime: now},
+		"c": {size: 1, modTime: now},
+	}
+
+	updated, listChanged := diffFileStates(previous, map[string]fileState{
+		"a": {size: 1, modTime: now},
+		"b": {size: 2, modTime: now},
+		"c": {size: 1, modTime: now.Add(time.Second)},
+	})
+	sort.Strings(updated)
+	if strings.Join(updated, ",") != "b,c" || listChanged {
+		t.Errorf("Expected b and c modified without a list change, got %v, %v", updated, listChanged)
+	}
+
+	updated, listChanged = diffFileStates(previous, map[string]fileState{
+		"a": {size: 1, modTime: now},
+		"b": {size: 1, modTime: now},
+		"d": {size: 1, modTime: now},
+	})
+	sort.Strings(updated)
+	if strings.Join(updated, ",") != "c,d" || !listChanged {
+		t.Errorf("Expected c removed and d created, got %v, %v", updated, listChanged)
+	}
+}
+
+// TestResourceWatcherDebounce tests that a burst of edits is reported once
+func TestResourceWatcherDebounce(t *testing.T) {
+	root := newTestWorkspace(t)
+	h := NewHandler()
+	h.SetWorkspaceRoot(root)
+	watcher := newResourceWatcher(h, nil, 5*time.Millisecond, 50*time.Millisecond)
+
+	reports := make(chan resourceChanges, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watcher.run(ctx, func(changes resourceChanges) { reports <- changes })
+
+	// Let the watcher take its first snapshot
+	time.Sleep(20 * time.Millisecond)
+
+	tasks := filepath.Join(root, "specs", "001-auth", "tasks.md")
+	for i := 1; i <= 3; i++ {
+		if err := os.WriteFile(tasks, []byte(strings.Repeat("- [ ] task\n", i)), 0644); err != nil {
+			t.Fatalf("Failed to write tasks: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	changes := waitFor(t, reports, "change report")
+	if !changes.listChanged || strings.Join(changes.updated, ",") != "tchncrt://features/001-auth/tasks" {
+		t.Errorf("Expected one report for the new tasks file, got %+v", changes)
+	}
+	select {
+	case extra := <-reports:
+		t.Errorf("Expected the burst to be reported once, got another report %+v", extra)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "specs", "001-auth", "spec.md"), []byte("# Auth spec, revised"), 0644); err != nil {
+		t.Fatalf("Failed to write spec: %v", err)
+	}
+	changes = waitFor(t, reports, "change report")
+	if changes.listChanged || strings.Join(changes.updated, ",") != "tchncrt://features/001-auth/spec" {
+		t.Errorf("Expected an update to the spec only, got %+v", changes)
+	}
+}
+
+// TestResourceSubscriptions tests subscribing and the notifications sent to each session
+func TestResourceSubscriptions(t *testing.T) {
+	h := NewHandler()
+	h.SetWorkspaceRoot(newTestWorkspace(t))
+	d := NewDispatcher(h)
+
+	subscriber, other := NewSession(), NewSession()
+	subscriberNotes, otherNotes := &methodNotifier{}, &methodNotifier{}
+	subscriber.setNotifier(subscriberNotes)
+	other.setNotifier(otherNotes)
+	d.attachSession(subscriber)
+	d.attachSession(other)
+	ctx := ContextWithSession(context.Background(), subscriber)
+
+	resp := d.Handle(ctx, newTestRequest(t, 1, "resources/subscribe", SubscribeParams{URI: "tchncrt://unknown/thing"}))
+	if resp.Error == nil || resp.Error.Code != CodeResourceNotFound {
+		t.Errorf("Expected resource not found for an unknown URI, got %#v", resp)
+	}
+	resp = d.Handle(ctx, newTestRequest(t, 2, "resources/subscribe", SubscribeParams{}))
+	if resp.Error == nil || resp.Error.Code != CodeInvalidParams {
+		t.Errorf("Expected invalid params without a URI, got %#v", resp)
+	}
+
+	// Resources that do not exist yet can be subscribed to through their template
+	resp = d.Handle(ctx, newTestRequest(t, 3, "resources/subscribe", SubscribeParams{URI: "tchncrt://features/001-auth/tasks"}))
+	if resp.Error != nil {
+		t.Fatalf("Subscribe failed: %v", resp.Error)
+	}
+
+	d.publishResourceChanges(resourceChanges{
+		updated:     []string{"tchncrt://features/001-auth/spec", "tchncrt://features/001-auth/tasks"},
+		listChanged: true,
+	})
+	expected := "notifications/resources/list_changed,notifications/resources/updated tchncrt://features/001-auth/tasks"
+	if sent := strings.Join(subscriberNotes.sent(), ","); sent != expected {
+		t.Errorf("Expected %s for the subscriber, got %s", expected, sent)
+	}
+	if sent := strings.Join(otherNotes.sent(), ","); sent != "notifications/resources/list_changed" {
+		t.Errorf("Expected only the list change for the other session, got %s", sent)
+	}
+
+	d.Handle(ctx, newTestRequest(t, 4, "resources/unsubscribe", SubscribeParams{URI: "tchncrt://features/001-auth/tasks"}))
+	d.detachSession(other)
+	d.publishResourceChanges(resourceChanges{updated: []string{"tchncrt://features/001-auth/tasks"}})
+	if sent := subscriberNotes.sent(); len(sent) != 2 {
+		t.Errorf("Expected no update after unsubscribing, got %v", sent)
+	}
+	if sent := otherNotes.sent(); len(sent) != 1 {
+		t.Errorf("Expected no notifications after detaching, got %v", sent)
+	}
+}
+
+// TestStdioServerResourceUpdates tests that stdio clients hear about edits to subscribed resources
+func TestStdioServerResourceUpdates(t *testing.T) {
+	root := newTestWorkspace(t)
+	cfg := DefaultConfig()
+	cfg.WatchIntervalMs = 5
+	cfg.WatchDebounceMs = 10
+	server := NewStdioServerWithConfig(cfg)
+	server.handler.SetWorkspaceRoot(root)
+
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	served := make(chan error, 1)
+	go func() {
+		served <- server.serve(context.Background(), inReader, outWriter)
+		outWriter.Close()
+	}()
+	out := bufio.NewReader(outReader)
+
+	io.WriteString(inWriter, `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-06-18","capabilities":{},"clientInfo":{"name":"test","version":"1"}}}`+"\n")
+	line, _ := out.ReadString('\n')
+	capabilities := decodeReply(t, []byte(line))["result"].(map[string]interface{})["capabilities"].(map[string]interface{})
+	if resources := capabilities["resources"].(map[string]interface{}); resources["subscribe"] != true || resources["listChanged"] != true {
+		t.Errorf("Expected subscribe and listChanged capabilities, got %v", resources)
+	}
+
+	io.WriteString(inWriter, `{"jsonrpc":"2.0","id":2,"method":"resources/subscribe","params":{"uri":"tchncrt://features/001-auth/plan"}}`+"\n")
+	line, _ = out.ReadString('\n')
+	if response := decodeReply(t, []byte(line)); response["error"] != nil {
+		t.Fatalf("Subscribe failed: %s", line)
+	}
+
+	if err := os.WriteFile(filepath.Join(root, "specs", "001-auth", "plan.md"), []byte("# Auth plan, revised"), 0644); err != nil {
+		t.Fatalf("Failed to write plan: %v", err)
+	}
+
+	lines := make(chan string, 1)
+	go func() {
+		line, _ := out.ReadString('\n')
+		lines <- line
+	}()
+	line = waitFor(t, lines, "resource update")
+	notification := decodeReply(t, []byte(line))
+	if notification["method"] != "notifications/resources/updated" {
+		t.Fatalf("Expected a resource update, got %s", line)
+	}
+	if uri := notification["params"].(map[string]interface{})["uri"]; uri != "tchncrt://features/001-auth/plan" {
+		t.Errorf("Expected the plan URI, got %v", uri)
+	}
+
+	inWriter.Close()
+	if err := waitFor(t, served, "serve to return"); err != nil {
+		t.Errorf("serve returned error: %v", err)
+	}
+}
+
+// TestStreamableHTTPResourceUpdates tests that resource updates reach HTTP clients on their GET stream
+func TestStreamableHTTPResourceUpdates(t *testing.T) {
+	server := NewServer(8080)
+	server.handler.SetWorkspaceRoot(newTestWorkspace(t))
+	ts := httptest.NewServer(server.routes())
+	defer ts.Close()
+
+	id := initSession(t, server)
+	hs, _, _ := server.sessions.get(id)
+	resp := postMCPSession(t, server, id, `{"jsonrpc":"2.0","id":1,"method":"resources/subscribe","params":{"uri":"tchncrt://features/001-auth/spec"}}`, "application/json")
+	body, _ := io.ReadAll(resp.Body)
+	if response := decodeReply(t, body); response["error"] != nil {
+		t.Fatalf("Subscribe failed: %v", response["error"])
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+mcpEndpoint, nil)
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set(sessionIDHeader, id)
+	stream, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET stream failed: %v", err)
+	}
+	defer stream.Body.Close()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if hs.streams.open() > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("SSE stream was never registered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	server.dispatcher.publishResourceChanges(resourceChanges{updated: []string{"tchncrt://features/001-auth/spec"}})
+
+	reader := bufio.NewReader(stream.Body)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read SSE stream: %v", err)
+		}
+		if strings.HasPrefix(line, "data: ") {
+			if !strings.Contains(line, "notifications/resources/updated") || !strings.Contains(line, "001-auth/spec") {
+				t.Errorf("Expected a resource update, got %q", line)
+			}
+			break
+		}
+	}
+}
+
+// TestSharedResourceWatchers tests that HTTP sessions of the same workspace
+// share one watcher, started by the first subscribe and stopped with the last
+// session, and that its changes reach every subscribed session
+func TestSharedResourceWatchers(t *testing.T) {
+	root := newTestWorkspace(t)
+	cfg := DefaultConfig()
+	cfg.WatchIntervalMs = 10
+	cfg.WatchDebounceMs = 20
+	server := NewServerWithConfig(cfg)
+	server.handler.SetWorkspaceRoot(root)
+	defer server.cancelBase()
+
+	subscribe := `{"jsonrpc":"2.0","id":1,"method":"resources/subscribe","params":{"uri":"tchncrt://features/001-auth/spec"}}`
+	var sessions []*httpSession
+	var notifiers []*methodNotifier
+	for i := 0; i < 2; i++ {
+		id := initSession(t, server)
+		hs, _, _ := server.sessions.get(id)
+		notifier := &methodNotifier{}
+		hs.session.setNotifier(notifier)
+		sessions = append(sessions, hs)
+		notifiers = append(notifiers, notifier)
+
+		if i == 0 && server.dispatcher.watchers.active() != 0 {
+			t.Fatal("Expected no watcher before the first subscribe")
+		}
+		resp := postMCPSession(t, server, id, subscribe, "application/json")
+		resp.Body.Close()
+	}
+	if n := server.dispatcher.watchers.active(); n != 1 {
+		t.Fatalf("Expected one shared watcher, got %d", n)
+	}
+
+	// Let the watcher take its first snapshot before changing the spec
+	time.Sleep(50 * time.Millisecond)
+	spec := filepath.Join(root, "specs", "001-auth", "spec.md")
+	if err := os.WriteFile(spec, []byte("# Changed spec\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().Add(time.Minute)
+	os.Chtimes(spec, future, future)
+
+	want := "notifications/resources/updated tchncrt://features/001-auth/spec"
+	for i, notifier := range notifiers {
+		deadline := time.Now().Add(2 * time.Second)
+		for !containsString(notifier.sent(), want) {
+			if time.Now().After(deadline) {
+				t.Fatalf("Session %d was not notified, got %v", i, notifier.sent())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	server.endSession(sessions[0], "deleted by client")
+	if n := server.dispatcher.watchers.active(); n != 1 {
+		t.Errorf("Expected the watcher to run while a session remains, got %d", n)
+	}
+	server.endSession(sessions[1], "deleted by client")
+	if n := server.dispatcher.watchers.active(); n != 0 {
+		t.Errorf("Expected the watcher to stop with its last session, got %d", n)
+	}
+}
@@ -132,6 +132,31 @@ func (t *StepTracker) maybeRefresh() {
 	}
 }
 
+// Progress reports how far the tracked work has come. Each finished step
+// (done, failed or skipped) counts as 1 and a running step as 0.5, so the
+// value only grows as steps move forward.
+func (t *StepTracker) Progress() (completed float64, total int) {
+	for _, step := range t.Steps {
+		switch step.Status {
+		case StatusDone, StatusError, StatusSkipped:
+			completed++
+		case StatusRunning:
+			completed += 0.5
+		}
+	}
+	return completed, len(t.Steps)
+}
+
+// Current returns the first running step, if any
+func (t *StepTracker) Current() (Step, bool) {
+	for _, step := range t.Steps {
+		if step.Status == StatusRunning {
+			return step, true
+		}
+	}
+	return Step{}, false
+}
+
 // Render creates a tree-style rendering of the steps
 func (t *StepTracker) Render() string {
 	// Create title
@@ -202,3 +202,32 @@ func TestStepTrackerRefreshCallback(t *testing.T) {
 		t.Errorf("Expected refresh to be called three times, got %d", callCount)
 	}
 }
+
+func TestStepTrackerProgress(t *testing.T) {
+	tracker := NewStepTracker("Test")
+	tracker.Add("step1", "Step 1")
+	tracker.Add("step2", "Step 2")
+	tracker.Add("step3", "Step 3")
+
+	if completed, total := tracker.Progress(); completed != 0 || total != 3 {
+		t.Errorf("Expected 0/3, got %v/%d", completed, total)
+	}
+	if _, ok := tracker.Current(); ok {
+		t.Error("Expected no running step")
+	}
+
+	tracker.Complete("step1")
+	tracker.Start("step2", "working")
+	if completed, _ := tracker.Progress(); completed != 1.5 {
+		t.Errorf("Expected 1.5, got %v", completed)
+	}
+	if current, ok := tracker.Current(); !ok || current.Key != "step2" {
+		t.Errorf("Expected step2 to be current, got %+v", current)
+	}
+
+	tracker.Error("step2")
+	tracker.Skip("step3")
+	if completed, total := tracker.Progress(); completed != 3 || total != 3 {
+		t.Errorf("Expected 3/3, got %v/%d", completed, total)
+	}
+}